@@ -0,0 +1,18 @@
+// Command vapid-keys generates a VAPID key pair for Web Push notifications.
+// Put the output in .env to enable push alerts.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/swelljoe/wthr.lol/internal/push"
+)
+
+func main() {
+	public, private, err := push.GenerateVAPIDKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", public, private)
+}
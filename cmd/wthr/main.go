@@ -1,21 +1,50 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/swelljoe/wthr.lol/internal/accuracy"
+	"github.com/swelljoe/wthr.lol/internal/alerts"
 	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/digest"
 	"github.com/swelljoe/wthr.lol/internal/handlers"
+	"github.com/swelljoe/wthr.lol/internal/live"
+	"github.com/swelljoe/wthr.lol/internal/logging"
+	"github.com/swelljoe/wthr.lol/internal/metrics"
+	"github.com/swelljoe/wthr.lol/internal/mqtt"
+	"github.com/swelljoe/wthr.lol/internal/observations"
+	"github.com/swelljoe/wthr.lol/internal/push"
+	"github.com/swelljoe/wthr.lol/internal/tracing"
 	"github.com/swelljoe/wthr.lol/internal/weather"
+	"github.com/swelljoe/wthr.lol/internal/webhook"
 )
 
 func main() {
 	// Load .env
 	_ = godotenv.Load()
 
+	if err := logging.Setup(os.Stderr, os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL")); err != nil {
+		log.Fatalf("Invalid logging settings: %v", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), os.Stdout, os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		log.Fatalf("Invalid tracing settings: %v", err)
+	}
+
+	// Cancelled by SIGINT or SIGTERM, which stops the background workers
+	// and shuts the server down.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -35,6 +64,166 @@ func main() {
 	// Initialize services
 	wService := weather.NewService(database)
 
+	// Background alert consumers share one nationwide fetch of active alerts
+	// per poll and match their locations locally.
+	activeAlerts := wService.NewActiveAlerts(time.Minute)
+
+	// Record alert history for watched points and states
+	if database != nil {
+		recorder := alerts.NewRecorder(database, activeAlerts)
+		points, err := alerts.ParsePoints(os.Getenv("ALERT_WATCH_POINTS"))
+		if err != nil {
+			log.Fatalf("Invalid ALERT_WATCH_POINTS: %v", err)
+		}
+		recorder.Points = points
+		for _, state := range strings.Split(os.Getenv("ALERT_WATCH_STATES"), ",") {
+			if state = strings.TrimSpace(state); state != "" {
+				recorder.States = append(recorder.States, strings.ToUpper(state))
+			}
+		}
+		if interval, err := time.ParseDuration(os.Getenv("ALERT_POLL_INTERVAL")); err == nil && interval > 0 {
+			recorder.Interval = interval
+		}
+		go recorder.Run(ctx)
+	}
+
+	// Track issued forecasts for accuracy scoring
+	trackPoints, err := alerts.ParsePoints(os.Getenv("FORECAST_TRACK_POINTS"))
+	if err != nil {
+		log.Fatalf("Invalid FORECAST_TRACK_POINTS: %v", err)
+	}
+	if len(trackPoints) > 0 && database != nil {
+		tracker := accuracy.NewTracker(database, wService)
+		tracker.Points = trackPoints
+		if interval, err := time.ParseDuration(os.Getenv("FORECAST_POLL_INTERVAL")); err == nil && interval > 0 {
+			tracker.Interval = interval
+		}
+		go tracker.Run(ctx)
+		log.Printf("Forecast tracker started for %d points (every %s)", len(trackPoints), tracker.Interval)
+	}
+
+	// Record observations from watched stations for the almanac, and from
+	// the stations tracked forecasts are verified against
+	if stations := observations.ParseStations(os.Getenv("OBSERVATION_STATIONS")); (len(stations) > 0 || len(trackPoints) > 0) && database != nil {
+		recorder := observations.NewRecorder(database, wService)
+		recorder.Stations = stations
+		if interval, err := time.ParseDuration(os.Getenv("OBSERVATION_POLL_INTERVAL")); err == nil && interval > 0 {
+			recorder.Interval = interval
+		}
+		go recorder.Run(ctx)
+		log.Printf("Observation recorder started for %s (every %s)", strings.Join(stations, ", "), recorder.Interval)
+	}
+
+	// Start the Web Push alert poller when VAPID keys are configured
+	if priv := os.Getenv("VAPID_PRIVATE_KEY"); priv != "" && database != nil {
+		keys, err := push.ParseVAPIDKeys(os.Getenv("VAPID_PUBLIC_KEY"), priv)
+		if err != nil {
+			log.Fatalf("Invalid VAPID keys: %v", err)
+		}
+		subject := os.Getenv("VAPID_SUBJECT")
+		if subject == "" {
+			subject = "mailto:contact@wthr.lol"
+		}
+		poller := push.NewPoller(database, activeAlerts, push.NewSender(keys, subject))
+		if interval, err := time.ParseDuration(os.Getenv("PUSH_POLL_INTERVAL")); err == nil && interval > 0 {
+			poller.Interval = interval
+		}
+		go poller.Run(ctx)
+		log.Printf("Push alert poller started (every %s)", poller.Interval)
+	}
+
+	// Start the alert webhook dispatcher when the management API is enabled
+	if os.Getenv("WEBHOOK_API_TOKEN") != "" && database != nil {
+		dispatcher := webhook.NewDispatcher(database, activeAlerts)
+		if interval, err := time.ParseDuration(os.Getenv("WEBHOOK_POLL_INTERVAL")); err == nil && interval > 0 {
+			dispatcher.Interval = interval
+		}
+		go dispatcher.Run(ctx)
+		log.Printf("Webhook dispatcher started (every %s)", dispatcher.Interval)
+	}
+
+	// Email is optional; without SMTP_ADDR there is no daily digest signup.
+	var mailer *digest.SMTPSender
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" && database != nil {
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			from = "wthr.lol <digest@wthr.lol>"
+		}
+		mailer, err = digest.NewSMTPSender(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+		if err != nil {
+			log.Fatalf("Invalid SMTP settings: %v", err)
+		}
+		templates, err := digest.ParseTemplates("templates")
+		if err != nil {
+			log.Fatalf("Failed to parse digest templates: %v", err)
+		}
+		baseURL := strings.TrimRight(os.Getenv("BASE_URL"), "/")
+		if baseURL == "" {
+			baseURL = "https://wthr.lol"
+		}
+		scheduler := digest.NewScheduler(database, wService, mailer, templates, baseURL)
+		if interval, err := time.ParseDuration(os.Getenv("DIGEST_POLL_INTERVAL")); err == nil && interval > 0 {
+			scheduler.Interval = interval
+		}
+		go scheduler.Run(ctx)
+		log.Printf("Daily digest scheduler started (every %s)", scheduler.Interval)
+	}
+
+	// Publish conditions and alerts to an MQTT broker for home automation
+	if broker := os.Getenv("MQTT_BROKER"); broker != "" {
+		locations, err := mqtt.ParseLocations(os.Getenv("MQTT_LOCATIONS"))
+		if err != nil {
+			log.Fatalf("Invalid MQTT_LOCATIONS: %v", err)
+		}
+		publisher := mqtt.NewPublisher(wService, activeAlerts, broker)
+		publisher.Locations = locations
+		publisher.Username = os.Getenv("MQTT_USERNAME")
+		publisher.Password = os.Getenv("MQTT_PASSWORD")
+		if prefix := os.Getenv("MQTT_TOPIC_PREFIX"); prefix != "" {
+			publisher.Prefix = prefix
+		}
+		if prefix := os.Getenv("MQTT_DISCOVERY_PREFIX"); prefix != "" {
+			publisher.DiscoveryPrefix = prefix
+		}
+		if u := os.Getenv("MQTT_UNITS"); u != "" {
+			publisher.Units, err = weather.ParseUnits(u)
+			if err != nil {
+				log.Fatalf("Invalid MQTT_UNITS: %v", err)
+			}
+		}
+		if interval, err := time.ParseDuration(os.Getenv("MQTT_POLL_INTERVAL")); err == nil && interval > 0 {
+			publisher.Interval = interval
+		}
+		go publisher.Run(ctx)
+		log.Printf("MQTT publisher started for %d locations (every %s)", len(locations), publisher.Interval)
+	}
+
+	// Export current conditions as Prometheus gauges
+	metricsPoints, err := alerts.ParsePoints(os.Getenv("METRICS_POINTS"))
+	if err != nil {
+		log.Fatalf("Invalid METRICS_POINTS: %v", err)
+	}
+	if len(metricsPoints) > 0 {
+		exporter := weather.NewConditionsExporter(wService)
+		exporter.Points = metricsPoints
+		if interval, err := time.ParseDuration(os.Getenv("METRICS_POLL_INTERVAL")); err == nil && interval > 0 {
+			exporter.Interval = interval
+		}
+		go exporter.Run(ctx)
+		log.Printf("Conditions exporter started for %d points (every %s)", len(metricsPoints), exporter.Interval)
+	}
+
+	// Live updates for open pages. The hub reads through the weather cache,
+	// which needs the database.
+	var hub *live.Hub
+	if database != nil {
+		hub = live.NewHub(wService, activeAlerts)
+		if interval, err := time.ParseDuration(os.Getenv("LIVE_POLL_INTERVAL")); err == nil && interval > 0 {
+			hub.Interval = interval
+		}
+		go hub.Run(ctx)
+	}
+
 	// Setup routes
 	mux := http.NewServeMux()
 
@@ -52,18 +241,104 @@ func main() {
 	mux.Handle("/.well-known/", http.StripPrefix("/.well-known/", http.FileServer(http.Dir("static/.well-known"))))
 
 	// Setup handlers
-	h := handlers.New(database, wService)
+	h := handlers.New(database, wService, activeAlerts, mailer, hub)
 	mux.HandleFunc("/", h.HandleIndex)
 	mux.HandleFunc("/health", h.HandleHealth)
+	mux.Handle("GET /metrics", metrics.Handler())
 	mux.HandleFunc("/api/weather", h.HandleWeatherAPI)
+	mux.HandleFunc("GET /api/weather/stream", h.HandleWeatherStream)
+	mux.HandleFunc("GET /api/v1/weather", h.HandleWeatherJSON)
+	mux.HandleFunc("GET /api/v1/homeassistant", h.HandleHomeAssistant)
+	// Shareable server-rendered pages per place or coordinate pair
+	mux.HandleFunc("GET /w/{state}/{place}", h.HandlePlacePermalink)
+	mux.HandleFunc("GET /w/{coords}", h.HandleCoordsPermalink)
+	mux.HandleFunc("GET /w/{coords}/calendar.ics", h.HandleCoordsCalendar)
+	mux.HandleFunc("GET /w/{state}/{place}/calendar.ics", h.HandlePlaceCalendar)
+	// Weather card images for link previews
+	mux.HandleFunc("GET /card/{coords}", h.HandleCoordsCard)
+	mux.HandleFunc("GET /card/{state}/{place}", h.HandlePlaceCard)
+	// No-JavaScript version of the site
+	mux.HandleFunc("GET /lite", h.HandleLite)
+	// Widget for other sites' iframes, and the page that builds its snippet
+	mux.HandleFunc("GET /embed", h.HandleEmbed)
+	mux.HandleFunc("GET /embed/builder", h.HandleEmbedBuilder)
+
+	mux.HandleFunc("POST /digest/subscribe", h.HandleDigestSubscribe)
+	mux.HandleFunc("GET /digest/confirm", h.HandleDigestConfirm)
+	mux.HandleFunc("/digest/unsubscribe", h.HandleDigestUnsubscribe)
+
+	mux.HandleFunc("GET /feeds/alerts", h.HandleAlertsFeed)
+	mux.HandleFunc("GET /feeds/alerts/{coords}", h.HandleCoordsAlertsFeed)
+	mux.HandleFunc("GET /feeds/alerts/{state}/{place}", h.HandlePlaceAlertsFeed)
+	// Saved locations sync and multi-location overview
+	mux.HandleFunc("POST /api/saved", h.HandleCreateSavedLocations)
+	mux.HandleFunc("/api/saved/{token}", h.HandleSavedLocations)
+	mux.HandleFunc("GET /api/overview", h.HandleOverview)
+	// Web Push alert notifications
+	mux.HandleFunc("GET /api/push/key", h.HandlePushKey)
+	mux.HandleFunc("/api/push/subscribe", h.HandlePushSubscribe)
+	// Alert webhook management (bearer token from WEBHOOK_API_TOKEN)
+	mux.HandleFunc("/api/webhooks", h.HandleWebhooks)
+	mux.HandleFunc("DELETE /api/webhooks/{id}", h.HandleDeleteWebhook)
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", h.HandleWebhookDeliveries)
+	// Alert history recorded by the background poller
+	mux.HandleFunc("GET /api/v1/alerts/history", h.HandleAlertHistory)
+	mux.HandleFunc("GET /api/v1/alerts.geojson", h.HandleAlertsGeoJSON)
+	mux.HandleFunc("GET /alerts/map", h.HandleAlertsMap)
+	// Observed weather at recorded stations
+	mux.HandleFunc("GET /api/v1/almanac/{station}", h.HandleAlmanacAPI)
+	mux.HandleFunc("GET /almanac", h.HandleAlmanac)
+	mux.HandleFunc("GET /almanac/{station}", h.HandleAlmanac)
+	// Forecast accuracy at tracked points
+	mux.HandleFunc("GET /api/v1/accuracy/{site}", h.HandleAccuracyAPI)
+	mux.HandleFunc("GET /accuracy", h.HandleAccuracy)
+	mux.HandleFunc("GET /accuracy/{site}", h.HandleAccuracy)
 	mux.HandleFunc("/api/search", h.HandleSearch)
 	// Endpoint to collect app interest submissions (email, platforms, country)
 	mux.HandleFunc("/api/app-interest", h.HandleAppInterest)
 
 	// Start server
 	addr := fmt.Sprintf(":%s", port)
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handlers.FramePolicy(handlers.Trace(handlers.LogRequests(handlers.Instrument(mux)))),
+	}
 	log.Printf("Server starting on http://localhost%s", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	err = serve(ctx, server)
+
+	// Flush buffered spans, whether the server stopped cleanly or not.
+	flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// shutdownTimeout bounds how long shutdown waits for in-flight requests,
+// and then for traces to be flushed.
+const shutdownTimeout = 10 * time.Second
+
+// serve runs server until it fails or ctx is cancelled, then shuts it down,
+// letting in-flight requests finish for up to shutdownTimeout before
+// closing whatever connections remain, such as live update streams.
+func serve(ctx context.Context, server *http.Server) error {
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown timed out, closing connections: %v", err)
+		return server.Close()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package accuracy
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// measurablePrecip is the smallest daily total, in mm, that counts as
+// precipitation having occurred: 0.01 in, the NWS threshold.
+const measurablePrecip = 0.254
+
+// minDayHours is how many hours of a day a station must have reported in
+// for its high, low and precipitation to be trusted. Stations report
+// hourly, so a day with much less has gaps where the extremes or the rain
+// may have been missed.
+const minDayHours = 18
+
+// Report is how well a site's forecasts verified, by how far ahead they
+// were issued. Temperature errors are in TemperatureUnit; precipitation
+// errors are in percentage points, comparing each chance with 100 when
+// measurable precipitation fell that day and 0 when it didn't.
+type Report struct {
+	Site            db.ForecastSite `json:"site"`
+	StationName     string          `json:"station_name"`
+	Units           weather.Units   `json:"units"`
+	TemperatureUnit string          `json:"temperature_unit"`
+	Since           string          `json:"since"`
+	Leads           []Lead          `json:"leads"`
+}
+
+// Lead scores the forecasts issued LeadDay days ahead (lead time 24 ×
+// LeadDay to 24 × LeadDay + 23 hours). A kind is nil when none of its
+// forecasts could be verified.
+type Lead struct {
+	LeadDay     int    `json:"lead_day"`
+	Temperature *Score `json:"temperature,omitempty"`
+	High        *Score `json:"high,omitempty"`
+	Low         *Score `json:"low,omitempty"`
+	Precip      *Score `json:"precip,omitempty"`
+}
+
+// Score summarizes forecast errors. Bias is the mean of forecast minus
+// observed, so a positive bias means forecasts ran high.
+type Score struct {
+	Count     int     `json:"count"`
+	MeanError float64 `json:"mean_abs_error"`
+	Bias      float64 `json:"bias"`
+}
+
+// BuildReport scores forecasts against the station's observed days and
+// hours, in units. Only days before today in the station's time zone, with
+// reports from at least minDayHours hours, are complete enough to verify
+// highs, lows and precipitation.
+func BuildReport(site db.ForecastSite, st db.Station, since string, forecasts []db.IssuedForecast, days []db.ObservationDay, hours []db.ObservationHour, units weather.Units, now time.Time) *Report {
+	loc, err := time.LoadLocation(st.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	today := now.In(loc).Format("2006-01-02")
+
+	_, tempUnit := units.Temperature(0, weather.UnitCelsius)
+	tempScale := 1.0
+	if tempUnit == weather.UnitFahrenheit {
+		tempScale = 9.0 / 5
+	}
+
+	observedDays := make(map[string]db.ObservationDay, len(days))
+	for _, d := range days {
+		if d.Date < today && d.Hours >= minDayHours {
+			observedDays[d.Date] = d
+		}
+	}
+	observedHours := make(map[string]float64, len(hours))
+	for _, h := range hours {
+		observedHours[h.Hour] = h.Temperature
+	}
+
+	type key struct {
+		lead int
+		kind string
+	}
+	type sums struct {
+		n           int
+		abs, signed float64
+	}
+	totals := make(map[key]*sums)
+	add := func(f db.IssuedForecast, observed, scale float64) {
+		k := key{f.LeadHours / 24, f.Kind}
+		s := totals[k]
+		if s == nil {
+			s = &sums{}
+			totals[k] = s
+		}
+		diff := (f.Value - observed) * scale
+		s.n++
+		s.abs += math.Abs(diff)
+		s.signed += diff
+	}
+
+	for _, f := range forecasts {
+		switch f.Kind {
+		case db.ForecastTemperature:
+			if t, ok := observedHours[f.Target]; ok {
+				add(f, t, tempScale)
+			}
+		case db.ForecastHigh:
+			if d, ok := observedDays[f.Target]; ok && d.High != nil {
+				add(f, *d.High, tempScale)
+			}
+		case db.ForecastLow:
+			if d, ok := observedDays[f.Target]; ok && d.Low != nil {
+				add(f, *d.Low, tempScale)
+			}
+		case db.ForecastPrecip:
+			if d, ok := observedDays[f.Target]; ok && d.Precip != nil {
+				occurred := 0.0
+				if *d.Precip >= measurablePrecip {
+					occurred = 100
+				}
+				add(f, occurred, 1)
+			}
+		}
+	}
+
+	leads := make(map[int]*Lead)
+	for k, s := range totals {
+		l := leads[k.lead]
+		if l == nil {
+			l = &Lead{LeadDay: k.lead}
+			leads[k.lead] = l
+		}
+		score := &Score{
+			Count:     s.n,
+			MeanError: roundTenth(s.abs / float64(s.n)),
+			Bias:      roundTenth(s.signed / float64(s.n)),
+		}
+		switch k.kind {
+		case db.ForecastTemperature:
+			l.Temperature = score
+		case db.ForecastHigh:
+			l.High = score
+		case db.ForecastLow:
+			l.Low = score
+		case db.ForecastPrecip:
+			l.Precip = score
+		}
+	}
+
+	r := &Report{
+		Site:            site,
+		StationName:     st.Name,
+		Units:           units,
+		TemperatureUnit: tempUnit,
+		Since:           since,
+		Leads:           []Lead{},
+	}
+	for _, l := range leads {
+		r.Leads = append(r.Leads, *l)
+	}
+	sort.Slice(r.Leads, func(i, j int) bool { return r.Leads[i].LeadDay < r.Leads[j].LeadDay })
+	return r
+}
+
+func roundTenth(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,74 @@
+package accuracy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func f64(v float64) *float64 { return &v }
+
+func TestBuildReport(t *testing.T) {
+	site := db.ForecastSite{ID: "35.47,-97.52", Station: "KOKC"}
+	st := db.Station{ID: "KOKC", Name: "Oklahoma City", TimeZone: "America/Chicago"}
+	forecasts := []db.IssuedForecast{
+		{Kind: db.ForecastHigh, Target: "2025-05-05", LeadHours: 2, Value: 30},
+		{Kind: db.ForecastHigh, Target: "2025-05-05", LeadHours: 26, Value: 26},
+		{Kind: db.ForecastHigh, Target: "2025-05-04", LeadHours: 30, Value: 25},
+		{Kind: db.ForecastHigh, Target: "2025-05-03", LeadHours: 28, Value: 30},
+		{Kind: db.ForecastLow, Target: "2025-05-05", LeadHours: 8, Value: 15},
+		{Kind: db.ForecastPrecip, Target: "2025-05-05", LeadHours: 2, Value: 30},
+		{Kind: db.ForecastPrecip, Target: "2025-05-04", LeadHours: 30, Value: 80},
+		{Kind: db.ForecastTemperature, Target: "2025-05-05 19", LeadHours: 3, Value: 29},
+		// Today isn't over, so its high can't be verified yet.
+		{Kind: db.ForecastHigh, Target: "2025-05-06", LeadHours: 20, Value: 20},
+	}
+	days := []db.ObservationDay{
+		// A station down most of the day may have missed its extremes.
+		{Date: "2025-05-03", High: f64(20), Low: f64(15), Precip: f64(0), Hours: 17},
+		{Date: "2025-05-04", High: f64(25), Low: f64(12), Precip: f64(0.1), Hours: 24},
+		{Date: "2025-05-05", High: f64(28), Low: f64(16), Precip: f64(5), Hours: 23},
+		{Date: "2025-05-06", High: f64(22), Hours: 19},
+	}
+	hours := []db.ObservationHour{{Hour: "2025-05-05 19", Temperature: 28}}
+	now := time.Date(2025, 5, 6, 18, 0, 0, 0, time.UTC)
+
+	r := BuildReport(site, st, "2025-04-06", forecasts, days, hours, weather.UnitsMetric, now)
+	if r.TemperatureUnit != "C" || len(r.Leads) != 2 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	day0, day1 := r.Leads[0], r.Leads[1]
+	if day0.LeadDay != 0 || day1.LeadDay != 1 {
+		t.Fatalf("expected lead days 0 and 1, got %+v", r.Leads)
+	}
+	if *day0.High != (Score{Count: 1, MeanError: 2, Bias: 2}) {
+		t.Errorf("unexpected day 0 high %+v", day0.High)
+	}
+	if *day0.Low != (Score{Count: 1, MeanError: 1, Bias: -1}) {
+		t.Errorf("unexpected day 0 low %+v", day0.Low)
+	}
+	// 30% chance on a wet day is 70 points short.
+	if *day0.Precip != (Score{Count: 1, MeanError: 70, Bias: -70}) {
+		t.Errorf("unexpected day 0 precip %+v", day0.Precip)
+	}
+	if *day0.Temperature != (Score{Count: 1, MeanError: 1, Bias: 1}) {
+		t.Errorf("unexpected day 0 temperature %+v", day0.Temperature)
+	}
+	// Errors of -2 and 0; 0.1 mm isn't measurable, so 80% is 80 too high.
+	if *day1.High != (Score{Count: 2, MeanError: 1, Bias: -1}) {
+		t.Errorf("unexpected day 1 high %+v", day1.High)
+	}
+	if *day1.Precip != (Score{Count: 1, MeanError: 80, Bias: 80}) {
+		t.Errorf("unexpected day 1 precip %+v", day1.Precip)
+	}
+	if day1.Low != nil || day1.Temperature != nil {
+		t.Errorf("expected nothing else verified on day 1, got %+v", day1)
+	}
+
+	us := BuildReport(site, st, "2025-04-06", forecasts, days, hours, weather.UnitsUS, now)
+	if us.TemperatureUnit != "F" || us.Leads[0].High.MeanError != 3.6 {
+		t.Errorf("expected errors scaled to °F, got %+v", us.Leads[0].High)
+	}
+}
@@ -0,0 +1,199 @@
+// Package accuracy records issued forecasts and scores them against the
+// observations recorded for the almanac.
+package accuracy
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// maxLeadHours is how far ahead hourly temperatures are recorded. The daily
+// forecast only runs seven days, so hourly values past that add little.
+const maxLeadHours = 7 * 24
+
+// Store is the forecast storage the Tracker needs; *db.DB implements it.
+type Store interface {
+	SaveForecastSite(site db.ForecastSite) error
+	RecordForecasts(site string, forecasts []db.IssuedForecast) error
+	GetStation(ctx context.Context, id string) (*db.Station, error)
+	SaveStation(st db.Station) error
+}
+
+// Source fetches issued forecasts and station metadata; *weather.Service
+// implements it.
+type Source interface {
+	GetForecastIssue(lat, lon float64) (*weather.ForecastIssue, error)
+	GetStation(id string) (*weather.Station, error)
+}
+
+// Tracker periodically records the forecast issued for each of Points. It
+// also saves each point's nearest station, so the observation recorder
+// starts recording what the forecasts are scored against. NWS offices
+// reissue forecasts a few times a day; recording the same issue again is
+// harmless.
+type Tracker struct {
+	store    Store
+	source   Source
+	Points   []db.Point
+	Interval time.Duration
+}
+
+// NewTracker creates a Tracker that polls every three hours.
+func NewTracker(store Store, source Source) *Tracker {
+	return &Tracker{
+		store:    store,
+		source:   source,
+		Interval: 3 * time.Hour,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		t.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll records the current forecast for each point.
+func (t *Tracker) Poll() {
+	for _, p := range t.Points {
+		if err := t.track(p, time.Now()); err != nil {
+			log.Printf("Forecast accuracy: %s: %v", db.PointKey(p.Latitude, p.Longitude), err)
+		}
+	}
+}
+
+func (t *Tracker) track(p db.Point, now time.Time) error {
+	issue, err := t.source.GetForecastIssue(p.Latitude, p.Longitude)
+	if err != nil {
+		return err
+	}
+	station := strings.ToUpper(issue.Station)
+	if err := t.saveStation(station, issue.TimeZone); err != nil {
+		return err
+	}
+
+	site := db.ForecastSite{
+		ID:        db.PointKey(p.Latitude, p.Longitude),
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		Station:   station,
+	}
+	if err := t.store.SaveForecastSite(site); err != nil {
+		return err
+	}
+	return t.store.RecordForecasts(site.ID, Issued(issue, now))
+}
+
+// saveStation saves a station the first time a point uses it, falling back
+// to the point's time zone if the station's metadata can't be fetched.
+func (t *Tracker) saveStation(id, timeZone string) error {
+	st, err := t.store.GetStation(context.Background(), id)
+	if err != nil || st != nil {
+		return err
+	}
+
+	saved := db.Station{ID: id, Name: id, TimeZone: timeZone}
+	if ws, err := t.source.GetStation(id); err != nil {
+		log.Printf("Forecast accuracy: failed to get station %s: %v", id, err)
+	} else {
+		saved.Name = ws.Name
+		if ws.TimeZone != "" {
+			saved.TimeZone = ws.TimeZone
+		}
+	}
+	if saved.TimeZone == "" {
+		saved.TimeZone = "UTC"
+	}
+	return t.store.SaveStation(saved)
+}
+
+// Issued converts a forecast issue into the values to record: hourly
+// temperatures for the next week, and each day's high and precipitation
+// chance from its daytime period. A night period's low is the low for the
+// morning it ends on: the following date for "Tonight", but the same date
+// for an "Overnight" period that starts after midnight.
+// Temperatures are stored in °C. now stands in for the issue time when the
+// forecast doesn't say.
+func Issued(issue *weather.ForecastIssue, now time.Time) []db.IssuedForecast {
+	issued := now.UTC().Truncate(time.Second)
+	if issue.Daily != nil {
+		if t, err := time.Parse(time.RFC3339, issue.Daily.Properties.UpdateTime); err == nil {
+			issued = t.UTC()
+		}
+	}
+	leadHours := func(start time.Time) int {
+		return max(0, int(math.Round(start.Sub(issued).Hours())))
+	}
+
+	var out []db.IssuedForecast
+	if issue.Hourly != nil {
+		for _, p := range issue.Hourly.Properties.Periods {
+			start, err := time.Parse(time.RFC3339, p.StartTime)
+			if err != nil {
+				continue
+			}
+			lead := leadHours(start)
+			if lead > maxLeadHours {
+				break
+			}
+			out = append(out, db.IssuedForecast{
+				Kind:      db.ForecastTemperature,
+				Target:    start.UTC().Format("2006-01-02 15"),
+				IssuedAt:  issued,
+				LeadHours: lead,
+				Value:     celsius(p.Temperature, p.TemperatureUnit),
+			})
+		}
+	}
+
+	if issue.Daily != nil {
+		for _, p := range issue.Daily.Properties.Periods {
+			// StartTime carries the forecast's local offset, so its date is
+			// the local date.
+			start, err := time.Parse(time.RFC3339, p.StartTime)
+			if err != nil {
+				continue
+			}
+			f := db.IssuedForecast{IssuedAt: issued, LeadHours: leadHours(start)}
+			if !p.IsDaytime {
+				f.Kind = db.ForecastLow
+				target := start
+				if start.Hour() >= 12 {
+					target = start.AddDate(0, 0, 1)
+				}
+				f.Target = target.Format("2006-01-02")
+				f.Value = celsius(p.Temperature, p.TemperatureUnit)
+				out = append(out, f)
+				continue
+			}
+			f.Target = start.Format("2006-01-02")
+			f.Kind = db.ForecastHigh
+			f.Value = celsius(p.Temperature, p.TemperatureUnit)
+			out = append(out, f)
+			f.Kind = db.ForecastPrecip
+			f.Value = float64(p.ProbabilityOfPrecipitation.Value)
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func celsius(v int, unit string) float64 {
+	c, _ := weather.UnitsMetric.Temperature(float64(v), unit)
+	return c
+}
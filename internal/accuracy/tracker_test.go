@@ -0,0 +1,142 @@
+package accuracy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type memoryStore struct {
+	sites     map[string]db.ForecastSite
+	stations  map[string]db.Station
+	forecasts map[string][]db.IssuedForecast
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		sites:     map[string]db.ForecastSite{},
+		stations:  map[string]db.Station{},
+		forecasts: map[string][]db.IssuedForecast{},
+	}
+}
+
+func (m *memoryStore) SaveForecastSite(site db.ForecastSite) error {
+	m.sites[site.ID] = site
+	return nil
+}
+
+func (m *memoryStore) RecordForecasts(site string, forecasts []db.IssuedForecast) error {
+	m.forecasts[site] = append(m.forecasts[site], forecasts...)
+	return nil
+}
+
+func (m *memoryStore) GetStation(ctx context.Context, id string) (*db.Station, error) {
+	if st, ok := m.stations[id]; ok {
+		return &st, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) SaveStation(st db.Station) error {
+	m.stations[st.ID] = st
+	return nil
+}
+
+type fakeSource struct{}
+
+func (fakeSource) GetForecastIssue(lat, lon float64) (*weather.ForecastIssue, error) {
+	return testIssue(), nil
+}
+
+func (fakeSource) GetStation(id string) (*weather.Station, error) {
+	return &weather.Station{ID: id, Name: "Oklahoma City", TimeZone: "America/Chicago"}, nil
+}
+
+func period(start string, daytime bool, temp, pop int) weather.ForecastPeriod {
+	p := weather.ForecastPeriod{StartTime: start, IsDaytime: daytime, Temperature: temp, TemperatureUnit: "F"}
+	p.ProbabilityOfPrecipitation.Value = pop
+	return p
+}
+
+func testIssue() *weather.ForecastIssue {
+	daily := &weather.ForecastResponse{}
+	daily.Properties.UpdateTime = "2025-05-05T14:00:00-05:00"
+	daily.Properties.Periods = []weather.ForecastPeriod{
+		period("2025-05-05T14:00:00-05:00", true, 86, 10),
+		period("2025-05-05T18:00:00-05:00", false, 59, 20),
+		period("2025-05-06T06:00:00-05:00", true, 77, 60),
+	}
+	hourly := &weather.ForecastResponse{}
+	hourly.Properties.Periods = []weather.ForecastPeriod{
+		period("2025-05-05T14:00:00-05:00", true, 86, 0),
+		period("2025-05-06T15:00:00-05:00", true, 50, 0),
+	}
+	return &weather.ForecastIssue{Station: "kokc", TimeZone: "America/Chicago", Daily: daily, Hourly: hourly}
+}
+
+func TestIssued(t *testing.T) {
+	got := Issued(testIssue(), time.Now())
+	issued := time.Date(2025, 5, 5, 19, 0, 0, 0, time.UTC)
+	want := []db.IssuedForecast{
+		{Kind: db.ForecastTemperature, Target: "2025-05-05 19", LeadHours: 0, Value: 30},
+		{Kind: db.ForecastTemperature, Target: "2025-05-06 20", LeadHours: 25, Value: 10},
+		{Kind: db.ForecastHigh, Target: "2025-05-05", LeadHours: 0, Value: 30},
+		{Kind: db.ForecastPrecip, Target: "2025-05-05", LeadHours: 0, Value: 10},
+		{Kind: db.ForecastLow, Target: "2025-05-06", LeadHours: 4, Value: 15},
+		{Kind: db.ForecastHigh, Target: "2025-05-06", LeadHours: 16, Value: 25},
+		{Kind: db.ForecastPrecip, Target: "2025-05-06", LeadHours: 16, Value: 60},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d forecasts, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		w.IssuedAt = issued
+		if got[i] != w {
+			t.Errorf("forecast %d: expected %+v, got %+v", i, w, got[i])
+		}
+	}
+}
+
+func TestIssued_Overnight(t *testing.T) {
+	// Issued after midnight, the first period is the rest of the night,
+	// whose low is this morning's.
+	daily := &weather.ForecastResponse{}
+	daily.Properties.UpdateTime = "2025-05-06T01:30:00-05:00"
+	daily.Properties.Periods = []weather.ForecastPeriod{
+		period("2025-05-06T02:00:00-05:00", false, 59, 20),
+		period("2025-05-06T06:00:00-05:00", true, 77, 60),
+		period("2025-05-06T18:00:00-05:00", false, 50, 0),
+	}
+	got := Issued(&weather.ForecastIssue{Daily: daily}, time.Now())
+	var lows []string
+	for _, f := range got {
+		if f.Kind == db.ForecastLow {
+			lows = append(lows, f.Target)
+		}
+	}
+	if len(lows) != 2 || lows[0] != "2025-05-06" || lows[1] != "2025-05-07" {
+		t.Errorf("expected lows for 2025-05-06 and 2025-05-07, got %v", lows)
+	}
+}
+
+func TestTrackerPoll(t *testing.T) {
+	store := newMemoryStore()
+	tr := NewTracker(store, fakeSource{})
+	tr.Points = []db.Point{{Latitude: 35.4676, Longitude: -97.5164}}
+
+	tr.Poll()
+
+	site, ok := store.sites["35.47,-97.52"]
+	if !ok || site.Station != "KOKC" {
+		t.Fatalf("expected the site to be saved, got %+v", store.sites)
+	}
+	if st := store.stations["KOKC"]; st.Name != "Oklahoma City" || st.TimeZone != "America/Chicago" {
+		t.Errorf("expected the station to be saved for recording, got %+v", st)
+	}
+	if len(store.forecasts[site.ID]) != 7 {
+		t.Errorf("expected 7 forecasts, got %+v", store.forecasts[site.ID])
+	}
+}
@@ -0,0 +1,157 @@
+// Package alerts keeps a history of the NWS alerts in effect for watched
+// locations.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// Store is the history storage the Recorder needs; *db.DB implements it.
+type Store interface {
+	WatchedPoints() ([]db.Point, error)
+	RecordAlerts(scope string, alerts []db.AlertVersion, seen time.Time) error
+}
+
+// Source fetches active alerts; *weather.ActiveAlerts and *weather.Service
+// implement it.
+type Source interface {
+	GetAlerts(lat, lon float64) ([]weather.Alert, error)
+	GetAlertsByArea(area string) ([]weather.Alert, error)
+}
+
+// Recorder periodically records the active alerts for every watched point
+// (push subscriptions, webhooks and Points) and for every state in States.
+type Recorder struct {
+	store    Store
+	source   Source
+	Points   []db.Point
+	States   []string
+	Interval time.Duration
+}
+
+// NewRecorder creates a Recorder that polls every five minutes.
+func NewRecorder(store Store, source Source) *Recorder {
+	return &Recorder{
+		store:    store,
+		source:   source,
+		Interval: 5 * time.Minute,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Poll(); err != nil {
+			log.Printf("Alert history poll error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll records one snapshot of active alerts for each watched point and state.
+func (r *Recorder) Poll() error {
+	points, err := r.store.WatchedPoints()
+	if err != nil {
+		return fmt.Errorf("failed to list watched points: %w", err)
+	}
+	points = append(points, r.Points...)
+
+	now := time.Now()
+	done := make(map[string]bool)
+	for _, p := range points {
+		scope := db.PointKey(p.Latitude, p.Longitude)
+		if done[scope] {
+			continue
+		}
+		done[scope] = true
+
+		alerts, err := r.source.GetAlerts(p.Latitude, p.Longitude)
+		if err != nil {
+			log.Printf("Alert history: failed to get alerts for %s: %v", scope, err)
+			continue
+		}
+		if err := r.store.RecordAlerts(scope, Versions(alerts), now); err != nil {
+			log.Printf("Alert history: failed to record alerts for %s: %v", scope, err)
+		}
+	}
+
+	for _, state := range r.States {
+		alerts, err := r.source.GetAlertsByArea(state)
+		if err != nil {
+			log.Printf("Alert history: failed to get alerts for %s: %v", state, err)
+			continue
+		}
+		if err := r.store.RecordAlerts(state, Versions(alerts), now); err != nil {
+			log.Printf("Alert history: failed to record alerts for %s: %v", state, err)
+		}
+	}
+	return nil
+}
+
+// Versions converts alerts to history records, skipping any without an ID.
+func Versions(alerts []weather.Alert) []db.AlertVersion {
+	versions := make([]db.AlertVersion, 0, len(alerts))
+	for _, a := range alerts {
+		if a.ID == "" {
+			continue
+		}
+		versions = append(versions, db.AlertVersion{
+			ID:          a.ID,
+			Event:       a.Event,
+			Severity:    a.Severity,
+			Headline:    a.Headline,
+			Description: a.Description,
+			Instruction: a.Instruction,
+			AreaDesc:    a.AreaDesc,
+			MessageType: a.MessageType,
+			Sent:        a.Sent,
+			Effective:   a.Effective,
+			Onset:       a.Onset,
+			Expires:     a.Expires,
+			Ends:        a.Ends,
+			References:  a.References,
+		})
+	}
+	return versions
+}
+
+// ParsePoints parses a semicolon separated list of "lat,lon" pairs, as used
+// by the ALERT_WATCH_POINTS setting.
+func ParsePoints(s string) ([]db.Point, error) {
+	var points []db.Point
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		latStr, lonStr, ok := strings.Cut(pair, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid point %q", pair)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("invalid latitude in %q", pair)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("invalid longitude in %q", pair)
+		}
+		points = append(points, db.Point{Latitude: lat, Longitude: lon})
+	}
+	return points, nil
+}
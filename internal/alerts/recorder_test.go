@@ -0,0 +1,99 @@
+package alerts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type memoryStore struct {
+	points   []db.Point
+	recorded map[string][]db.AlertVersion
+}
+
+func (m *memoryStore) WatchedPoints() ([]db.Point, error) {
+	return m.points, nil
+}
+
+func (m *memoryStore) RecordAlerts(scope string, alerts []db.AlertVersion, seen time.Time) error {
+	if m.recorded == nil {
+		m.recorded = map[string][]db.AlertVersion{}
+	}
+	m.recorded[scope] = append(m.recorded[scope], alerts...)
+	return nil
+}
+
+type fakeSource struct {
+	byPoint map[string][]weather.Alert
+	byArea  map[string][]weather.Alert
+	calls   int
+}
+
+func (f *fakeSource) GetAlerts(lat, lon float64) ([]weather.Alert, error) {
+	f.calls++
+	alerts, ok := f.byPoint[db.PointKey(lat, lon)]
+	if !ok {
+		return nil, errors.New("NWS API error")
+	}
+	return alerts, nil
+}
+
+func (f *fakeSource) GetAlertsByArea(area string) ([]weather.Alert, error) {
+	f.calls++
+	return f.byArea[area], nil
+}
+
+func TestRecorderPoll(t *testing.T) {
+	store := &memoryStore{points: []db.Point{{Latitude: 35.4676, Longitude: -97.5164}}}
+	source := &fakeSource{
+		byPoint: map[string][]weather.Alert{
+			"35.47,-97.52": {
+				{ID: "urn:oid:1", Event: "Tornado Warning", Severity: "Extreme"},
+				{Event: "No ID"},
+			},
+			"40.71,-74.01": {{ID: "urn:oid:2", Event: "Heat Advisory"}},
+		},
+		byArea: map[string][]weather.Alert{"OK": {{ID: "urn:oid:1"}, {ID: "urn:oid:3"}}},
+	}
+
+	r := NewRecorder(store, source)
+	// A configured point duplicating a watched one is only fetched once, and
+	// one that fails doesn't stop the rest.
+	r.Points = []db.Point{{Latitude: 35.47, Longitude: -97.52}, {Latitude: 1, Longitude: 1}, {Latitude: 40.71, Longitude: -74.01}}
+	r.States = []string{"OK"}
+
+	if err := r.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if source.calls != 4 {
+		t.Errorf("expected 4 upstream calls, got %d", source.calls)
+	}
+	if got := store.recorded["35.47,-97.52"]; len(got) != 1 || got[0].ID != "urn:oid:1" || got[0].Event != "Tornado Warning" {
+		t.Errorf("unexpected point history %+v", got)
+	}
+	if got := store.recorded["40.71,-74.01"]; len(got) != 1 {
+		t.Errorf("expected configured point to be recorded, got %+v", got)
+	}
+	if got := store.recorded["OK"]; len(got) != 2 {
+		t.Errorf("expected state alerts to be recorded, got %+v", got)
+	}
+}
+
+func TestParsePoints(t *testing.T) {
+	points, err := ParsePoints(" 35.47,-97.52; 40.71, -74.01 ;")
+	if err != nil {
+		t.Fatalf("ParsePoints failed: %v", err)
+	}
+	if len(points) != 2 || points[1] != (db.Point{Latitude: 40.71, Longitude: -74.01}) {
+		t.Errorf("unexpected points %+v", points)
+	}
+
+	for _, bad := range []string{"35.47", "abc,1", "91,0", "0,181"} {
+		if _, err := ParsePoints(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
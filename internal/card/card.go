@@ -0,0 +1,313 @@
+// Package card renders a location's weather as an image for link
+// previews, in SVG and, using only Go code and the bundled Go fonts, PNG.
+package card
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// Width and Height are the card's size in pixels, the Open Graph
+// recommended 1.91:1.
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// Card colors.
+const (
+	backgroundColor = "#0f172a"
+	textColor       = "#f8fafc"
+	mutedColor      = "#cbd5e1"
+	badgeColor      = "#dc2626"
+	brandColor      = "#38bdf8"
+)
+
+// Card is what a weather card shows.
+type Card struct {
+	Location    string
+	Icon        string // A mapIcon name
+	Temperature int
+	Unit        string // Temperature unit, "F" or "C"
+	Condition   string
+	High, Low   int
+	// Alert is the most severe active alert's event, and Alerts how many
+	// alerts there are in all.
+	Alert  string
+	Alerts int
+}
+
+// FromWeather builds the card for wd.
+func FromWeather(wd *weather.WeatherData) *Card {
+	c := &Card{
+		Location:    wd.Location,
+		Icon:        wd.Current.Icon,
+		Temperature: wd.Current.Temperature,
+		Unit:        wd.Current.TemperatureUnit,
+		Condition:   wd.Current.ShortForecast,
+		High:        wd.Current.HighTemp,
+		Low:         wd.Current.LowTemp,
+	}
+	rank := 0
+	for _, a := range wd.Alerts {
+		if a.MessageType == "Cancel" {
+			continue
+		}
+		c.Alerts++
+		if r := weather.SeverityRank(a.Severity); c.Alerts == 1 || r > rank {
+			c.Alert, rank = a.Event, r
+		}
+	}
+	return c
+}
+
+// text is one line of text placed on the card.
+type text struct {
+	s        string
+	x, y     int // Left end of the baseline; the right end when right is set
+	size     float64
+	bold     bool
+	fill     string
+	right    bool
+	maxWidth int
+}
+
+// box is a rounded rectangle placed on the card.
+type box struct {
+	x, y, w, h, r float32
+	fill          string
+}
+
+// layout is the card's content in position, shared by both renderers.
+type layout struct {
+	iconX, iconY, iconSize int
+	icon                   []layer
+	boxes                  []box
+	texts                  []text
+}
+
+func (c *Card) layout(fs faces) layout {
+	l := layout{iconX: 80, iconY: 170, iconSize: 260, icon: iconLayers(c.Icon)}
+	l.texts = []text{
+		{s: c.Location, x: 80, y: 120, size: 56, bold: true, fill: textColor, maxWidth: Width - 160},
+		{s: fmt.Sprintf("%d°%s", c.Temperature, c.Unit), x: 380, y: 340, size: 160, bold: true, fill: textColor},
+		{s: c.Condition, x: 390, y: 415, size: 44, fill: mutedColor, maxWidth: Width - 470},
+		{s: fmt.Sprintf("H: %d°  L: %d°", c.High, c.Low), x: 390, y: 475, size: 40, fill: mutedColor},
+		{s: "wthr.lol", x: Width - 80, y: 575, size: 34, bold: true, fill: brandColor, right: true},
+	}
+	if c.Alert != "" {
+		label := c.Alert
+		if c.Alerts > 1 {
+			label += " +" + strconv.Itoa(c.Alerts-1)
+		}
+		t := text{s: label, x: 104, y: 574, size: 30, bold: true, fill: textColor, maxWidth: Width - 500}
+		t.s = fs.fit(t.s, t.size, t.bold, t.maxWidth)
+		w := fs.measure(t.s, t.size, t.bold)
+		l.boxes = append(l.boxes, box{x: 80, y: 530, w: float32(w + 48), h: 60, r: 12, fill: badgeColor})
+		l.texts = append(l.texts, t)
+	}
+	for i, t := range l.texts {
+		if t.maxWidth > 0 {
+			l.texts[i].s = fs.fit(t.s, t.size, t.bold, t.maxWidth)
+		}
+	}
+	return l
+}
+
+// WriteSVG writes the card as SVG. Text uses the Go fonts if the viewer
+// has them and a sans-serif font otherwise.
+func (c *Card) WriteSVG(w io.Writer) error {
+	l := c.layout(faces{})
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		Width, Height, Width, Height, html.EscapeString(c.description()))
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, Width, Height, backgroundColor)
+
+	scale := float64(l.iconSize) / iconSize
+	fmt.Fprintf(&b, `<g transform="translate(%d %d) scale(%g)">`, l.iconX, l.iconY, scale)
+	for _, ly := range l.icon {
+		fmt.Fprintf(&b, `<path d="%s" fill="%s"/>`, svgPath(ly.path), fillColor(ly.fill))
+	}
+	b.WriteString(`</g>`)
+
+	for _, bx := range l.boxes {
+		fmt.Fprintf(&b, `<rect x="%g" y="%g" width="%g" height="%g" rx="%g" fill="%s"/>`, bx.x, bx.y, bx.w, bx.h, bx.r, bx.fill)
+	}
+	for _, t := range l.texts {
+		weight := "normal"
+		if t.bold {
+			weight = "bold"
+		}
+		anchor := ""
+		if t.right {
+			anchor = ` text-anchor="end"`
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="Go, sans-serif" font-size="%g" font-weight="%s" fill="%s"%s>%s</text>`,
+			t.x, t.y, t.size, weight, t.fill, anchor, html.EscapeString(t.s))
+	}
+	b.WriteString(`</svg>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePNG writes the card as a PNG.
+func (c *Card) WritePNG(w io.Writer) error {
+	fs := faces{}
+	l := c.layout(fs)
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(parseColor(backgroundColor)), image.Point{}, draw.Src)
+
+	z := vector.NewRasterizer(l.iconSize, l.iconSize)
+	scale := float32(l.iconSize) / iconSize
+	for _, ly := range l.icon {
+		z.Reset(l.iconSize, l.iconSize)
+		for _, o := range ly.path {
+			a := o.args
+			switch o.cmd {
+			case 'M':
+				z.MoveTo(a[0]*scale, a[1]*scale)
+			case 'L':
+				z.LineTo(a[0]*scale, a[1]*scale)
+			case 'C':
+				z.CubeTo(a[0]*scale, a[1]*scale, a[2]*scale, a[3]*scale, a[4]*scale, a[5]*scale)
+			case 'Z':
+				z.ClosePath()
+			}
+		}
+		r := image.Rect(l.iconX, l.iconY, l.iconX+l.iconSize, l.iconY+l.iconSize)
+		z.Draw(img, r, image.NewUniform(parseColor(fillColor(ly.fill))), image.Point{})
+	}
+
+	for _, bx := range l.boxes {
+		w, h := int(bx.w+1), int(bx.h+1)
+		z.Reset(w, h)
+		roundRect(z, bx.w, bx.h, bx.r)
+		x, y := int(bx.x), int(bx.y)
+		z.Draw(img, image.Rect(x, y, x+w, y+h), image.NewUniform(parseColor(bx.fill)), image.Point{})
+	}
+
+	for _, t := range l.texts {
+		d := font.Drawer{Dst: img, Src: image.NewUniform(parseColor(t.fill)), Face: fs.face(t.size, t.bold)}
+		x := fixed.I(t.x)
+		if t.right {
+			x -= d.MeasureString(t.s)
+		}
+		d.Dot = fixed.Point26_6{X: x, Y: fixed.I(t.y)}
+		d.DrawString(t.s)
+	}
+	return png.Encode(w, img)
+}
+
+// description is the card's text alternative.
+func (c *Card) description() string {
+	s := fmt.Sprintf("%s: %d°%s, %s. High %d°, low %d°.", c.Location, c.Temperature, c.Unit, c.Condition, c.High, c.Low)
+	if c.Alert != "" {
+		s += " " + c.Alert + "."
+	}
+	return s
+}
+
+func roundRect(z *vector.Rasterizer, w, h, r float32) {
+	k := r * 0.5523
+	z.MoveTo(r, 0)
+	z.LineTo(w-r, 0)
+	z.CubeTo(w-r+k, 0, w, r-k, w, r)
+	z.LineTo(w, h-r)
+	z.CubeTo(w, h-r+k, w-r+k, h, w-r, h)
+	z.LineTo(r, h)
+	z.CubeTo(r-k, h, 0, h-r+k, 0, h-r)
+	z.LineTo(0, r)
+	z.CubeTo(0, r-k, r-k, 0, r, 0)
+	z.ClosePath()
+}
+
+func fillColor(fill string) string {
+	if fill == background {
+		return backgroundColor
+	}
+	return fill
+}
+
+// parseColor parses "#rrggbb".
+func parseColor(s string) color.RGBA {
+	v, _ := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
+
+// fonts parses the Go fonts once. Faces aren't safe for concurrent use, so
+// each render makes its own from these.
+var fonts = sync.OnceValues(func() (*opentype.Font, *opentype.Font) {
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return regular, bold
+})
+
+type faceKey struct {
+	size float64
+	bold bool
+}
+
+// faces holds one render's font faces.
+type faces map[faceKey]font.Face
+
+// face returns the Go font at size pixels.
+func (fs faces) face(size float64, bold bool) font.Face {
+	k := faceKey{size, bold}
+	if f, ok := fs[k]; ok {
+		return f
+	}
+	regular, boldFont := fonts()
+	f := regular
+	if bold {
+		f = boldFont
+	}
+	fc, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		panic(err)
+	}
+	fs[k] = fc
+	return fc
+}
+
+// measure returns the width of s in pixels.
+func (fs faces) measure(s string, size float64, bold bool) int {
+	return font.MeasureString(fs.face(size, bold), s).Ceil()
+}
+
+// fit shortens s with an ellipsis until it is no wider than maxWidth.
+func (fs faces) fit(s string, size float64, bold bool, maxWidth int) string {
+	if fs.measure(s, size, bold) <= maxWidth {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 {
+		r = r[:len(r)-1]
+		if t := strings.TrimSpace(string(r)) + "…"; fs.measure(t, size, bold) <= maxWidth {
+			return t
+		}
+	}
+	return "…"
+}
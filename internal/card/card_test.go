@@ -0,0 +1,112 @@
+package card
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func testWeather() *weather.WeatherData {
+	return &weather.WeatherData{
+		Location: "Oklahoma City, Oklahoma",
+		Current: weather.CurrentCondition{
+			Temperature: 68, TemperatureUnit: "F", ShortForecast: "Sunny",
+			HighTemp: 75, LowTemp: 52, Icon: "sunny",
+		},
+		Alerts: []weather.Alert{
+			{Event: "Wind Advisory", Severity: "Moderate"},
+			{Event: "Tornado Warning", Severity: "Extreme"},
+			{Event: "Tornado Warning", Severity: "Extreme", MessageType: "Cancel"},
+		},
+	}
+}
+
+func TestFromWeather(t *testing.T) {
+	c := FromWeather(testWeather())
+	if c.Alert != "Tornado Warning" || c.Alerts != 2 {
+		t.Errorf("expected the most severe alert, got %q of %d", c.Alert, c.Alerts)
+	}
+	if c.Temperature != 68 || c.Unit != "F" || c.High != 75 || c.Icon != "sunny" {
+		t.Errorf("unexpected card %+v", c)
+	}
+
+	// Severities are matched as NWS sends them or not, and an alert with an
+	// unknown one still shows when it's the only one.
+	wd := testWeather()
+	wd.Alerts = []weather.Alert{{Event: "Test Message", Severity: "Unknown"}, {Event: "Flood Warning", Severity: "severe"}}
+	if c := FromWeather(wd); c.Alert != "Flood Warning" {
+		t.Errorf("expected the severe alert, got %q", c.Alert)
+	}
+	wd.Alerts = []weather.Alert{{Event: "Test Message"}}
+	if c := FromWeather(wd); c.Alert != "Test Message" {
+		t.Errorf("expected the only alert, got %q", c.Alert)
+	}
+}
+
+func TestIcons(t *testing.T) {
+	// Every name mapIcon can return.
+	for _, name := range []string{
+		"sunny", "clear_night", "partly_cloudy_day", "partly_cloudy_night", "cloud",
+		"rainy", "thunderstorm", "weather_snowy", "foggy", "air", "thermostat",
+	} {
+		if !HasIcon(name) {
+			t.Errorf("no icon for %s", name)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	c := FromWeather(testWeather())
+	c.Location = "Oklahoma City & <County>"
+	var buf bytes.Buffer
+	if err := c.WriteSVG(&buf); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+	svg := buf.String()
+	for _, want := range []string{
+		`width="1200" height="630"`,
+		`>Oklahoma City &amp; &lt;County&gt;</text>`,
+		`>68°F</text>`,
+		`>Tornado Warning +1</text>`,
+		`fill="#fbbf24"`, // The sun
+		`aria-label="Oklahoma City &amp; &lt;County&gt;: 68°F, Sunny. High 75°, low 52°. Tornado Warning."`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected %q in SVG", want)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	c := FromWeather(testWeather())
+	c.Location = strings.Repeat("Very Long Place Name ", 10)
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf); err != nil {
+		t.Fatalf("WritePNG failed: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("bad PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != Width || b.Dy() != Height {
+		t.Fatalf("unexpected size %v", b)
+	}
+	check := func(x, y int, want string) {
+		t.Helper()
+		r, g, b, _ := img.At(x, y).RGBA()
+		got := parseColor(want)
+		if uint8(r>>8) != got.R || uint8(g>>8) != got.G || uint8(b>>8) != got.B {
+			t.Errorf("pixel %d,%d: expected %s, got %v", x, y, want, img.At(x, y))
+		}
+	}
+	check(5, 5, backgroundColor)
+	check(80+130, 170+130, sunColor) // Middle of the icon
+	check(84, 560, badgeColor)       // Left end of the alert badge
+
+	if fit := (faces{}).fit(c.Location, 56, true, Width-160); !strings.HasSuffix(fit, "…") {
+		t.Errorf("expected the location to be shortened, got %q", fit)
+	}
+}
@@ -0,0 +1,177 @@
+package card
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// iconSize is the side of the square grid icons are drawn on.
+const iconSize = 24
+
+// background is the layer color that paints the card's background, used
+// to cut the crescent out of the moon.
+const background = "bg"
+
+// op is one path command: 'M' (move to), 'L' (line to), 'C' (cubic Bézier
+// to) or 'Z' (close), with its points.
+type op struct {
+	cmd  byte
+	args []float32
+}
+
+// layer is one filled shape of an icon.
+type layer struct {
+	fill string // "#rrggbb", or background
+	path []op
+}
+
+// Icon colors.
+const (
+	sunColor     = "#fbbf24"
+	cloudColor   = "#e2e8f0"
+	stormColor   = "#94a3b8"
+	rainColor    = "#60a5fa"
+	boltColor    = "#facc15"
+	snowColor    = "#ffffff"
+	mistColor    = "#cbd5e1"
+	mercuryColor = "#f87171"
+)
+
+// icons are vector stand-ins for the Material Symbols names mapIcon
+// returns, drawn on a 24×24 grid.
+var icons = map[string][]layer{
+	"sunny":               sun(12, 12, 1),
+	"clear_night":         moon(12, 12, 1),
+	"partly_cloudy_day":   join(sun(8.5, 8.5, 0.7), cloud(2.5, 3, 0.85, cloudColor)),
+	"partly_cloudy_night": join(moon(8.5, 8, 0.65), cloud(2.5, 3, 0.85, cloudColor)),
+	"cloud":               cloud(0, 0, 1, cloudColor),
+	"rainy": join(cloud(0, -3, 1, cloudColor),
+		line(8, 17, 7, 20.5, 1.6, rainColor),
+		line(12, 17, 11, 20.5, 1.6, rainColor),
+		line(16, 17, 15, 20.5, 1.6, rainColor)),
+	"thunderstorm": join(cloud(0, -3, 1, stormColor),
+		poly(boltColor, 13, 13.5, 14.5, 13.5, 13, 16.5, 15.5, 16.5, 10.5, 22.5, 12, 18.5, 9.5, 18.5)),
+	"weather_snowy": join(cloud(0, -3, 1, cloudColor),
+		circle(8, 18.5, 1.1, snowColor),
+		circle(12, 19.5, 1.1, snowColor),
+		circle(16, 18.5, 1.1, snowColor),
+		circle(10, 22, 1.1, snowColor),
+		circle(14, 22, 1.1, snowColor)),
+	"foggy": join(rect(4, 7.5, 16, 1.8, mistColor),
+		rect(6, 11, 14, 1.8, mistColor),
+		rect(4, 14.5, 16, 1.8, mistColor),
+		rect(7, 18, 10, 1.8, mistColor)),
+	"air": join(rect(3, 8, 13, 1.8, mistColor),
+		circle(16, 8.9, 2, mistColor), circle(16, 8.9, 1, background),
+		rect(3, 12, 18, 1.8, mistColor),
+		rect(3, 16, 11, 1.8, mistColor),
+		circle(14, 16.9, 2, mistColor), circle(14, 16.9, 1, background)),
+	"thermostat": join(rect(10, 2.5, 4, 13, cloudColor),
+		circle(12, 2.5, 2, cloudColor),
+		circle(12, 17.5, 4, mercuryColor),
+		rect(11, 8, 2, 9, mercuryColor)),
+}
+
+// HasIcon reports whether name, a mapIcon name, has a bundled icon.
+func HasIcon(name string) bool {
+	_, ok := icons[name]
+	return ok
+}
+
+// iconLayers returns the layers for a mapIcon name, falling back to the
+// thermometer like mapIcon does.
+func iconLayers(name string) []layer {
+	if l, ok := icons[name]; ok {
+		return l
+	}
+	return icons["thermostat"]
+}
+
+func join(parts ...[]layer) []layer {
+	var out []layer
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// circle approximates a circle with four cubic Béziers.
+func circle(cx, cy, r float32, fill string) []layer {
+	k := r * 0.5523
+	return []layer{{fill, []op{
+		{'M', []float32{cx + r, cy}},
+		{'C', []float32{cx + r, cy + k, cx + k, cy + r, cx, cy + r}},
+		{'C', []float32{cx - k, cy + r, cx - r, cy + k, cx - r, cy}},
+		{'C', []float32{cx - r, cy - k, cx - k, cy - r, cx, cy - r}},
+		{'C', []float32{cx + k, cy - r, cx + r, cy - k, cx + r, cy}},
+		{'Z', nil},
+	}}}
+}
+
+// poly is a closed polygon through the x, y pairs in pts.
+func poly(fill string, pts ...float32) []layer {
+	path := []op{{'M', pts[:2]}}
+	for i := 2; i+1 < len(pts); i += 2 {
+		path = append(path, op{'L', pts[i : i+2]})
+	}
+	return []layer{{fill, append(path, op{'Z', nil})}}
+}
+
+func rect(x, y, w, h float32, fill string) []layer {
+	return poly(fill, x, y, x+w, y, x+w, y+h, x, y+h)
+}
+
+// line is a straight stroke of width w.
+func line(x1, y1, x2, y2, w float32, fill string) []layer {
+	dx, dy := x2-x1, y2-y1
+	n := float32(math.Hypot(float64(dx), float64(dy)))
+	ox, oy := -dy/n*w/2, dx/n*w/2
+	return poly(fill, x1+ox, y1+oy, x2+ox, y2+oy, x2-ox, y2-oy, x1-ox, y1-oy)
+}
+
+// sun is a sun centred on cx, cy, scaled by s.
+func sun(cx, cy, s float32) []layer {
+	out := circle(cx, cy, 4.5*s, sunColor)
+	for i := range 8 {
+		a := float64(i) * math.Pi / 4
+		sin, cos := float32(math.Sin(a)), float32(math.Cos(a))
+		out = append(out, line(cx+7*s*cos, cy+7*s*sin, cx+10*s*cos, cy+10*s*sin, 1.8*s, sunColor)...)
+	}
+	return out
+}
+
+// moon is a crescent centred on cx, cy, scaled by s.
+func moon(cx, cy, s float32) []layer {
+	return join(circle(cx, cy, 7.5*s, cloudColor), circle(cx+3.5*s, cy-3*s, 6.5*s, background))
+}
+
+// cloud is the cloud shape scaled by s and moved by dx, dy.
+func cloud(dx, dy, s float32, fill string) []layer {
+	at := func(x, y float32) (float32, float32) { return x*s + dx, y*s + dy }
+	x1, y1 := at(8.5, 14)
+	x2, y2 := at(13, 11)
+	x3, y3 := at(17.5, 14)
+	rx, ry := at(8.5, 14)
+	return join(
+		circle(x1, y1, 3.5*s, fill),
+		circle(x2, y2, 5*s, fill),
+		circle(x3, y3, 3.5*s, fill),
+		rect(rx, ry, 9*s, 3.5*s, fill),
+	)
+}
+
+// svgPath formats a path as SVG path data.
+func svgPath(path []op) string {
+	var b strings.Builder
+	for _, o := range path {
+		b.WriteByte(o.cmd)
+		for i, v := range o.args {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%g", math.Round(float64(v)*100)/100)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AlertVersion is one NWS alert message as recorded in the alert history.
+// Each update or cancellation of an alert is a new message with its own ID.
+// FirstSeen and LastSeen are when the poller first and last saw it active
+// for the scope it was looked up by.
+type AlertVersion struct {
+	ID          string    `json:"id"`
+	Event       string    `json:"event"`
+	Severity    string    `json:"severity"`
+	Headline    string    `json:"headline"`
+	Description string    `json:"description"`
+	Instruction string    `json:"instruction,omitempty"`
+	AreaDesc    string    `json:"area_desc"`
+	MessageType string    `json:"message_type,omitempty"`
+	Sent        time.Time `json:"sent"`
+	Effective   time.Time `json:"effective"`
+	Onset       time.Time `json:"onset"`
+	Expires     time.Time `json:"expires"`
+	Ends        time.Time `json:"ends"`
+	References  []string  `json:"references"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Point is a watched location.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// RecordAlerts stores the alerts active for scope (a PointKey or a state
+// code) at time seen. New versions are inserted; versions already recorded
+// for the scope just have their last seen time moved forward.
+func (db *DB) RecordAlerts(scope string, alerts []AlertVersion, seen time.Time) error {
+	defer observeQuery("record_alerts", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seen = seen.UTC()
+	for _, a := range alerts {
+		if a.References == nil {
+			a.References = []string{}
+		}
+		refs, err := json.Marshal(a.References)
+		if err != nil {
+			return err
+		}
+
+		// An alert version never changes once issued, so the first copy wins.
+		_, err = tx.Exec(`
+			INSERT INTO alerts (id, event, severity, headline, description, instruction, area_desc,
+				message_type, sent, effective, onset, expires, ends, refs)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO NOTHING
+		`, a.ID, a.Event, a.Severity, a.Headline, a.Description, a.Instruction, a.AreaDesc,
+			a.MessageType, nullTime(a.Sent), nullTime(a.Effective), nullTime(a.Onset),
+			nullTime(a.Expires), nullTime(a.Ends), string(refs))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO alert_scopes (alert_id, scope, first_seen, last_seen)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(alert_id, scope) DO UPDATE SET last_seen = excluded.last_seen
+		`, a.ID, scope, seen, seen)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AlertHistory returns the alert versions recorded for scope that were
+// still active at or after since, most recently issued first.
+func (db *DB) AlertHistory(ctx context.Context, scope string, since time.Time, limit int) ([]AlertVersion, error) {
+	ctx, done := startQuery(ctx, "alert_history")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT a.id, a.event, a.severity, a.headline, a.description, a.instruction, a.area_desc,
+			a.message_type, a.sent, a.effective, a.onset, a.expires, a.ends, a.refs,
+			s.first_seen, s.last_seen
+		FROM alert_scopes s
+		JOIN alerts a ON a.id = s.alert_id
+		WHERE s.scope = ? AND s.last_seen >= ?
+		ORDER BY a.sent DESC, s.first_seen DESC
+		LIMIT ?
+	`, scope, since.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []AlertVersion{}
+	for rows.Next() {
+		var a AlertVersion
+		var refs string
+		var sent, effective, onset, expires, ends sql.NullTime
+		err := rows.Scan(&a.ID, &a.Event, &a.Severity, &a.Headline, &a.Description, &a.Instruction, &a.AreaDesc,
+			&a.MessageType, &sent, &effective, &onset, &expires, &ends, &refs, &a.FirstSeen, &a.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+		a.Sent, a.Effective, a.Onset, a.Expires, a.Ends = sent.Time, effective.Time, onset.Time, expires.Time, ends.Time
+		if err := json.Unmarshal([]byte(refs), &a.References); err != nil {
+			return nil, fmt.Errorf("failed to decode alert references: %w", err)
+		}
+		history = append(history, a)
+	}
+	return history, rows.Err()
+}
+
+// WatchedPoints returns the distinct locations that push subscriptions and
+// webhooks are watching, at PointKey precision.
+func (db *DB) WatchedPoints() ([]Point, error) {
+	defer observeQuery("watched_points", time.Now())
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT ROUND(latitude, 2), ROUND(longitude, 2) FROM (
+			SELECT latitude, longitude FROM push_subscriptions
+			UNION ALL
+			SELECT latitude, longitude FROM webhooks
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []Point
+	for rows.Next() {
+		var p Point
+		if err := rows.Scan(&p.Latitude, &p.Longitude); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// nullTime stores zero times as NULL.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
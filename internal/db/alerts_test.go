@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAlertHistory(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	issued := time.Date(2025, 5, 6, 20, 0, 0, 0, time.UTC)
+	warning := AlertVersion{
+		ID:          "urn:oid:1",
+		Event:       "Tornado Warning",
+		Severity:    "Extreme",
+		MessageType: "Alert",
+		Sent:        issued,
+		Expires:     issued.Add(45 * time.Minute),
+	}
+	update := AlertVersion{
+		ID:          "urn:oid:2",
+		Event:       "Tornado Warning",
+		Severity:    "Extreme",
+		MessageType: "Update",
+		Sent:        issued.Add(20 * time.Minute),
+		References:  []string{"urn:oid:1"},
+	}
+
+	scope := PointKey(35.4676, -97.5164)
+	if scope != "35.47,-97.52" {
+		t.Fatalf("Unexpected point key %q", scope)
+	}
+
+	polls := []struct {
+		at     time.Time
+		alerts []AlertVersion
+	}{
+		{issued.Add(time.Minute), []AlertVersion{warning}},
+		{issued.Add(21 * time.Minute), []AlertVersion{warning, update}},
+		{issued.Add(26 * time.Minute), []AlertVersion{update}},
+	}
+	for _, p := range polls {
+		if err := testDB.RecordAlerts(scope, p.alerts, p.at); err != nil {
+			t.Fatalf("RecordAlerts failed: %v", err)
+		}
+	}
+	// Another scope sees the same alert independently
+	if err := testDB.RecordAlerts("OK", []AlertVersion{warning}, issued.Add(5*time.Minute)); err != nil {
+		t.Fatalf("RecordAlerts failed: %v", err)
+	}
+
+	history, err := testDB.AlertHistory(context.Background(), scope, issued.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("AlertHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 versions, got %+v", history)
+	}
+	if history[0].ID != "urn:oid:2" || history[0].References[0] != "urn:oid:1" || !history[0].Onset.IsZero() {
+		t.Errorf("Expected the update first, got %+v", history[0])
+	}
+	if !history[1].FirstSeen.Equal(issued.Add(time.Minute)) || !history[1].LastSeen.Equal(issued.Add(21*time.Minute)) {
+		t.Errorf("Unexpected seen range %v - %v", history[1].FirstSeen, history[1].LastSeen)
+	}
+	if !history[1].Expires.Equal(issued.Add(45 * time.Minute)) {
+		t.Errorf("Unexpected expiry %v", history[1].Expires)
+	}
+
+	// since filters on when the version was last active
+	history, err = testDB.AlertHistory(context.Background(), scope, issued.Add(25*time.Minute), 10)
+	if err != nil || len(history) != 1 || history[0].ID != "urn:oid:2" {
+		t.Errorf("Expected only the update after 25 minutes, got %+v err=%v", history, err)
+	}
+}
+
+func TestWatchedPoints(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	testDB.SavePushSubscription(context.Background(), PushSubscription{Endpoint: "https://push.example/1", P256dh: "k", Auth: "a", Latitude: 35.4676, Longitude: -97.5164})
+	testDB.SavePushSubscription(context.Background(), PushSubscription{Endpoint: "https://push.example/2", P256dh: "k", Auth: "a", Latitude: 35.4712, Longitude: -97.5199})
+	testDB.CreateWebhook(context.Background(), &Webhook{URL: "https://hooks.example", Latitude: 40.71, Longitude: -74.01})
+
+	points, err := testDB.WatchedPoints()
+	if err != nil {
+		t.Fatalf("WatchedPoints failed: %v", err)
+	}
+	if len(points) != 2 {
+		t.Errorf("Expected 2 distinct points, got %+v", points)
+	}
+}
@@ -1,7 +1,11 @@
 package db
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -118,6 +122,186 @@ func initSchema(db *sql.DB) error {
 		return err
 	}
 
+	savedQuery := `
+	CREATE TABLE IF NOT EXISTS saved_locations (
+		token TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err = db.Exec(savedQuery)
+	if err != nil {
+		return err
+	}
+
+	pushQuery := `
+	CREATE TABLE IF NOT EXISTS push_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		endpoint TEXT NOT NULL UNIQUE,
+		p256dh TEXT NOT NULL,
+		auth TEXT NOT NULL,
+		latitude REAL NOT NULL,
+		longitude REAL NOT NULL,
+		seen_alerts TEXT NOT NULL DEFAULT '[]',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err = db.Exec(pushQuery)
+	if err != nil {
+		return err
+	}
+
+	webhookQuery := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		latitude REAL NOT NULL,
+		longitude REAL NOT NULL,
+		min_severity TEXT NOT NULL DEFAULT '',
+		events TEXT NOT NULL DEFAULT '[]',
+		seen_alerts TEXT NOT NULL DEFAULT '[]',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		alert_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		attempt INTEGER NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, alert_id);
+	`
+	_, err = db.Exec(webhookQuery)
+	if err != nil {
+		return err
+	}
+
+	alertsQuery := `
+	CREATE TABLE IF NOT EXISTS alerts (
+		id TEXT PRIMARY KEY,
+		event TEXT NOT NULL,
+		severity TEXT NOT NULL DEFAULT '',
+		headline TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		instruction TEXT NOT NULL DEFAULT '',
+		area_desc TEXT NOT NULL DEFAULT '',
+		message_type TEXT NOT NULL DEFAULT '',
+		sent DATETIME,
+		effective DATETIME,
+		onset DATETIME,
+		expires DATETIME,
+		ends DATETIME,
+		refs TEXT NOT NULL DEFAULT '[]'
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_scopes (
+		alert_id TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		PRIMARY KEY (alert_id, scope)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alert_scopes_scope ON alert_scopes(scope, last_seen);
+	`
+	_, err = db.Exec(alertsQuery)
+	if err != nil {
+		return err
+	}
+
+	zonesQuery := `
+	CREATE TABLE IF NOT EXISTS zones (
+		url TEXT PRIMARY KEY,
+		geometry TEXT NOT NULL,
+		fetched_at DATETIME NOT NULL
+	);
+	`
+	_, err = db.Exec(zonesQuery)
+	if err != nil {
+		return err
+	}
+
+	digestQuery := `
+	CREATE TABLE IF NOT EXISTS digest_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL,
+		latitude REAL NOT NULL,
+		longitude REAL NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		time_zone TEXT NOT NULL,
+		send_hour INTEGER NOT NULL,
+		units TEXT NOT NULL DEFAULT 'us',
+		confirm_token TEXT NOT NULL UNIQUE,
+		unsubscribe_token TEXT NOT NULL UNIQUE,
+		confirmed INTEGER NOT NULL DEFAULT 0,
+		confirm_sent_at DATETIME,
+		pending_name TEXT,
+		pending_time_zone TEXT,
+		pending_send_hour INTEGER,
+		pending_units TEXT,
+		last_sent TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (email, latitude, longitude)
+	);
+	`
+	_, err = db.Exec(digestQuery)
+	if err != nil {
+		return err
+	}
+
+	observationsQuery := `
+	CREATE TABLE IF NOT EXISTS stations (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		time_zone TEXT NOT NULL DEFAULT 'UTC'
+	);
+
+	CREATE TABLE IF NOT EXISTS observations (
+		station TEXT NOT NULL,
+		observed_at DATETIME NOT NULL,
+		local_date TEXT NOT NULL,
+		temperature REAL,
+		precip_last_hour REAL,
+		description TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (station, observed_at)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_observations_date ON observations(station, local_date);
+	`
+	_, err = db.Exec(observationsQuery)
+	if err != nil {
+		return err
+	}
+
+	forecastsQuery := `
+	CREATE TABLE IF NOT EXISTS forecast_sites (
+		id TEXT PRIMARY KEY,
+		latitude REAL NOT NULL,
+		longitude REAL NOT NULL,
+		station TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS issued_forecasts (
+		site TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		target TEXT NOT NULL,
+		issued_at DATETIME NOT NULL,
+		lead_hours INTEGER NOT NULL,
+		value REAL NOT NULL,
+		PRIMARY KEY (site, kind, target, issued_at)
+	);
+	`
+	_, err = db.Exec(forecastsQuery)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -128,15 +312,23 @@ type CacheEntry struct {
 	CreatedAt time.Time
 }
 
+// PointKey identifies a location at the 2 decimal place (about 1.1km)
+// precision used for cache entries and alert history.
+func PointKey(lat, lon float64) string {
+	return fmt.Sprintf("%.2f,%.2f", lat, lon)
+}
+
 // GetCachedWeather retrieves weather data if valid
-func (db *DB) GetCachedWeather(lat, lon float64) (*CacheEntry, error) {
+func (db *DB) GetCachedWeather(ctx context.Context, lat, lon float64) (*CacheEntry, error) {
+	ctx, done := startQuery(ctx, "get_cached_weather")
+	defer done()
 	// Round to 2 decimal places to match key generation
-	key := fmt.Sprintf("%.2f,%.2f", lat, lon)
+	key := PointKey(lat, lon)
 
 	var data string
 	var expiresAt, createdAt time.Time
 
-	err := db.QueryRow("SELECT data, expires_at, created_at FROM weather_cache WHERE id = ? AND expires_at > ?", key, time.Now()).Scan(&data, &expiresAt, &createdAt)
+	err := db.QueryRowContext(ctx, "SELECT data, expires_at, created_at FROM weather_cache WHERE id = ? AND expires_at > ?", key, time.Now()).Scan(&data, &expiresAt, &createdAt)
 	if err == sql.ErrNoRows {
 		return nil, nil // Cache miss
 	}
@@ -151,12 +343,34 @@ func (db *DB) GetCachedWeather(lat, lon float64) (*CacheEntry, error) {
 	}, nil
 }
 
+// GetLastCachedWeather retrieves the most recent weather data for a point
+// even if it has expired, e.g. to compare a refreshed forecast with.
+func (db *DB) GetLastCachedWeather(ctx context.Context, lat, lon float64) (*CacheEntry, error) {
+	ctx, done := startQuery(ctx, "get_last_cached_weather")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var entry CacheEntry
+	err := db.QueryRowContext(ctx, "SELECT data, expires_at, created_at FROM weather_cache WHERE id = ?", PointKey(lat, lon)).Scan(&entry.Data, &entry.ExpiresAt, &entry.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
 // SetCachedWeather saves weather data
-func (db *DB) SetCachedWeather(lat, lon float64, data string, duration time.Duration) error {
-	key := fmt.Sprintf("%.2f,%.2f", lat, lon)
+func (db *DB) SetCachedWeather(ctx context.Context, lat, lon float64, data string, duration time.Duration) error {
+	ctx, done := startQuery(ctx, "set_cached_weather")
+	defer done()
+	key := PointKey(lat, lon)
 	expiresAt := time.Now().Add(duration)
 
-	_, err := db.Exec(`
+	_, err := db.ExecContext(ctx, `
 		INSERT INTO weather_cache (id, data, expires_at)
 		VALUES (?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
@@ -178,7 +392,9 @@ type Place struct {
 }
 
 // SearchPlaces searches for places matching the query
-func (db *DB) SearchPlaces(query string) ([]Place, error) {
+func (db *DB) SearchPlaces(ctx context.Context, query string) ([]Place, error) {
+	ctx, done := startQuery(ctx, "search_places")
+	defer done()
 	terms := strings.Fields(query)
 	if len(terms) == 0 {
 		return nil, nil
@@ -212,7 +428,7 @@ func (db *DB) SearchPlaces(query string) ([]Place, error) {
 	LIMIT 10;
 	`
 
-	rows, err := db.Query(q, ftsQuery)
+	rows, err := db.QueryContext(ctx, q, ftsQuery)
 	if err != nil {
 		// Provide more context about the error, especially for FTS5 query issues
 		return nil, fmt.Errorf("failed to execute search query (query: %q): %w", ftsQuery, err)
@@ -237,6 +453,67 @@ func (db *DB) SearchPlaces(query string) ([]Place, error) {
 	return places, nil
 }
 
+// PlaceSlug returns the URL form of a place name used in permalinks,
+// e.g. "St. Louis" -> "st-louis".
+func PlaceSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// FindPlace looks up a place by state code and PlaceSlug of its name. When
+// several places share a name the most populous one wins. Returns nil if
+// nothing matches.
+func (db *DB) FindPlace(ctx context.Context, state, slug string) (*Place, error) {
+	ctx, done := startQuery(ctx, "find_place")
+	defer done()
+	if slug == "" {
+		return nil, nil
+	}
+
+	// Narrow candidates with LIKE ("st-louis" -> "st%louis") and then compare
+	// slugs exactly, since punctuation can't be normalized in SQL.
+	pattern := strings.ReplaceAll(slug, "-", "%")
+	rows, err := db.QueryContext(ctx, `
+		SELECT name, state, zip, latitude, longitude
+		FROM places
+		WHERE state = ? COLLATE NOCASE AND name LIKE ?
+		ORDER BY population DESC, id
+	`, state, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up place: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Place
+		var zip sql.NullString
+		if err := rows.Scan(&p.Name, &p.State, &zip, &p.Latitude, &p.Longitude); err != nil {
+			return nil, fmt.Errorf("failed to scan place: %w", err)
+		}
+		p.Zip = zip.String
+		if PlaceSlug(p.Name) == slug {
+			return &p, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating places: %w", err)
+	}
+
+	return nil, nil
+}
+
 // sanitizeFTSTerm sanitizes a search term for use in FTS5 queries
 // It removes or escapes characters that have special meaning in FTS5
 func sanitizeFTSTerm(term string) string {
@@ -261,7 +538,9 @@ func sanitizeFTSTerm(term string) string {
 }
 
 // SaveAppInterest inserts a new record into the app_interest table
-func (db *DB) SaveAppInterest(email string, android bool, ios bool, country string) error {
+func (db *DB) SaveAppInterest(ctx context.Context, email string, android bool, ios bool, country string) error {
+	ctx, done := startQuery(ctx, "save_app_interest")
+	defer done()
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
@@ -275,10 +554,94 @@ func (db *DB) SaveAppInterest(email string, android bool, ios bool, country stri
 		i = 1
 	}
 
-	_, err := db.Exec(`
+	_, err := db.ExecContext(ctx, `
 		INSERT INTO app_interest (email, android, ios, country)
 		VALUES (?, ?, ?, ?)
 	`, email, a, i, country)
 
 	return err
 }
+
+// SavedLocation is one entry in a user's saved locations list
+type SavedLocation struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// CreateSavedLocations stores a saved locations list under a new random
+// token and returns the token. The token is the only credential, so it is
+// long enough to be unguessable.
+func (db *DB) CreateSavedLocations(ctx context.Context, locations []SavedLocation) (string, error) {
+	ctx, done := startQuery(ctx, "create_saved_locations")
+	defer done()
+	if db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	data, err := json.Marshal(locations)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO saved_locations (token, data) VALUES (?, ?)", token, string(data)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetSavedLocations returns the list stored under token, or nil if the token
+// is unknown.
+func (db *DB) GetSavedLocations(ctx context.Context, token string) ([]SavedLocation, error) {
+	ctx, done := startQuery(ctx, "get_saved_locations")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var data string
+	err := db.QueryRowContext(ctx, "SELECT data FROM saved_locations WHERE token = ?", token).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []SavedLocation{}
+	if err := json.Unmarshal([]byte(data), &locations); err != nil {
+		return nil, fmt.Errorf("failed to decode saved locations: %w", err)
+	}
+	return locations, nil
+}
+
+// UpdateSavedLocations replaces the list stored under token. It reports
+// false if the token is unknown.
+func (db *DB) UpdateSavedLocations(ctx context.Context, token string, locations []SavedLocation) (bool, error) {
+	ctx, done := startQuery(ctx, "update_saved_locations")
+	defer done()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	data, err := json.Marshal(locations)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.ExecContext(ctx, "UPDATE saved_locations SET data = ?, updated_at = CURRENT_TIMESTAMP WHERE token = ?", string(data), token)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
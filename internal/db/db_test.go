@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 )
 
 func setupTestDB(t *testing.T) *DB {
@@ -118,7 +120,7 @@ func TestSearchPlaces(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			places, err := testDB.SearchPlaces(tt.query)
+			places, err := testDB.SearchPlaces(context.Background(), tt.query)
 
 			if tt.expectError {
 				if err == nil {
@@ -215,7 +217,7 @@ func TestSearchPlacesErrorMessages(t *testing.T) {
 	// Test that error messages include the query for debugging
 	// We can't easily trigger FTS5 errors with valid SQLite, but we can
 	// verify the structure by checking normal queries still work
-	places, err := testDB.SearchPlaces("San")
+	places, err := testDB.SearchPlaces(context.Background(), "San")
 	if err != nil {
 		t.Errorf("Normal query should not error: %v", err)
 	}
@@ -307,7 +309,7 @@ func TestSaveAppInterest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := testDB.SaveAppInterest(tt.email, tt.android, tt.ios, tt.country)
+			err := testDB.SaveAppInterest(context.Background(), tt.email, tt.android, tt.ios, tt.country)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SaveAppInterest() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -356,7 +358,7 @@ func TestSaveAppInterest(t *testing.T) {
 
 func TestSaveAppInterestNilDB(t *testing.T) {
 	var db *DB
-	err := db.SaveAppInterest("test@example.com", true, true, "US")
+	err := db.SaveAppInterest(context.Background(), "test@example.com", true, true, "US")
 	if err == nil {
 		t.Error("Expected error for nil database, got nil")
 	}
@@ -365,3 +367,145 @@ func TestSaveAppInterestNilDB(t *testing.T) {
 		t.Errorf("Expected error message %q, got %q", expectedMsg, err.Error())
 	}
 }
+
+func TestPlaceSlug(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"San Francisco", "san-francisco"},
+		{"St. Louis", "st-louis"},
+		{"Winston-Salem", "winston-salem"},
+		{"  Coeur d'Alene ", "coeur-d-alene"},
+		{"94102", "94102"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := PlaceSlug(tt.input); got != tt.expected {
+				t.Errorf("PlaceSlug(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindPlace(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	if _, err := testDB.Exec(
+		"INSERT INTO places (name, state, latitude, longitude, population) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)",
+		"St. Louis", "MO", 38.6270, -90.1994, 300000,
+		"St Louis", "MO", 38.0, -90.0, 10,
+	); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		state    string
+		slug     string
+		expected string
+		lat      float64
+	}{
+		{"simple", "CA", "san-francisco", "San Francisco", 37.7749},
+		{"lowercase state", "ny", "new-york", "New York", 40.7128},
+		{"punctuation and population", "MO", "st-louis", "St. Louis", 38.6270},
+		{"wrong state", "NY", "san-francisco", "", 0},
+		{"partial slug", "CA", "san", "", 0},
+		{"empty slug", "CA", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := testDB.FindPlace(context.Background(), tt.state, tt.slug)
+			if err != nil {
+				t.Fatalf("FindPlace returned error: %v", err)
+			}
+			if tt.expected == "" {
+				if p != nil {
+					t.Errorf("Expected no place, got %+v", p)
+				}
+				return
+			}
+			if p == nil {
+				t.Fatalf("Expected %q, got nil", tt.expected)
+			}
+			if p.Name != tt.expected || p.Latitude != tt.lat {
+				t.Errorf("Expected %q at %f, got %q at %f", tt.expected, tt.lat, p.Name, p.Latitude)
+			}
+		})
+	}
+}
+
+func TestSavedLocations(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	locations := []SavedLocation{
+		{Name: "Home", Latitude: 37.77, Longitude: -122.42},
+		{Name: "Office", Latitude: 37.33, Longitude: -121.89},
+	}
+
+	token, err := testDB.CreateSavedLocations(context.Background(), locations)
+	if err != nil {
+		t.Fatalf("CreateSavedLocations failed: %v", err)
+	}
+	if len(token) != 32 {
+		t.Errorf("Expected 32 character token, got %q", token)
+	}
+
+	got, err := testDB.GetSavedLocations(context.Background(), token)
+	if err != nil {
+		t.Fatalf("GetSavedLocations failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != locations[0] || got[1] != locations[1] {
+		t.Errorf("Expected %+v, got %+v", locations, got)
+	}
+
+	found, err := testDB.UpdateSavedLocations(context.Background(), token, locations[1:])
+	if err != nil || !found {
+		t.Fatalf("UpdateSavedLocations failed: found=%v err=%v", found, err)
+	}
+	got, err = testDB.GetSavedLocations(context.Background(), token)
+	if err != nil {
+		t.Fatalf("GetSavedLocations failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "Office" {
+		t.Errorf("Expected only Office after update, got %+v", got)
+	}
+
+	// Unknown tokens are reported rather than created
+	found, err = testDB.UpdateSavedLocations(context.Background(), "unknown", locations)
+	if err != nil || found {
+		t.Errorf("Expected found=false for unknown token, got found=%v err=%v", found, err)
+	}
+	got, err = testDB.GetSavedLocations(context.Background(), "unknown")
+	if err != nil || got != nil {
+		t.Errorf("Expected nil for unknown token, got %+v err=%v", got, err)
+	}
+}
+
+func TestGetLastCachedWeather(t *testing.T) {
+	db := setupTestDB(t)
+
+	entry, err := db.GetLastCachedWeather(context.Background(), 35.47, -97.52)
+	if err != nil || entry != nil {
+		t.Fatalf("expected no entry, got %+v, %v", entry, err)
+	}
+
+	if err := db.SetCachedWeather(context.Background(), 35.47, -97.52, `{"v":1}`, -time.Minute); err != nil {
+		t.Fatalf("SetCachedWeather failed: %v", err)
+	}
+	if cached, _ := db.GetCachedWeather(context.Background(), 35.47, -97.52); cached != nil {
+		t.Errorf("expected the expired entry to be a cache miss")
+	}
+	entry, err = db.GetLastCachedWeather(context.Background(), 35.4712, -97.5199)
+	if err != nil {
+		t.Fatalf("GetLastCachedWeather failed: %v", err)
+	}
+	if entry == nil || entry.Data != `{"v":1}` {
+		t.Errorf("expected the expired entry, got %+v", entry)
+	}
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DigestSubscription is a daily forecast email for one location. It is
+// only sent once Confirmed, which happens when the ConfirmToken link in the
+// opt-in email is followed. LastSent is the subscriber's local date of the
+// last digest, "2006-01-02".
+type DigestSubscription struct {
+	ID               int64
+	Email            string
+	Latitude         float64
+	Longitude        float64
+	Name             string
+	TimeZone         string
+	SendHour         int
+	Units            string
+	ConfirmToken     string
+	UnsubscribeToken string
+	Confirmed        bool
+	LastSent         string
+}
+
+// ConfirmPendingFor is how long a confirmation email stays outstanding:
+// until it is followed or this has passed, subscribing again is refused
+// with ErrConfirmationPending instead of sending another.
+const ConfirmPendingFor = 24 * time.Hour
+
+// ErrConfirmationPending is returned by SaveDigestSubscription when the
+// subscription already has a confirmation email awaiting its link.
+var ErrConfirmationPending = errors.New("digest confirmation already pending")
+
+// SaveDigestSubscription stores a subscription and fills in its ID and
+// tokens, issuing a new confirmation token. Subscribing the same email to
+// an unconfirmed location again replaces it; for a confirmed one the new
+// name, time zone, hour and units are held as a pending change until the
+// new token is confirmed, and the unsubscribe token and confirmed state are
+// kept.
+func (db *DB) SaveDigestSubscription(ctx context.Context, sub *DigestSubscription) error {
+	ctx, done := startQuery(ctx, "save_digest_subscription")
+	defer done()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	confirm, err := newToken()
+	if err != nil {
+		return err
+	}
+	unsubscribe, err := newToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sentAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, unsubscribe_token, confirmed, confirm_sent_at FROM digest_subscriptions
+		WHERE email = ? AND latitude = ? AND longitude = ?
+	`, sub.Email, sub.Latitude, sub.Longitude).Scan(&sub.ID, &sub.UnsubscribeToken, &sub.Confirmed, &sentAt)
+	switch {
+	case err == sql.ErrNoRows:
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO digest_subscriptions (email, latitude, longitude, name, time_zone, send_hour, units,
+				confirm_token, unsubscribe_token, confirm_sent_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, sub.Email, sub.Latitude, sub.Longitude, sub.Name, sub.TimeZone, sub.SendHour, sub.Units,
+			confirm, unsubscribe, now).Scan(&sub.ID)
+		if err != nil {
+			return err
+		}
+		sub.UnsubscribeToken, sub.Confirmed = unsubscribe, false
+	case err != nil:
+		return err
+	case sentAt.Valid && now.Sub(sentAt.Time) < ConfirmPendingFor:
+		return ErrConfirmationPending
+	case sub.Confirmed:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE digest_subscriptions SET pending_name = ?, pending_time_zone = ?, pending_send_hour = ?,
+				pending_units = ?, confirm_token = ?, confirm_sent_at = ?
+			WHERE id = ?
+		`, sub.Name, sub.TimeZone, sub.SendHour, sub.Units, confirm, now, sub.ID)
+	default:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE digest_subscriptions SET name = ?, time_zone = ?, send_hour = ?, units = ?,
+				confirm_token = ?, confirm_sent_at = ?
+			WHERE id = ?
+		`, sub.Name, sub.TimeZone, sub.SendHour, sub.Units, confirm, now, sub.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	sub.ConfirmToken = confirm
+	return nil
+}
+
+// ConfirmDigestSubscription marks the subscription with the given
+// confirmation token as confirmed, applying any pending change, and returns
+// it, or nil if the token is unknown.
+func (db *DB) ConfirmDigestSubscription(ctx context.Context, token string) (*DigestSubscription, error) {
+	ctx, done := startQuery(ctx, "confirm_digest_subscription")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var sub DigestSubscription
+	err := db.QueryRowContext(ctx, `
+		UPDATE digest_subscriptions SET confirmed = 1, confirm_sent_at = NULL,
+			name = COALESCE(pending_name, name),
+			time_zone = COALESCE(pending_time_zone, time_zone),
+			send_hour = COALESCE(pending_send_hour, send_hour),
+			units = COALESCE(pending_units, units),
+			pending_name = NULL, pending_time_zone = NULL, pending_send_hour = NULL, pending_units = NULL
+		WHERE confirm_token = ?
+		RETURNING `+digestColumns, token).Scan(sub.fields()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// DeleteDigestSubscription removes the subscription with the given
+// unsubscribe token. It reports false if the token is unknown.
+func (db *DB) DeleteDigestSubscription(ctx context.Context, token string) (bool, error) {
+	ctx, done := startQuery(ctx, "delete_digest_subscription")
+	defer done()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM digest_subscriptions WHERE unsubscribe_token = ?", token)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListDigestSubscriptions returns every confirmed subscription.
+func (db *DB) ListDigestSubscriptions() ([]DigestSubscription, error) {
+	defer observeQuery("list_digest_subscriptions", time.Now())
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query("SELECT " + digestColumns + " FROM digest_subscriptions WHERE confirmed = 1 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []DigestSubscription
+	for rows.Next() {
+		var sub DigestSubscription
+		if err := rows.Scan(sub.fields()...); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// SetDigestSent records the local date a subscription's digest was sent.
+func (db *DB) SetDigestSent(id int64, date string) error {
+	defer observeQuery("set_digest_sent", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec("UPDATE digest_subscriptions SET last_sent = ? WHERE id = ?", date, id)
+	return err
+}
+
+const digestColumns = "id, email, latitude, longitude, name, time_zone, send_hour, units, confirm_token, unsubscribe_token, confirmed, last_sent"
+
+// fields returns scan destinations in digestColumns order.
+func (sub *DigestSubscription) fields() []any {
+	return []any{&sub.ID, &sub.Email, &sub.Latitude, &sub.Longitude, &sub.Name, &sub.TimeZone, &sub.SendHour,
+		&sub.Units, &sub.ConfirmToken, &sub.UnsubscribeToken, &sub.Confirmed, &sub.LastSent}
+}
+
+// newToken returns a random 32 character hex token.
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDigestSubscriptions(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	sub := &DigestSubscription{
+		Email:     "me@example.com",
+		Latitude:  35.47,
+		Longitude: -97.52,
+		Name:      "Oklahoma City, OK",
+		TimeZone:  "America/Chicago",
+		SendHour:  7,
+		Units:     "us",
+	}
+	if err := testDB.SaveDigestSubscription(context.Background(), sub); err != nil {
+		t.Fatalf("SaveDigestSubscription failed: %v", err)
+	}
+	if sub.ID == 0 || len(sub.ConfirmToken) != 32 || len(sub.UnsubscribeToken) != 32 || sub.Confirmed {
+		t.Fatalf("Expected a new unconfirmed subscription with tokens, got %+v", sub)
+	}
+
+	// Asking again before confirming doesn't send another email
+	if err := testDB.SaveDigestSubscription(context.Background(), &DigestSubscription{Email: sub.Email, Latitude: 35.47, Longitude: -97.52, TimeZone: "UTC"}); !errors.Is(err, ErrConfirmationPending) {
+		t.Errorf("Expected ErrConfirmationPending, got %v", err)
+	}
+
+	// Unconfirmed subscriptions aren't sent
+	subs, err := testDB.ListDigestSubscriptions()
+	if err != nil || len(subs) != 0 {
+		t.Fatalf("Expected no confirmed subscriptions, got %+v err=%v", subs, err)
+	}
+
+	if got, err := testDB.ConfirmDigestSubscription(context.Background(), "nope"); err != nil || got != nil {
+		t.Errorf("Expected unknown token to confirm nothing, got %+v err=%v", got, err)
+	}
+	confirmed, err := testDB.ConfirmDigestSubscription(context.Background(), sub.ConfirmToken)
+	if err != nil || confirmed == nil || !confirmed.Confirmed || confirmed.Email != sub.Email {
+		t.Fatalf("ConfirmDigestSubscription failed: %+v err=%v", confirmed, err)
+	}
+
+	// Subscribing again stages the new schedule until it is confirmed too
+	again := &DigestSubscription{Email: sub.Email, Latitude: 35.47, Longitude: -97.52, Name: "Home", TimeZone: "America/Chicago", SendHour: 6, Units: "metric"}
+	if err := testDB.SaveDigestSubscription(context.Background(), again); err != nil {
+		t.Fatalf("SaveDigestSubscription failed: %v", err)
+	}
+	if again.ID != sub.ID || again.UnsubscribeToken != sub.UnsubscribeToken || again.ConfirmToken == sub.ConfirmToken || !again.Confirmed {
+		t.Errorf("Expected the existing subscription to be updated, got %+v", again)
+	}
+	subs, err = testDB.ListDigestSubscriptions()
+	if err != nil || len(subs) != 1 || subs[0].SendHour != 7 || subs[0].Name != "Oklahoma City, OK" {
+		t.Fatalf("Expected the confirmed schedule to be unchanged, got %+v err=%v", subs, err)
+	}
+	if err := testDB.SaveDigestSubscription(context.Background(), &DigestSubscription{Email: sub.Email, Latitude: 35.47, Longitude: -97.52, TimeZone: "UTC"}); !errors.Is(err, ErrConfirmationPending) {
+		t.Errorf("Expected ErrConfirmationPending for the pending change, got %v", err)
+	}
+	if got, err := testDB.ConfirmDigestSubscription(context.Background(), sub.ConfirmToken); err != nil || got != nil {
+		t.Errorf("Expected the replaced token to confirm nothing, got %+v err=%v", got, err)
+	}
+	if _, err := testDB.ConfirmDigestSubscription(context.Background(), again.ConfirmToken); err != nil {
+		t.Fatalf("ConfirmDigestSubscription failed: %v", err)
+	}
+
+	if err := testDB.SetDigestSent(sub.ID, "2025-05-06"); err != nil {
+		t.Fatalf("SetDigestSent failed: %v", err)
+	}
+	subs, err = testDB.ListDigestSubscriptions()
+	if err != nil || len(subs) != 1 {
+		t.Fatalf("Expected one confirmed subscription, got %+v err=%v", subs, err)
+	}
+	if subs[0].SendHour != 6 || subs[0].Units != "metric" || subs[0].Name != "Home" || subs[0].LastSent != "2025-05-06" {
+		t.Errorf("Unexpected subscription %+v", subs[0])
+	}
+
+	if ok, err := testDB.DeleteDigestSubscription(context.Background(), sub.UnsubscribeToken); err != nil || !ok {
+		t.Errorf("DeleteDigestSubscription failed: %v %v", ok, err)
+	}
+	if ok, _ := testDB.DeleteDigestSubscription(context.Background(), sub.UnsubscribeToken); ok {
+		t.Errorf("Expected second unsubscribe to find nothing")
+	}
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Kinds of IssuedForecast.
+const (
+	ForecastTemperature = "temperature" // Hourly temperature, °C
+	ForecastHigh        = "high"        // Daily high, °C
+	ForecastLow         = "low"         // Daily low, °C
+	ForecastPrecip      = "precip"      // Daily precipitation chance, %
+)
+
+// ForecastSite is a location whose forecasts are tracked for accuracy. ID
+// is its PointKey and Station the observation station that verifies it.
+type ForecastSite struct {
+	ID        string  `json:"id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Station   string  `json:"station"`
+}
+
+// IssuedForecast is one forecast value as issued. Target is the UTC hour,
+// "2006-01-02 15", for hourly temperatures and the site's local date,
+// "2006-01-02", for the daily kinds. LeadHours is how far ahead of Target
+// it was issued.
+type IssuedForecast struct {
+	Kind      string
+	Target    string
+	IssuedAt  time.Time
+	LeadHours int
+	Value     float64
+}
+
+// ObservationHour is a station's average temperature in °C over one UTC
+// hour, "2006-01-02 15".
+type ObservationHour struct {
+	Hour        string
+	Temperature float64
+}
+
+// SaveForecastSite stores or updates a tracked site.
+func (db *DB) SaveForecastSite(site ForecastSite) error {
+	defer observeQuery("save_forecast_site", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO forecast_sites (id, latitude, longitude, station) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET station = excluded.station
+	`, site.ID, site.Latitude, site.Longitude, site.Station)
+	return err
+}
+
+// GetForecastSite returns a tracked site, or nil if it isn't one.
+func (db *DB) GetForecastSite(ctx context.Context, id string) (*ForecastSite, error) {
+	ctx, done := startQuery(ctx, "get_forecast_site")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var site ForecastSite
+	err := db.QueryRowContext(ctx, "SELECT id, latitude, longitude, station FROM forecast_sites WHERE id = ?", id).
+		Scan(&site.ID, &site.Latitude, &site.Longitude, &site.Station)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// ListForecastSites returns every tracked site.
+func (db *DB) ListForecastSites(ctx context.Context) ([]ForecastSite, error) {
+	ctx, done := startQuery(ctx, "list_forecast_sites")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, latitude, longitude, station FROM forecast_sites ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sites []ForecastSite
+	for rows.Next() {
+		var site ForecastSite
+		if err := rows.Scan(&site.ID, &site.Latitude, &site.Longitude, &site.Station); err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	return sites, rows.Err()
+}
+
+// RecordForecasts stores a site's issued forecast values. Values already
+// recorded for the same issue time are left alone, so recording an
+// unchanged forecast again is a no-op.
+func (db *DB) RecordForecasts(site string, forecasts []IssuedForecast) error {
+	defer observeQuery("record_forecasts", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO issued_forecasts (site, kind, target, issued_at, lead_hours, value)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, f := range forecasts {
+		if _, err := stmt.Exec(site, f.Kind, f.Target, f.IssuedAt.UTC(), f.LeadHours, f.Value); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// IssuedForecasts returns a site's forecasts for targets from since on:
+// a local date, "2006-01-02", which also covers hourly targets after it.
+func (db *DB) IssuedForecasts(ctx context.Context, site, since string) ([]IssuedForecast, error) {
+	ctx, done := startQuery(ctx, "issued_forecasts")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT kind, target, issued_at, lead_hours, value FROM issued_forecasts
+		WHERE site = ? AND target >= ?
+		ORDER BY target, issued_at
+	`, site, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forecasts []IssuedForecast
+	for rows.Next() {
+		var f IssuedForecast
+		if err := rows.Scan(&f.Kind, &f.Target, &f.IssuedAt, &f.LeadHours, &f.Value); err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, f)
+	}
+	return forecasts, rows.Err()
+}
+
+// ObservationHours returns a station's hourly average temperatures from
+// since, a UTC date ("2006-01-02"), on.
+func (db *DB) ObservationHours(ctx context.Context, station, since string) ([]ObservationHour, error) {
+	ctx, done := startQuery(ctx, "observation_hours")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT substr(observed_at, 1, 13) AS hour, AVG(temperature)
+		FROM observations
+		WHERE station = ? AND observed_at >= ? AND temperature IS NOT NULL
+		GROUP BY hour
+		ORDER BY hour
+	`, station, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hours []ObservationHour
+	for rows.Next() {
+		var h ObservationHour
+		if err := rows.Scan(&h.Hour, &h.Temperature); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIssuedForecasts(t *testing.T) {
+	db := setupTestDB(t)
+
+	site := ForecastSite{ID: "35.47,-97.52", Latitude: 35.47, Longitude: -97.52, Station: "KOKC"}
+	if err := db.SaveForecastSite(site); err != nil {
+		t.Fatalf("SaveForecastSite failed: %v", err)
+	}
+	if got, err := db.GetForecastSite(context.Background(), site.ID); err != nil || got == nil || *got != site {
+		t.Fatalf("unexpected site %+v, %v", got, err)
+	}
+	if got, _ := db.GetForecastSite(context.Background(), "0.00,0.00"); got != nil {
+		t.Errorf("expected no site, got %+v", got)
+	}
+	if sites, err := db.ListForecastSites(context.Background()); err != nil || len(sites) != 1 {
+		t.Errorf("unexpected sites %+v, %v", sites, err)
+	}
+
+	issued := time.Date(2025, 5, 5, 19, 0, 0, 0, time.UTC)
+	forecasts := []IssuedForecast{
+		{Kind: ForecastHigh, Target: "2025-05-05", IssuedAt: issued, LeadHours: 0, Value: 30},
+		{Kind: ForecastTemperature, Target: "2025-05-06 20", IssuedAt: issued, LeadHours: 25, Value: 10},
+		{Kind: ForecastHigh, Target: "2025-05-04", IssuedAt: issued, LeadHours: 0, Value: 28},
+	}
+	// Recording the same issue twice stores it once.
+	for range 2 {
+		if err := db.RecordForecasts(site.ID, forecasts); err != nil {
+			t.Fatalf("RecordForecasts failed: %v", err)
+		}
+	}
+
+	got, err := db.IssuedForecasts(context.Background(), site.ID, "2025-05-05")
+	if err != nil {
+		t.Fatalf("IssuedForecasts failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 forecasts, got %+v", got)
+	}
+	if got[0].Target != "2025-05-05" || !got[0].IssuedAt.Equal(issued) || got[1].Kind != ForecastTemperature || got[1].LeadHours != 25 {
+		t.Errorf("unexpected forecasts %+v", got)
+	}
+}
+
+func TestObservationHours(t *testing.T) {
+	db := setupTestDB(t)
+
+	f := func(v float64) *float64 { return &v }
+	for _, o := range []Observation{
+		{Time: time.Date(2025, 5, 5, 19, 53, 0, 0, time.UTC), Temperature: f(20)},
+		{Time: time.Date(2025, 5, 5, 19, 20, 0, 0, time.UTC), Temperature: f(21)},
+		{Time: time.Date(2025, 5, 5, 20, 53, 0, 0, time.UTC)},
+	} {
+		o.Station, o.LocalDate = "KOKC", "2025-05-05"
+		if err := db.RecordObservation(o); err != nil {
+			t.Fatalf("RecordObservation failed: %v", err)
+		}
+	}
+
+	hours, err := db.ObservationHours(context.Background(), "KOKC", "2025-05-05")
+	if err != nil {
+		t.Fatalf("ObservationHours failed: %v", err)
+	}
+	if len(hours) != 1 || hours[0] != (ObservationHour{Hour: "2025-05-05 19", Temperature: 20.5}) {
+		t.Errorf("unexpected hours %+v", hours)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/logging"
+	"github.com/swelljoe/wthr.lol/internal/metrics"
+	"github.com/swelljoe/wthr.lol/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var queryDuration = metrics.NewHistogram("wthr_db_query_duration_seconds",
+	"Time taken by database calls, by method.", nil, "query")
+
+// observeQuery records the time since start against query, and logs it at
+// debug level. Methods call it first thing with defer, so it covers every
+// statement they run.
+func observeQuery(query string, start time.Time) {
+	elapsed := time.Since(start)
+	queryDuration.Observe(elapsed.Seconds(), query)
+	slog.Debug("DB query", "query", query, "latency_ms", logging.Millis(elapsed))
+}
+
+// startQuery is observeQuery for methods that can be called while serving
+// a request. Within a traced request it also records the call as a span,
+// e.g. "db.find_place"; background work, with no trace in ctx, doesn't
+// start new traces. Methods call it first thing and defer the returned
+// function:
+//
+//	ctx, done := startQuery(ctx, "find_place")
+//	defer done()
+func startQuery(ctx context.Context, query string) (context.Context, func()) {
+	start := time.Now()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, func() { observeQuery(query, start) }
+	}
+	ctx, span := tracing.Start(ctx, "db."+query,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNameSQLite, semconv.DBOperationName(query)))
+	return ctx, func() {
+		span.End()
+		observeQuery(query, start)
+	}
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Station is an observation station being recorded.
+type Station struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	TimeZone string `json:"time_zone"`
+}
+
+// Observation is one recorded station observation. Temperature is in °C
+// and PrecipLastHour in mm; either is nil when the station didn't report
+// it. LocalDate is the station's local date, "2006-01-02".
+type Observation struct {
+	Station        string
+	Time           time.Time
+	LocalDate      string
+	Temperature    *float64
+	PrecipLastHour *float64
+	Description    string
+}
+
+// ObservationDay summarizes a station's observations for one local date.
+// High and Low are nil without any temperature reports, and Precip without
+// any precipitation reports. Hours counts the distinct hours with a report.
+type ObservationDay struct {
+	Date         string
+	High         *float64
+	Low          *float64
+	Precip       *float64
+	Observations int
+	Hours        int
+}
+
+// SaveStation stores or updates a station's metadata.
+func (db *DB) SaveStation(st Station) error {
+	defer observeQuery("save_station", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO stations (id, name, time_zone) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, time_zone = excluded.time_zone
+	`, st.ID, st.Name, st.TimeZone)
+	return err
+}
+
+// GetStation returns a recorded station, or nil if it isn't one.
+func (db *DB) GetStation(ctx context.Context, id string) (*Station, error) {
+	ctx, done := startQuery(ctx, "get_station")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var st Station
+	err := db.QueryRowContext(ctx, "SELECT id, name, time_zone FROM stations WHERE id = ?", id).Scan(&st.ID, &st.Name, &st.TimeZone)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// ListStations returns every recorded station.
+func (db *DB) ListStations(ctx context.Context) ([]Station, error) {
+	ctx, done := startQuery(ctx, "list_stations")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name, time_zone FROM stations ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stations []Station
+	for rows.Next() {
+		var st Station
+		if err := rows.Scan(&st.ID, &st.Name, &st.TimeZone); err != nil {
+			return nil, err
+		}
+		stations = append(stations, st)
+	}
+	return stations, rows.Err()
+}
+
+// RecordObservation stores an observation. Recording the same observation
+// again is a no-op, since stations only update every hour or so.
+func (db *DB) RecordObservation(o Observation) error {
+	defer observeQuery("record_observation", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO observations (station, observed_at, local_date, temperature, precip_last_hour, description)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, o.Station, o.Time.UTC(), o.LocalDate, o.Temperature, o.PrecipLastHour, o.Description)
+	return err
+}
+
+// ObservationDays summarizes a station's observations by local date, oldest
+// first, from since ("2006-01-02", or "" for all of them). Stations send
+// extra reports during bad weather whose precipitation overlaps the hourly
+// ones, so only the largest report in each hour counts towards Precip.
+func (db *DB) ObservationDays(ctx context.Context, station, since string) ([]ObservationDay, error) {
+	ctx, done := startQuery(ctx, "observation_days")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		WITH hours AS (
+			SELECT local_date,
+				MAX(temperature) AS high,
+				MIN(temperature) AS low,
+				MAX(precip_last_hour) AS precip,
+				COUNT(*) AS n
+			FROM observations
+			WHERE station = ? AND local_date >= ?
+			GROUP BY local_date, substr(observed_at, 1, 13)
+		)
+		SELECT local_date, MAX(high), MIN(low), SUM(precip), SUM(n), COUNT(*)
+		FROM hours
+		GROUP BY local_date
+		ORDER BY local_date
+	`, station, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []ObservationDay
+	for rows.Next() {
+		var d ObservationDay
+		var high, low, precip sql.NullFloat64
+		if err := rows.Scan(&d.Date, &high, &low, &precip, &d.Observations, &d.Hours); err != nil {
+			return nil, err
+		}
+		d.High, d.Low, d.Precip = nullFloat(high), nullFloat(low), nullFloat(precip)
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func nullFloat(n sql.NullFloat64) *float64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Float64
+}
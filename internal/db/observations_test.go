@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestObservationDays(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := db.SaveStation(Station{ID: "KOKC", Name: "Oklahoma City, Will Rogers World Airport", TimeZone: "America/Chicago"}); err != nil {
+		t.Fatalf("SaveStation failed: %v", err)
+	}
+	st, err := db.GetStation(context.Background(), "KOKC")
+	if err != nil || st == nil || st.TimeZone != "America/Chicago" {
+		t.Fatalf("unexpected station %+v, %v", st, err)
+	}
+	if st, _ := db.GetStation(context.Background(), "KXXX"); st != nil {
+		t.Errorf("expected no station, got %+v", st)
+	}
+
+	f := func(v float64) *float64 { return &v }
+	at := func(day, hour, min int) time.Time { return time.Date(2025, 5, day, hour, min, 0, 0, time.UTC) }
+	obs := []Observation{
+		{Time: at(5, 20, 53), LocalDate: "2025-05-05", Temperature: f(24.4), PrecipLastHour: f(0)},
+		{Time: at(5, 21, 53), LocalDate: "2025-05-05", Temperature: f(18.9), PrecipLastHour: f(5.1)},
+		// A special report inside the same hour overlaps the hourly total.
+		{Time: at(5, 21, 20), LocalDate: "2025-05-05", Temperature: f(19.5), PrecipLastHour: f(3.0)},
+		{Time: at(5, 22, 53), LocalDate: "2025-05-05", Temperature: f(17.2), PrecipLastHour: f(2.0)},
+		{Time: at(6, 13, 53), LocalDate: "2025-05-06", Temperature: f(16.1)},
+		{Time: at(6, 14, 53), LocalDate: "2025-05-06"},
+	}
+	for _, o := range obs {
+		o.Station = "KOKC"
+		if err := db.RecordObservation(o); err != nil {
+			t.Fatalf("RecordObservation failed: %v", err)
+		}
+	}
+	// Recording an observation twice doesn't count it twice.
+	if err := db.RecordObservation(Observation{Station: "KOKC", Time: at(6, 14, 53), LocalDate: "2025-05-06"}); err != nil {
+		t.Fatalf("RecordObservation failed: %v", err)
+	}
+
+	days, err := db.ObservationDays(context.Background(), "KOKC", "")
+	if err != nil {
+		t.Fatalf("ObservationDays failed: %v", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days, got %+v", days)
+	}
+	d := days[0]
+	if d.Date != "2025-05-05" || *d.High != 24.4 || *d.Low != 17.2 || *d.Precip != 7.1 || d.Observations != 4 || d.Hours != 3 {
+		t.Errorf("unexpected first day %+v", d)
+	}
+	d = days[1]
+	if *d.High != 16.1 || *d.Low != 16.1 || d.Precip != nil || d.Observations != 2 || d.Hours != 2 {
+		t.Errorf("unexpected second day %+v", d)
+	}
+
+	days, err = db.ObservationDays(context.Background(), "KOKC", "2025-05-06")
+	if err != nil || len(days) != 1 {
+		t.Errorf("expected only days since 2025-05-06, got %+v, %v", days, err)
+	}
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PushSubscription is a browser Web Push subscription for alerts at one
+// location. SeenAlerts holds the IDs of the alerts that were active at the
+// last poll so only new alerts are delivered.
+type PushSubscription struct {
+	ID         int64
+	Endpoint   string
+	P256dh     string
+	Auth       string
+	Latitude   float64
+	Longitude  float64
+	SeenAlerts []string
+}
+
+// SavePushSubscription stores a subscription, replacing the keys and
+// location of an existing subscription with the same endpoint.
+func (db *DB) SavePushSubscription(ctx context.Context, sub PushSubscription) error {
+	ctx, done := startQuery(ctx, "save_push_subscription")
+	defer done()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO push_subscriptions (endpoint, p256dh, auth, latitude, longitude)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET
+			p256dh = excluded.p256dh,
+			auth = excluded.auth,
+			latitude = excluded.latitude,
+			longitude = excluded.longitude,
+			seen_alerts = '[]'
+	`, sub.Endpoint, sub.P256dh, sub.Auth, sub.Latitude, sub.Longitude)
+
+	return err
+}
+
+// DeletePushSubscription removes the subscription for endpoint, if any.
+func (db *DB) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	ctx, done := startQuery(ctx, "delete_push_subscription")
+	defer done()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.ExecContext(ctx, "DELETE FROM push_subscriptions WHERE endpoint = ?", endpoint)
+	return err
+}
+
+// ListPushSubscriptions returns every stored subscription.
+func (db *DB) ListPushSubscriptions() ([]PushSubscription, error) {
+	defer observeQuery("list_push_subscriptions", time.Now())
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, endpoint, p256dh, auth, latitude, longitude, seen_alerts
+		FROM push_subscriptions
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []PushSubscription
+	for rows.Next() {
+		var sub PushSubscription
+		var seen string
+		if err := rows.Scan(&sub.ID, &sub.Endpoint, &sub.P256dh, &sub.Auth, &sub.Latitude, &sub.Longitude, &seen); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(seen), &sub.SeenAlerts); err != nil {
+			return nil, fmt.Errorf("failed to decode seen alerts: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// SetPushSeenAlerts records the alert IDs a subscription has been notified of.
+func (db *DB) SetPushSeenAlerts(id int64, alertIDs []string) error {
+	defer observeQuery("set_push_seen_alerts", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if alertIDs == nil {
+		alertIDs = []string{}
+	}
+
+	data, err := json.Marshal(alertIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE push_subscriptions SET seen_alerts = ? WHERE id = ?", string(data), id)
+	return err
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPushSubscriptions(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	sub := PushSubscription{
+		Endpoint:  "https://push.example/abc",
+		P256dh:    "key",
+		Auth:      "secret",
+		Latitude:  37.77,
+		Longitude: -122.42,
+	}
+	if err := testDB.SavePushSubscription(context.Background(), sub); err != nil {
+		t.Fatalf("SavePushSubscription failed: %v", err)
+	}
+
+	subs, err := testDB.ListPushSubscriptions()
+	if err != nil {
+		t.Fatalf("ListPushSubscriptions failed: %v", err)
+	}
+	if len(subs) != 1 || subs[0].Endpoint != sub.Endpoint || len(subs[0].SeenAlerts) != 0 {
+		t.Fatalf("Expected one fresh subscription, got %+v", subs)
+	}
+
+	if err := testDB.SetPushSeenAlerts(subs[0].ID, []string{"urn:oid:1"}); err != nil {
+		t.Fatalf("SetPushSeenAlerts failed: %v", err)
+	}
+	subs, _ = testDB.ListPushSubscriptions()
+	if len(subs[0].SeenAlerts) != 1 || subs[0].SeenAlerts[0] != "urn:oid:1" {
+		t.Errorf("Expected seen alerts to be stored, got %+v", subs[0].SeenAlerts)
+	}
+
+	// Re-subscribing the same endpoint moves it and forgets what was seen
+	sub.Latitude = 40.71
+	if err := testDB.SavePushSubscription(context.Background(), sub); err != nil {
+		t.Fatalf("SavePushSubscription failed: %v", err)
+	}
+	subs, _ = testDB.ListPushSubscriptions()
+	if len(subs) != 1 || subs[0].Latitude != 40.71 || len(subs[0].SeenAlerts) != 0 {
+		t.Errorf("Expected updated subscription, got %+v", subs)
+	}
+
+	if err := testDB.DeletePushSubscription(context.Background(), sub.Endpoint); err != nil {
+		t.Fatalf("DeletePushSubscription failed: %v", err)
+	}
+	subs, _ = testDB.ListPushSubscriptions()
+	if len(subs) != 0 {
+		t.Errorf("Expected no subscriptions after delete, got %+v", subs)
+	}
+}
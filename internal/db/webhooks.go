@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Webhook is an alert webhook subscription for one location. Events, if not
+// empty, limits it to those alert event names (e.g. "Tornado Warning").
+type Webhook struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	MinSeverity string    `json:"min_severity,omitempty"`
+	Events      []string  `json:"events"`
+	SeenAlerts  []string  `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one attempt to deliver an alert to a webhook.
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  int64     `json:"webhook_id"`
+	AlertID    string    `json:"alert_id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateWebhook stores a webhook, generating a signing secret if none is
+// set, and fills in its ID, Secret and CreatedAt.
+func (db *DB) CreateWebhook(ctx context.Context, wh *Webhook) error {
+	ctx, done := startQuery(ctx, "create_webhook")
+	defer done()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if wh.Secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate secret: %w", err)
+		}
+		wh.Secret = hex.EncodeToString(buf)
+	}
+	if wh.Events == nil {
+		wh.Events = []string{}
+	}
+	events, err := json.Marshal(wh.Events)
+	if err != nil {
+		return err
+	}
+
+	wh.CreatedAt = time.Now().UTC()
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO webhooks (url, secret, latitude, longitude, min_severity, events, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, wh.URL, wh.Secret, wh.Latitude, wh.Longitude, wh.MinSeverity, string(events), wh.CreatedAt)
+	if err != nil {
+		return err
+	}
+	wh.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListWebhooks returns every webhook, including secrets.
+func (db *DB) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	ctx, done := startQuery(ctx, "list_webhooks")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, url, secret, latitude, longitude, min_severity, events, seen_alerts, created_at
+		FROM webhooks
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []Webhook
+	for rows.Next() {
+		var wh Webhook
+		var events, seen string
+		if err := rows.Scan(&wh.ID, &wh.URL, &wh.Secret, &wh.Latitude, &wh.Longitude, &wh.MinSeverity, &events, &seen, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &wh.Events); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook events: %w", err)
+		}
+		if err := json.Unmarshal([]byte(seen), &wh.SeenAlerts); err != nil {
+			return nil, fmt.Errorf("failed to decode seen alerts: %w", err)
+		}
+		hooks = append(hooks, wh)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook removes a webhook and its delivery log. It reports false if
+// the webhook does not exist.
+func (db *DB) DeleteWebhook(ctx context.Context, id int64) (bool, error) {
+	ctx, done := startQuery(ctx, "delete_webhook")
+	defer done()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// SetWebhookSeenAlerts records the alert IDs a webhook has been sent.
+func (db *DB) SetWebhookSeenAlerts(id int64, alertIDs []string) error {
+	defer observeQuery("set_webhook_seen_alerts", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if alertIDs == nil {
+		alertIDs = []string{}
+	}
+
+	data, err := json.Marshal(alertIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE webhooks SET seen_alerts = ? WHERE id = ?", string(data), id)
+	return err
+}
+
+// LogWebhookDelivery appends a delivery attempt to the delivery log.
+func (db *DB) LogWebhookDelivery(d WebhookDelivery) error {
+	defer observeQuery("log_webhook_delivery", time.Now())
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, alert_id, event, attempt, status_code, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, d.WebhookID, d.AlertID, d.Event, d.Attempt, d.StatusCode, d.Error, time.Now().UTC())
+	return err
+}
+
+// CountWebhookAttempts returns how many delivery attempts have been logged
+// for an alert on a webhook.
+func (db *DB) CountWebhookAttempts(webhookID int64, alertID string) (int, error) {
+	defer observeQuery("count_webhook_attempts", time.Now())
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ? AND alert_id = ?", webhookID, alertID).Scan(&n)
+	return n, err
+}
+
+// ListWebhookDeliveries returns the most recent delivery attempts for a
+// webhook, newest first.
+func (db *DB) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]WebhookDelivery, error) {
+	ctx, done := startQuery(ctx, "list_webhook_deliveries")
+	defer done()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, webhook_id, alert_id, event, attempt, status_code, error, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.AlertID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWebhooks(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	wh := &Webhook{
+		URL:         "https://hooks.example/alerts",
+		Latitude:    35.47,
+		Longitude:   -97.52,
+		MinSeverity: "Severe",
+		Events:      []string{"Tornado Warning"},
+	}
+	if err := testDB.CreateWebhook(context.Background(), wh); err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	if wh.ID == 0 || len(wh.Secret) != 64 {
+		t.Errorf("Expected ID and generated secret, got %+v", wh)
+	}
+
+	hooks, err := testDB.ListWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("ListWebhooks failed: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].Secret != wh.Secret || hooks[0].Events[0] != "Tornado Warning" {
+		t.Fatalf("Expected stored webhook, got %+v", hooks)
+	}
+
+	if err := testDB.SetWebhookSeenAlerts(wh.ID, []string{"urn:oid:1"}); err != nil {
+		t.Fatalf("SetWebhookSeenAlerts failed: %v", err)
+	}
+	hooks, _ = testDB.ListWebhooks(context.Background())
+	if len(hooks[0].SeenAlerts) != 1 {
+		t.Errorf("Expected seen alerts to be stored, got %v", hooks[0].SeenAlerts)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		err := testDB.LogWebhookDelivery(WebhookDelivery{WebhookID: wh.ID, AlertID: "urn:oid:1", Event: "alert.created", Attempt: attempt, StatusCode: 500})
+		if err != nil {
+			t.Fatalf("LogWebhookDelivery failed: %v", err)
+		}
+	}
+	n, err := testDB.CountWebhookAttempts(wh.ID, "urn:oid:1")
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 attempts, got %d err=%v", n, err)
+	}
+	deliveries, err := testDB.ListWebhookDeliveries(context.Background(), wh.ID, 1)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Attempt != 2 {
+		t.Errorf("Expected newest delivery first, got %+v err=%v", deliveries, err)
+	}
+
+	found, err := testDB.DeleteWebhook(context.Background(), wh.ID)
+	if err != nil || !found {
+		t.Fatalf("DeleteWebhook failed: found=%v err=%v", found, err)
+	}
+	if n, _ := testDB.CountWebhookAttempts(wh.ID, "urn:oid:1"); n != 0 {
+		t.Errorf("Expected delivery log to be removed, got %d", n)
+	}
+	if found, _ := testDB.DeleteWebhook(context.Background(), wh.ID); found {
+		t.Errorf("Expected found=false deleting a missing webhook")
+	}
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetZone returns the cached GeoJSON geometry for an NWS zone URL, or ""
+// if the zone is not cached or was fetched before notBefore.
+func (db *DB) GetZone(ctx context.Context, url string, notBefore time.Time) (string, error) {
+	ctx, done := startQuery(ctx, "get_zone")
+	defer done()
+	if db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	var geometry string
+	err := db.QueryRowContext(ctx, "SELECT geometry FROM zones WHERE url = ? AND fetched_at >= ?", url, notBefore.UTC()).Scan(&geometry)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return geometry, err
+}
+
+// SetZone caches the GeoJSON geometry for an NWS zone URL.
+func (db *DB) SetZone(ctx context.Context, url, geometry string) error {
+	ctx, done := startQuery(ctx, "set_zone")
+	defer done()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO zones (url, geometry, fetched_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			geometry = excluded.geometry,
+			fetched_at = excluded.fetched_at
+	`, url, geometry, time.Now().UTC())
+	return err
+}
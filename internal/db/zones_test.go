@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestZones(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	url := "https://api.weather.gov/zones/forecast/OKZ025"
+	got, err := testDB.GetZone(context.Background(), url, time.Time{})
+	if err != nil || got != "" {
+		t.Fatalf("Expected cache miss, got %q err=%v", got, err)
+	}
+
+	geometry := `{"type":"Polygon","coordinates":[[[-98,35],[-97,35],[-97,36],[-98,35]]]}`
+	if err := testDB.SetZone(context.Background(), url, geometry); err != nil {
+		t.Fatalf("SetZone failed: %v", err)
+	}
+	got, err = testDB.GetZone(context.Background(), url, time.Now().Add(-time.Hour))
+	if err != nil || got != geometry {
+		t.Errorf("Expected cached geometry, got %q err=%v", got, err)
+	}
+
+	// Stale entries are treated as missing
+	got, err = testDB.GetZone(context.Background(), url, time.Now().Add(time.Hour))
+	if err != nil || got != "" {
+		t.Errorf("Expected stale entry to be ignored, got %q err=%v", got, err)
+	}
+}
+
+func TestQuerySpans(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+
+	// Untraced calls, like the pollers', don't start traces of their own.
+	testDB.GetZone(context.Background(), "https://api.weather.gov/zones/forecast/OKZ025", time.Time{})
+	if spans := exp.GetSpans(); len(spans) != 0 {
+		t.Fatalf("expected no spans outside a trace, got %d", len(spans))
+	}
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	testDB.GetZone(ctx, "https://api.weather.gov/zones/forecast/OKZ025", time.Time{})
+	parent.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "db.get_zone" || spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("expected db.get_zone as a child of the caller's span, got %q", spans[0].Name)
+	}
+}
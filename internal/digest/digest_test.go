@@ -0,0 +1,270 @@
+package digest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// smtpServer is a minimal SMTP stand-in that records each message.
+type smtpServer struct {
+	addr string
+	mu   sync.Mutex
+	auth []string
+	rcpt []string
+	msgs []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.Fields(cmd + " x")[0])
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = append(s.auth, cmd)
+			s.mu.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, cmd)
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	server := newSMTPServer(t)
+	sender, err := NewSMTPSender(server.addr, "user", "pass", "wthr.lol <digest@wthr.lol>")
+	if err != nil {
+		t.Fatalf("NewSMTPSender failed: %v", err)
+	}
+
+	err = sender.Send(Message{
+		To:      "me@example.com",
+		Subject: "⚠️ Weather",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://wthr.lol/u>"},
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.auth) != 1 || !strings.Contains(server.auth[0], base64.StdEncoding.EncodeToString([]byte("\x00user\x00pass"))) {
+		t.Errorf("expected PLAIN auth, got %v", server.auth)
+	}
+	if len(server.rcpt) != 1 || server.rcpt[0] != "RCPT TO:<me@example.com>" {
+		t.Errorf("unexpected recipients %v", server.rcpt)
+	}
+
+	msgs := server.msgs
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(msgs[0]))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "⚠️ Weather" || msg.Header.Get("List-Unsubscribe") != "<https://wthr.lol/u>" {
+		t.Errorf("unexpected headers %v", msg.Header)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("bad Content-Type: %v", err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(body))
+	}
+	if len(parts) != 2 || !strings.HasSuffix(parts[0], "plain body") || !strings.HasSuffix(parts[1], "<p>html body</p>") {
+		t.Errorf("unexpected parts %q", parts)
+	}
+}
+
+func TestDue(t *testing.T) {
+	// 13:30 UTC is 08:30 in Chicago (CDT) and 06:30 in Los Angeles (PDT).
+	now := time.Date(2025, 5, 6, 13, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		sub  db.DigestSubscription
+		want bool
+	}{
+		{"due", db.DigestSubscription{TimeZone: "America/Chicago", SendHour: 7}, true},
+		{"already sent today", db.DigestSubscription{TimeZone: "America/Chicago", SendHour: 7, LastSent: "2025-05-06"}, false},
+		{"sent yesterday", db.DigestSubscription{TimeZone: "America/Chicago", SendHour: 8, LastSent: "2025-05-05"}, true},
+		{"too early", db.DigestSubscription{TimeZone: "America/Los_Angeles", SendHour: 7}, false},
+		{"missed the window", db.DigestSubscription{TimeZone: "America/Chicago", SendHour: 5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := Due(tt.sub, now); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+type memoryStore struct {
+	subs []db.DigestSubscription
+	sent map[int64]string
+}
+
+func (m *memoryStore) ListDigestSubscriptions() ([]db.DigestSubscription, error) {
+	return m.subs, nil
+}
+
+func (m *memoryStore) SetDigestSent(id int64, date string) error {
+	m.sent[id] = date
+	for i := range m.subs {
+		if m.subs[i].ID == id {
+			m.subs[i].LastSent = date
+		}
+	}
+	return nil
+}
+
+type fakeWeather struct{}
+
+func (fakeWeather) GetWeather(lat, lon float64) (*weather.WeatherData, error) {
+	return &weather.WeatherData{
+		Current:  weather.CurrentCondition{Temperature: 68, TemperatureUnit: "F", ShortForecast: "Sunny"},
+		Forecast: []weather.DailyForecast{{Name: "Today", HighTemp: 86, LowTemp: 59, TemperatureUnit: "F", ShortForecast: "Sunny", PrecipChance: 10}},
+		Alerts:   []weather.Alert{{Event: "Heat Advisory", Severity: "Moderate", Instruction: "Drink plenty of fluids."}},
+		Units:    weather.UnitsUS,
+	}, nil
+}
+
+type recordingSender struct {
+	sent []Message
+}
+
+func (r *recordingSender) Send(m Message) error {
+	r.sent = append(r.sent, m)
+	return nil
+}
+
+func TestSchedulerPoll(t *testing.T) {
+	templates, err := ParseTemplates("../../templates")
+	if err != nil {
+		t.Fatalf("ParseTemplates failed: %v", err)
+	}
+	store := &memoryStore{
+		sent: map[int64]string{},
+		subs: []db.DigestSubscription{
+			{ID: 1, Email: "okc@example.com", Latitude: 35.47, Longitude: -97.52, Name: "Oklahoma City, OK", TimeZone: "America/Chicago", SendHour: 8, Units: "metric", UnsubscribeToken: "tok1"},
+			{ID: 2, Email: "la@example.com", Latitude: 34.05, Longitude: -118.24, TimeZone: "America/Los_Angeles", SendHour: 7, UnsubscribeToken: "tok2"},
+		},
+	}
+	sender := &recordingSender{}
+	s := NewScheduler(store, fakeWeather{}, sender, templates, "https://wthr.example")
+
+	now := time.Date(2025, 5, 6, 13, 30, 0, 0, time.UTC)
+	if err := s.Poll(now); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(sender.sent) != 1 || store.sent[1] != "2025-05-06" {
+		t.Fatalf("expected only the Chicago digest, got %+v", sender.sent)
+	}
+
+	m := sender.sent[0]
+	if m.To != "okc@example.com" || m.Subject != "⚠️ Oklahoma City, OK weather for Tuesday, May 6" {
+		t.Errorf("unexpected message %q to %q", m.Subject, m.To)
+	}
+	for _, want := range []string{"Heat Advisory", "Drink plenty of fluids.", "High 30°, low 15°", "https://wthr.example/w/35.47,-97.52", "https://wthr.example/digest/unsubscribe?token=tok1"} {
+		if !strings.Contains(m.Text, want) || !strings.Contains(m.HTML, want) {
+			t.Errorf("expected text and HTML to contain %q\ntext: %s", want, m.Text)
+		}
+	}
+	if m.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("expected one-click unsubscribe headers, got %v", m.Headers)
+	}
+
+	// An hour later the Los Angeles digest is due and Chicago isn't resent.
+	if err := s.Poll(now.Add(time.Hour)); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(sender.sent) != 2 || sender.sent[1].To != "la@example.com" {
+		t.Errorf("expected the Los Angeles digest next, got %+v", sender.sent)
+	}
+}
@@ -0,0 +1,146 @@
+// Package digest sends the daily forecast email to confirmed subscribers.
+package digest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is one email. HTML is optional; Headers are added as-is.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string
+}
+
+// Sender delivers email; *SMTPSender implements it.
+type Sender interface {
+	Send(m Message) error
+}
+
+// SMTPSender sends mail through an SMTP relay, using STARTTLS when the
+// server offers it.
+type SMTPSender struct {
+	Addr string // host:port
+	From string // e.g. "wthr.lol <digest@wthr.lol>"
+	auth smtp.Auth
+}
+
+// NewSMTPSender creates a sender for the relay at addr. Username and
+// password are optional; net/smtp only sends them over TLS or to localhost.
+func NewSMTPSender(addr, username, password, from string) (*SMTPSender, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+	s := &SMTPSender{Addr: addr, From: from}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s, nil
+}
+
+// Send delivers m.
+func (s *SMTPSender) Send(m Message) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	body, err := m.encode(s.From, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.auth, from.Address, []string{to.Address}, body)
+}
+
+// encode renders the message as RFC 5322 text, multipart/alternative when
+// there is an HTML part.
+func (m Message) encode(from string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+
+	domain := "wthr.lol"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	header("From", from)
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain))
+	header("MIME-Version", "1.0")
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(k, m.Headers[k])
+	}
+
+	if m.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQP(&buf, m.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQP(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(s, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
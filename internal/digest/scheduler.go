@@ -0,0 +1,231 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/url"
+	"path/filepath"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// sendWindow is how many hours after the requested hour a missed digest is
+// still sent, e.g. after a restart. Later than that it waits for tomorrow.
+const sendWindow = 3
+
+// Store is the subscription storage the Scheduler needs; *db.DB implements
+// it.
+type Store interface {
+	ListDigestSubscriptions() ([]db.DigestSubscription, error)
+	SetDigestSent(id int64, date string) error
+}
+
+// WeatherSource fetches the weather for a point; *weather.Service
+// implements it.
+type WeatherSource interface {
+	GetWeather(lat, lon float64) (*weather.WeatherData, error)
+}
+
+// Templates renders the digest email. The HTML and text templates both
+// receive a Data.
+type Templates struct {
+	HTML *htmltemplate.Template
+	Text *texttemplate.Template
+}
+
+// ParseTemplates loads digest_email.html and digest_email.txt from dir.
+func ParseTemplates(dir string) (*Templates, error) {
+	html, err := htmltemplate.ParseFiles(filepath.Join(dir, "digest_email.html"))
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.ParseFiles(filepath.Join(dir, "digest_email.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{HTML: html, Text: text}, nil
+}
+
+// Data is passed to the digest templates.
+type Data struct {
+	Name           string
+	Date           string // e.g. "Tuesday, May 6"
+	Weather        *weather.WeatherData
+	Today          *weather.DailyForecast
+	PageURL        string
+	UnsubscribeURL string
+}
+
+// Scheduler sends each confirmed subscriber their digest once a day, at
+// SendHour in their own time zone.
+type Scheduler struct {
+	store     Store
+	weather   WeatherSource
+	sender    Sender
+	templates *Templates
+	BaseURL   string
+	Interval  time.Duration
+}
+
+// NewScheduler creates a Scheduler that checks every five minutes.
+func NewScheduler(store Store, weather WeatherSource, sender Sender, templates *Templates, baseURL string) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		weather:   weather,
+		sender:    sender,
+		templates: templates,
+		BaseURL:   baseURL,
+		Interval:  5 * time.Minute,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Poll(time.Now()); err != nil {
+			log.Printf("Digest poll error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll sends the digests that are due at now.
+func (s *Scheduler) Poll(now time.Time) error {
+	subs, err := s.store.ListDigestSubscriptions()
+	if err != nil {
+		return fmt.Errorf("failed to list digest subscriptions: %w", err)
+	}
+
+	for _, sub := range subs {
+		local, due := Due(sub, now)
+		if !due {
+			continue
+		}
+
+		wd, err := s.weather.GetWeather(sub.Latitude, sub.Longitude)
+		if err != nil {
+			// Try again next poll while still inside the send window.
+			log.Printf("Digest: failed to get weather for %s: %v", db.PointKey(sub.Latitude, sub.Longitude), err)
+			continue
+		}
+
+		msg, err := s.message(sub, wd, local)
+		if err != nil {
+			log.Printf("Digest: failed to render email: %v", err)
+			continue
+		}
+		if err := s.sender.Send(msg); err != nil {
+			log.Printf("Digest: failed to send to subscription %d: %v", sub.ID, err)
+			continue
+		}
+		if err := s.store.SetDigestSent(sub.ID, local.Format("2006-01-02")); err != nil {
+			log.Printf("Failed to record digest sent: %v", err)
+		}
+	}
+	return nil
+}
+
+// Due reports whether a subscription's digest should go out at now, and
+// returns now in the subscriber's time zone.
+func Due(sub db.DigestSubscription, now time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(sub.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	if sub.LastSent == local.Format("2006-01-02") {
+		return local, false
+	}
+	h := local.Hour()
+	return local, h >= sub.SendHour && h < sub.SendHour+sendWindow
+}
+
+func (s *Scheduler) message(sub db.DigestSubscription, wd *weather.WeatherData, local time.Time) (Message, error) {
+	units, err := weather.ParseUnits(sub.Units)
+	if err != nil {
+		units = weather.UnitsUS
+	}
+	wd = wd.WithUnits(units)
+
+	name := sub.Name
+	if name == "" {
+		name = wd.Location
+	}
+	data := Data{
+		Name:           name,
+		Date:           local.Format("Monday, January 2"),
+		Weather:        wd,
+		PageURL:        fmt.Sprintf("%s/w/%.2f,%.2f", s.BaseURL, sub.Latitude, sub.Longitude),
+		UnsubscribeURL: UnsubscribeURL(s.BaseURL, sub.UnsubscribeToken),
+	}
+	if len(wd.Forecast) > 0 {
+		data.Today = &wd.Forecast[0]
+	}
+
+	var html, text bytes.Buffer
+	if err := s.templates.HTML.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	if err := s.templates.Text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+
+	subject := fmt.Sprintf("%s weather for %s", name, data.Date)
+	if len(wd.Alerts) > 0 {
+		subject = "⚠️ " + subject
+	}
+	return Message{
+		To:      sub.Email,
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: unsubscribeHeaders(data.UnsubscribeURL),
+	}, nil
+}
+
+// ConfirmationMessage is the double opt-in email sent when someone
+// subscribes. Nothing is sent to the address until the link is followed.
+func ConfirmationMessage(sub db.DigestSubscription, baseURL string) Message {
+	confirm := baseURL + "/digest/confirm?token=" + url.QueryEscape(sub.ConfirmToken)
+	unsubscribe := UnsubscribeURL(baseURL, sub.UnsubscribeToken)
+	return Message{
+		To:      sub.Email,
+		Subject: "Confirm your wthr.lol daily forecast",
+		Text: fmt.Sprintf(`Someone, hopefully you, asked for a daily forecast email for %s at %d:00.
+
+To start getting it, confirm here:
+%s
+
+If you didn't ask for this, ignore this email and you won't hear from us again.
+
+Unsubscribe at any time: %s
+`, sub.Name, sub.SendHour, confirm, unsubscribe),
+		Headers: unsubscribeHeaders(unsubscribe),
+	}
+}
+
+// UnsubscribeURL is the one-click unsubscribe link for a token.
+func UnsubscribeURL(baseURL, token string) string {
+	return baseURL + "/digest/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+// unsubscribeHeaders are the RFC 8058 one-click unsubscribe headers.
+func unsubscribeHeaders(unsubscribe string) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribe + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/accuracy"
+	"github.com/swelljoe/wthr.lol/internal/db"
+)
+
+const (
+	defaultAccuracyDays = 30
+	maxAccuracyDays     = 366
+)
+
+// AccuracyPageData is the data passed to the accuracy.html template. Report
+// is nil on the site list.
+type AccuracyPageData struct {
+	Title        string
+	Description  string
+	CanonicalURL string
+	Report       *accuracy.Report
+	Sites        []db.ForecastSite
+}
+
+// HandleAccuracyAPI returns how well a tracked site's forecasts verified
+// over the last days days (default 30), by lead time.
+func (h *Handlers) HandleAccuracyAPI(w http.ResponseWriter, r *http.Request) {
+	report, status, msg := h.accuracyReport(r)
+	if report == nil {
+		http.Error(w, msg, status)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// HandleAccuracy serves the forecast accuracy report for a site, or the
+// list of tracked sites when none is given.
+func (h *Handlers) HandleAccuracy(w http.ResponseWriter, r *http.Request) {
+	if h.templates == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data := &AccuracyPageData{
+		Title:        "Forecast accuracy - wthr.lol",
+		Description:  "How well recorded forecasts matched the observed weather.",
+		CanonicalURL: h.baseURL + "/accuracy",
+	}
+
+	if r.PathValue("site") == "" {
+		if h.db == nil {
+			http.Error(w, "Forecast accuracy unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		sites, err := h.db.ListForecastSites(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Accuracy error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		data.Sites = sites
+	} else {
+		report, status, msg := h.accuracyReport(r)
+		if report == nil {
+			http.Error(w, msg, status)
+			return
+		}
+		data.Report = report
+		data.Title = "Forecast accuracy for " + report.Site.ID + " - wthr.lol"
+		data.Description = "How well forecasts for " + report.Site.ID + " matched observations at " + report.Site.Station + "."
+		data.CanonicalURL = h.baseURL + "/accuracy/" + report.Site.ID
+	}
+
+	if err := h.render(r.Context(), w, "accuracy.html", data); err != nil {
+		slog.ErrorContext(r.Context(), "Error executing template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// accuracyReport scores the forecasts for the request's {site}, given as
+// "lat,lon", or returns an HTTP status and message explaining why it can't.
+func (h *Handlers) accuracyReport(r *http.Request) (*accuracy.Report, int, string) {
+	if h.db == nil {
+		return nil, http.StatusServiceUnavailable, "Forecast accuracy unavailable"
+	}
+	lat, lon, ok := parseCoords(r.PathValue("site"))
+	if !ok {
+		return nil, http.StatusBadRequest, "invalid coordinates"
+	}
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+	days := defaultAccuracyDays
+	if d := r.URL.Query().Get("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 || n > maxAccuracyDays {
+			return nil, http.StatusBadRequest, "days must be between 1 and 366"
+		}
+		days = n
+	}
+
+	site, err := h.db.GetForecastSite(r.Context(), db.PointKey(lat, lon))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Accuracy error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	if site == nil {
+		return nil, http.StatusNotFound, "forecasts are not tracked here"
+	}
+	st, err := h.db.GetStation(r.Context(), site.Station)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Accuracy error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	if st == nil {
+		st = &db.Station{ID: site.Station, Name: site.Station, TimeZone: "UTC"}
+	}
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -days).Format("2006-01-02")
+	forecasts, err := h.db.IssuedForecasts(r.Context(), site.ID, since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Accuracy error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	observed, err := h.db.ObservationDays(r.Context(), st.ID, since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Accuracy error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	hours, err := h.db.ObservationHours(r.Context(), st.ID, since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Accuracy error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	return accuracy.BuildReport(*site, *st, since, forecasts, observed, hours, units, now), 0, ""
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/accuracy"
+	"github.com/swelljoe/wthr.lol/internal/db"
+)
+
+func newAccuracyMux(h *Handlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/accuracy/{site}", h.HandleAccuracyAPI)
+	mux.HandleFunc("GET /accuracy", h.HandleAccuracy)
+	mux.HandleFunc("GET /accuracy/{site}", h.HandleAccuracy)
+	return mux
+}
+
+func TestHandleAccuracy(t *testing.T) {
+	loc, _ := time.LoadLocation(testStation.TimeZone)
+	yesterday := time.Now().In(loc).AddDate(0, 0, -1).Format("2006-01-02")
+	site := db.ForecastSite{ID: "35.47,-97.52", Latitude: 35.47, Longitude: -97.52, Station: "KOKC"}
+	h := &Handlers{
+		db: &mockDB{
+			stations:      []db.Station{testStation},
+			forecastSites: []db.ForecastSite{site},
+			issuedForecasts: map[string][]db.IssuedForecast{site.ID: {
+				{Kind: db.ForecastHigh, Target: yesterday, LeadHours: 30, Value: 25},
+			}},
+			observationDays: map[string][]db.ObservationDay{"KOKC": {
+				{Date: yesterday, High: f64(20), Low: f64(10), Hours: 24},
+			}},
+		},
+		templates: loadTemplates(t),
+		baseURL:   "https://wthr.example",
+	}
+	mux := newAccuracyMux(h)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/accuracy/35.4676,-97.5164?units=metric", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	var report accuracy.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	if report.Site.Station != "KOKC" || len(report.Leads) != 1 || report.Leads[0].LeadDay != 1 ||
+		*report.Leads[0].High != (accuracy.Score{Count: 1, MeanError: 5, Bias: 5}) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/accuracy/35.47,-97.52", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "1 day ahead") ||
+		!strings.Contains(w.Body.String(), `>9<span class="accuracy-bias">&#43;9.0 (1)</span>`) {
+		t.Errorf("unexpected accuracy page %v: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/accuracy", nil))
+	if !strings.Contains(w.Body.String(), `href="/accuracy/35.47,-97.52"`) {
+		t.Errorf("expected the site list, got %s", w.Body.String())
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/accuracy/40.71,-74.01":         http.StatusNotFound,
+		"/api/v1/accuracy/oklahoma":             http.StatusBadRequest,
+		"/api/v1/accuracy/35.47,-97.52?days=0":  http.StatusBadRequest,
+		"/api/v1/accuracy/35.47,-97.52?units=k": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %v, got %v", target, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+const (
+	defaultHistoryDays = 30
+	maxHistoryDays     = 365
+	maxHistoryAlerts   = 1000
+)
+
+// HandleAlertHistory returns the recorded alert versions for a watched point
+// (lat and lon) or state (state), covering the last days days (default 30).
+// Only points and states the background recorder watches have history.
+func (h *Handlers) HandleAlertHistory(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Alert history unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+
+	var scope string
+	switch {
+	case q.Get("state") != "":
+		scope = strings.ToUpper(q.Get("state"))
+		if len(scope) != 2 {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+	case q.Get("lat") != "" && q.Get("lon") != "":
+		lat, lon, ok := parseCoords(q.Get("lat") + "," + q.Get("lon"))
+		if !ok {
+			http.Error(w, "invalid coordinates", http.StatusBadRequest)
+			return
+		}
+		scope = db.PointKey(lat, lon)
+	default:
+		http.Error(w, "lat and lon, or state, are required", http.StatusBadRequest)
+		return
+	}
+
+	days := defaultHistoryDays
+	if d := q.Get("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 || n > maxHistoryDays {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	since := time.Now().AddDate(0, 0, -days).UTC()
+
+	history, err := h.db.AlertHistory(r.Context(), scope, since, maxHistoryAlerts)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Alert history error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"scope":  scope,
+		"since":  since,
+		"alerts": history,
+	})
+}
+
+// HandleAlertsGeoJSON returns active alerts with a known area as a GeoJSON
+// FeatureCollection. bbox (minLon,minLat,maxLon,maxLat) limits it to alerts
+// overlapping a box and severity to alerts at least that severe.
+func (h *Handlers) HandleAlertsGeoJSON(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "Alerts unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+
+	var filter weather.AlertFilter
+	if b := q.Get("bbox"); b != "" {
+		bbox, ok := parseBBox(b)
+		if !ok {
+			http.Error(w, "bbox must be minLon,minLat,maxLon,maxLat", http.StatusBadRequest)
+			return
+		}
+		filter.BBox = bbox
+	}
+	if s := q.Get("severity"); s != "" {
+		filter.MinSeverity = weather.SeverityRank(s)
+		if filter.MinSeverity < 0 {
+			http.Error(w, "invalid severity", http.StatusBadRequest)
+			return
+		}
+	}
+
+	idx, err := h.alerts.IndexContext(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Active alerts error", "error", err)
+		http.Error(w, "Failed to get alerts", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.Header().Set("Content-Type", "application/geo+json")
+	if err := json.NewEncoder(w).Encode(idx.Features(filter)); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding GeoJSON", "error", err)
+	}
+}
+
+// HandleAlertsMap serves the active alerts map, which draws
+// /api/v1/alerts.geojson client-side.
+func (h *Handlers) HandleAlertsMap(w http.ResponseWriter, r *http.Request) {
+	if h.templates == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err := h.render(r.Context(), w, "alerts_map.html", &PageData{
+		Title:        "Active alerts - wthr.lol",
+		Description:  "Map of active National Weather Service alerts.",
+		CanonicalURL: h.baseURL + "/alerts/map",
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error executing template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// parseBBox parses "minLon,minLat,maxLon,maxLat".
+func parseBBox(s string) (*[4]float64, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, false
+	}
+	var bbox [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, false
+		}
+		bbox[i] = v
+	}
+	if bbox[0] > bbox[2] || bbox[1] > bbox[3] || bbox[1] < -90 || bbox[3] > 90 || bbox[0] < -180 || bbox[2] > 180 {
+		return nil, false
+	}
+	return &bbox, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func TestHandleAlertHistory(t *testing.T) {
+	var gotScope string
+	var gotSince time.Time
+	h := &Handlers{db: &mockDB{
+		alertHistoryFunc: func(scope string, since time.Time, limit int) ([]db.AlertVersion, error) {
+			gotScope, gotSince = scope, since
+			return []db.AlertVersion{{ID: "urn:oid:1", Event: "Tornado Warning"}}, nil
+		},
+	}}
+
+	w := httptest.NewRecorder()
+	h.HandleAlertHistory(w, httptest.NewRequest("GET", "/api/v1/alerts/history?lat=35.4676&lon=-97.5164&days=7", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	if gotScope != "35.47,-97.52" {
+		t.Errorf("expected rounded point scope, got %q", gotScope)
+	}
+	if d := time.Since(gotSince); d < 7*24*time.Hour-time.Minute || d > 7*24*time.Hour+time.Minute {
+		t.Errorf("expected history for the last 7 days, got since %v", gotSince)
+	}
+
+	var resp struct {
+		Scope  string            `json:"scope"`
+		Alerts []db.AlertVersion `json:"alerts"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Scope != "35.47,-97.52" || len(resp.Alerts) != 1 || resp.Alerts[0].ID != "urn:oid:1" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleAlertHistory(w, httptest.NewRequest("GET", "/api/v1/alerts/history?state=ok", nil))
+	if w.Code != http.StatusOK || gotScope != "OK" {
+		t.Errorf("expected state scope OK, got %q (%v)", gotScope, w.Code)
+	}
+}
+
+func TestHandleAlertHistory_BadRequest(t *testing.T) {
+	h := &Handlers{db: &mockDB{}}
+
+	for _, target := range []string{
+		"/api/v1/alerts/history",
+		"/api/v1/alerts/history?lat=91&lon=0",
+		"/api/v1/alerts/history?lat=35&lon=-97&days=0",
+		"/api/v1/alerts/history?lat=35&lon=-97&days=1000",
+		"/api/v1/alerts/history?state=Oklahoma",
+	} {
+		w := httptest.NewRecorder()
+		h.HandleAlertHistory(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status BadRequest for %s, got %v", target, w.Code)
+		}
+	}
+}
+
+type mockAlerts struct {
+	index *weather.AlertIndex
+}
+
+func (m *mockAlerts) IndexContext(ctx context.Context) (*weather.AlertIndex, error) {
+	return m.index, nil
+}
+
+func (m *mockAlerts) GetAlertsContext(ctx context.Context, lat, lon float64) ([]weather.Alert, error) {
+	return m.index.Match(lat, lon), nil
+}
+
+func newMockAlerts(t *testing.T) *mockAlerts {
+	t.Helper()
+	var al weather.AlertsResponse
+	err := json.Unmarshal([]byte(`{"features": [
+		{"id": "urn:oid:1", "geometry": {"type": "Polygon", "coordinates": [[[-98,35],[-97,35],[-97,36],[-98,36],[-98,35]]]},
+			"properties": {"event": "Tornado Warning", "severity": "Extreme", "expires": "2025-05-06T21:00:00Z"}},
+		{"id": "urn:oid:2", "geometry": {"type": "Polygon", "coordinates": [[[-75,40],[-74,40],[-74,41],[-75,41],[-75,40]]]},
+			"properties": {"event": "Heat Advisory", "severity": "Moderate"}},
+		{"id": "urn:oid:3", "geometry": null, "properties": {"event": "Special Weather Statement", "severity": "Minor"}}
+	]}`), &al)
+	if err != nil {
+		t.Fatalf("failed to decode alerts: %v", err)
+	}
+	return &mockAlerts{index: weather.NewAlertIndex(&al, nil)}
+}
+
+func TestHandleAlertsGeoJSON(t *testing.T) {
+	h := &Handlers{alerts: newMockAlerts(t)}
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		// Alerts without a known area can't be drawn and are left out.
+		{"/api/v1/alerts.geojson", []string{"urn:oid:1", "urn:oid:2"}},
+		{"/api/v1/alerts.geojson?severity=severe", []string{"urn:oid:1"}},
+		{"/api/v1/alerts.geojson?bbox=-80,38,-70,42", []string{"urn:oid:2"}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.HandleAlertsGeoJSON(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status OK for %s, got %v: %s", tt.target, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/geo+json" {
+			t.Errorf("unexpected Content-Type %q", ct)
+		}
+
+		var fc weather.FeatureCollection
+		if err := json.NewDecoder(w.Body).Decode(&fc); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if fc.Type != "FeatureCollection" || len(fc.Features) != len(tt.want) {
+			t.Fatalf("%s: expected %v, got %+v", tt.target, tt.want, fc)
+		}
+		for i, f := range fc.Features {
+			if f.ID != tt.want[i] {
+				t.Errorf("%s: feature %d expected %s, got %s", tt.target, i, tt.want[i], f.ID)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleAlertsGeoJSON(w, httptest.NewRequest("GET", "/api/v1/alerts.geojson?severity=severe", nil))
+	var fc weather.FeatureCollection
+	json.NewDecoder(w.Body).Decode(&fc)
+	p := fc.Features[0].Properties
+	if p.Event != "Tornado Warning" || p.Severity != "Extreme" || !p.Expires.Equal(time.Date(2025, 5, 6, 21, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected properties %+v", p)
+	}
+}
+
+func TestHandleAlertsGeoJSON_BadRequest(t *testing.T) {
+	h := &Handlers{alerts: newMockAlerts(t)}
+
+	for _, target := range []string{
+		"/api/v1/alerts.geojson?bbox=1,2,3",
+		"/api/v1/alerts.geojson?bbox=-70,38,-80,42",
+		"/api/v1/alerts.geojson?bbox=a,b,c,d",
+		"/api/v1/alerts.geojson?severity=scary",
+	} {
+		w := httptest.NewRecorder()
+		h.HandleAlertsGeoJSON(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status BadRequest for %s, got %v", target, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	(&Handlers{}).HandleAlertsGeoJSON(w, httptest.NewRequest("GET", "/api/v1/alerts.geojson", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable without alerts, got %v", w.Code)
+	}
+}
+
+func TestHandleAlertsMap(t *testing.T) {
+	h := &Handlers{templates: loadTemplates(t), baseURL: "https://wthr.lol"}
+
+	w := httptest.NewRecorder()
+	h.HandleAlertsMap(w, httptest.NewRequest("GET", "/alerts/map", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `id="alerts-map"`) || !strings.Contains(body, "/static/js/alerts-map.js") {
+		t.Errorf("map page missing map element or script")
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+const (
+	defaultAlmanacDays = 14
+	maxAlmanacDays     = 366
+)
+
+// Almanac is a station's recorded daily weather in one unit system. Records
+// cover everything recorded, not just Days.
+type Almanac struct {
+	Station           db.Station     `json:"station"`
+	Units             weather.Units  `json:"units"`
+	TemperatureUnit   string         `json:"temperature_unit"`
+	PrecipitationUnit string         `json:"precipitation_unit"`
+	Days              []AlmanacDay   `json:"days"`
+	Today             *AlmanacDay    `json:"today,omitempty"`
+	WeekAgo           *AlmanacDay    `json:"week_ago,omitempty"`
+	Records           AlmanacRecords `json:"records"`
+}
+
+// AlmanacDay is one local date's observed extremes and precipitation.
+type AlmanacDay struct {
+	Date         string   `json:"date"`
+	High         *float64 `json:"high"`
+	Low          *float64 `json:"low"`
+	Precip       *float64 `json:"precip"`
+	Observations int      `json:"observations"`
+}
+
+// AlmanacRecords are the extremes observed since recording began.
+type AlmanacRecords struct {
+	Since   string         `json:"since,omitempty"`
+	High    *AlmanacRecord `json:"high,omitempty"`
+	Low     *AlmanacRecord `json:"low,omitempty"`
+	Wettest *AlmanacRecord `json:"wettest,omitempty"`
+}
+
+// AlmanacRecord is a record value and the date it was observed.
+type AlmanacRecord struct {
+	Value float64 `json:"value"`
+	Date  string  `json:"date"`
+}
+
+// AlmanacPageData is the data passed to the almanac.html template. Almanac
+// is nil on the station list.
+type AlmanacPageData struct {
+	Title        string
+	Description  string
+	CanonicalURL string
+	Almanac      *Almanac
+	Stations     []db.Station
+}
+
+// HandleAlmanacAPI returns the almanac for a recorded station, covering the
+// last days days (default 14).
+func (h *Handlers) HandleAlmanacAPI(w http.ResponseWriter, r *http.Request) {
+	a, status, msg := h.almanac(r)
+	if a == nil {
+		http.Error(w, msg, status)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+// HandleAlmanac serves the almanac page for a station, or the list of
+// recorded stations when none is given.
+func (h *Handlers) HandleAlmanac(w http.ResponseWriter, r *http.Request) {
+	if h.templates == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data := &AlmanacPageData{
+		Title:        "Almanac - wthr.lol",
+		Description:  "Observed highs, lows and precipitation from weather stations.",
+		CanonicalURL: h.baseURL + "/almanac",
+	}
+
+	if r.PathValue("station") == "" {
+		if h.db == nil {
+			http.Error(w, "Almanac unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		stations, err := h.db.ListStations(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Almanac error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		data.Stations = stations
+	} else {
+		a, status, msg := h.almanac(r)
+		if a == nil {
+			http.Error(w, msg, status)
+			return
+		}
+		data.Almanac = a
+		data.Title = a.Station.ID + " almanac - wthr.lol"
+		data.Description = "Observed weather at " + a.Station.Name + "."
+		data.CanonicalURL = h.baseURL + "/almanac/" + a.Station.ID
+	}
+
+	if err := h.render(r.Context(), w, "almanac.html", data); err != nil {
+		slog.ErrorContext(r.Context(), "Error executing template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// almanac builds the almanac for the request's {station}, or returns an
+// HTTP status and message explaining why it can't.
+func (h *Handlers) almanac(r *http.Request) (*Almanac, int, string) {
+	if h.db == nil {
+		return nil, http.StatusServiceUnavailable, "Almanac unavailable"
+	}
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+	days := defaultAlmanacDays
+	if d := r.URL.Query().Get("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 || n > maxAlmanacDays {
+			return nil, http.StatusBadRequest, "days must be between 1 and 366"
+		}
+		days = n
+	}
+
+	st, err := h.db.GetStation(r.Context(), strings.ToUpper(r.PathValue("station")))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Almanac error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	if st == nil {
+		return nil, http.StatusNotFound, "station is not recorded"
+	}
+	all, err := h.db.ObservationDays(r.Context(), st.ID, "")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Almanac error", "error", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
+	return buildAlmanac(*st, all, days, units, time.Now()), 0, ""
+}
+
+// buildAlmanac summarizes all of a station's recorded days into an Almanac
+// of the last days days before now, in units.
+func buildAlmanac(st db.Station, all []db.ObservationDay, days int, units weather.Units, now time.Time) *Almanac {
+	loc, err := time.LoadLocation(st.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	today := now.In(loc)
+	since := today.AddDate(0, 0, 1-days).Format("2006-01-02")
+	todayDate := today.Format("2006-01-02")
+	weekAgoDate := today.AddDate(0, 0, -7).Format("2006-01-02")
+
+	_, tempUnit := units.Temperature(0, weather.UnitCelsius)
+	_, precipUnit := units.Precipitation(0, weather.UnitMM)
+	a := &Almanac{
+		Station:           st,
+		Units:             units,
+		TemperatureUnit:   tempUnit,
+		PrecipitationUnit: precipUnit,
+		Days:              []AlmanacDay{},
+	}
+
+	record := func(rec **AlmanacRecord, v *float64, date string, better func(a, b float64) bool) {
+		if v != nil && (*rec == nil || better(*v, (*rec).Value)) {
+			*rec = &AlmanacRecord{Value: *v, Date: date}
+		}
+	}
+	higher := func(a, b float64) bool { return a > b }
+	lower := func(a, b float64) bool { return a < b }
+
+	for _, d := range all {
+		day := AlmanacDay{Date: d.Date, Observations: d.Observations}
+		if d.High != nil {
+			v, _ := units.Temperature(*d.High, weather.UnitCelsius)
+			day.High = &v
+		}
+		if d.Low != nil {
+			v, _ := units.Temperature(*d.Low, weather.UnitCelsius)
+			day.Low = &v
+		}
+		if d.Precip != nil {
+			v, _ := units.Precipitation(*d.Precip, weather.UnitMM)
+			day.Precip = &v
+		}
+
+		if a.Records.Since == "" {
+			a.Records.Since = d.Date
+		}
+		record(&a.Records.High, day.High, d.Date, higher)
+		record(&a.Records.Low, day.Low, d.Date, lower)
+		if day.Precip != nil && *day.Precip > 0 {
+			record(&a.Records.Wettest, day.Precip, d.Date, higher)
+		}
+
+		if d.Date >= since && d.Date <= todayDate {
+			a.Days = append(a.Days, day)
+		}
+		switch d.Date {
+		case todayDate:
+			a.Today = &day
+		case weekAgoDate:
+			a.WeekAgo = &day
+		}
+	}
+	return a
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func f64(v float64) *float64 { return &v }
+
+var testStation = db.Station{ID: "KOKC", Name: "Oklahoma City, Will Rogers World Airport", TimeZone: "America/Chicago"}
+
+var testObservationDays = []db.ObservationDay{
+	{Date: "2025-04-01", High: f64(35.0), Low: f64(-2.0), Precip: f64(40.0), Observations: 24},
+	{Date: "2025-04-29", High: f64(25.0), Low: f64(12.0), Precip: f64(0), Observations: 24},
+	{Date: "2025-05-05", High: f64(20.0), Low: f64(10.0), Observations: 24},
+	{Date: "2025-05-06", High: f64(22.5), Low: f64(15.0), Precip: f64(2.5), Observations: 8},
+}
+
+func TestBuildAlmanac(t *testing.T) {
+	// 03:00 UTC on May 7 is still May 6 in Oklahoma City.
+	now := time.Date(2025, 5, 7, 3, 0, 0, 0, time.UTC)
+	a := buildAlmanac(testStation, testObservationDays, 14, weather.UnitsMetric, now)
+
+	if len(a.Days) != 3 || a.Days[0].Date != "2025-04-29" {
+		t.Fatalf("expected the last 14 days, got %+v", a.Days)
+	}
+	if a.Today == nil || a.Today.Date != "2025-05-06" || *a.Today.High != 22.5 || *a.Today.Precip != 2.5 {
+		t.Errorf("unexpected today %+v", a.Today)
+	}
+	if a.WeekAgo == nil || a.WeekAgo.Date != "2025-04-29" {
+		t.Errorf("unexpected week ago %+v", a.WeekAgo)
+	}
+	r := a.Records
+	if r.Since != "2025-04-01" || r.High.Value != 35 || r.Low.Value != -2 || r.Wettest.Date != "2025-04-01" {
+		t.Errorf("unexpected records %+v", r)
+	}
+	if a.TemperatureUnit != "C" || a.PrecipitationUnit != "mm" {
+		t.Errorf("unexpected units %q %q", a.TemperatureUnit, a.PrecipitationUnit)
+	}
+
+	us := buildAlmanac(testStation, testObservationDays, 14, weather.UnitsUS, now)
+	if *us.Today.High != 72.5 || *us.Today.Precip != 0.1 || us.Records.Low.Value != 28.4 {
+		t.Errorf("expected US units, got %+v %+v", us.Today, us.Records.Low)
+	}
+}
+
+func newAlmanacMux(h *Handlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/almanac/{station}", h.HandleAlmanacAPI)
+	mux.HandleFunc("GET /almanac", h.HandleAlmanac)
+	mux.HandleFunc("GET /almanac/{station}", h.HandleAlmanac)
+	return mux
+}
+
+func TestHandleAlmanac(t *testing.T) {
+	h := &Handlers{
+		db: &mockDB{
+			stations:        []db.Station{testStation},
+			observationDays: map[string][]db.ObservationDay{"KOKC": testObservationDays},
+		},
+		templates: loadTemplates(t),
+		baseURL:   "https://wthr.example",
+	}
+	mux := newAlmanacMux(h)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/almanac/kokc?days=366&units=metric", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	var a Almanac
+	if err := json.Unmarshal(w.Body.Bytes(), &a); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	if a.Station.ID != "KOKC" || a.Records.High == nil || a.Records.High.Value != 35 {
+		t.Errorf("unexpected almanac %+v", a)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/almanac/KOKC", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Records since 2025-04-01") ||
+		!strings.Contains(w.Body.String(), `data-station="KOKC"`) {
+		t.Errorf("unexpected almanac page %v: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/almanac", nil))
+	if !strings.Contains(w.Body.String(), `href="/almanac/KOKC"`) {
+		t.Errorf("expected the station list, got %s", w.Body.String())
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/almanac/KXXX":         http.StatusNotFound,
+		"/api/v1/almanac/KOKC?days=0":  http.StatusBadRequest,
+		"/api/v1/almanac/KOKC?units=k": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %v, got %v", target, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/swelljoe/wthr.lol/internal/card"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// HandleCoordsCard serves the weather card image for /card/{lat},{lon}.png
+// (or .svg).
+func (h *Handlers) HandleCoordsCard(w http.ResponseWriter, r *http.Request) {
+	coords, format, ok := cardFormat(r.PathValue("coords"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	lat, lon, ok := parseCoords(coords)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.renderCard(w, r, lat, lon, "", format)
+}
+
+// HandlePlaceCard serves the weather card image for
+// /card/{state}/{place}.png (or .svg), named like the place's permalink.
+func (h *Handlers) HandlePlaceCard(w http.ResponseWriter, r *http.Request) {
+	slug, format, ok := cardFormat(r.PathValue("place"))
+	if !ok || h.db == nil {
+		http.NotFound(w, r)
+		return
+	}
+	place, err := h.db.FindPlace(r.Context(), r.PathValue("state"), strings.ToLower(slug))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Place lookup error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if place == nil {
+		http.NotFound(w, r)
+		return
+	}
+	h.renderCard(w, r, place.Latitude, place.Longitude, placeName(*place), format)
+}
+
+// renderCard writes the card for a point. Cards are cached publicly, so
+// only the URL's units query picks the units, never the cookie.
+func (h *Handlers) renderCard(w http.ResponseWriter, r *http.Request, lat, lon float64, name, format string) {
+	units, err := weather.ParseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+	c := card.FromWeather(wd.WithUnits(units))
+	if name != "" {
+		c.Location = name
+	}
+
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = c.WriteSVG(&buf)
+	} else {
+		err = c.WritePNG(&buf)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Card error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	w.Write(buf.Bytes())
+}
+
+// cardFormat splits the image extension off a card path segment.
+func cardFormat(segment string) (string, string, bool) {
+	for _, format := range []string{"png", "svg"} {
+		if base, ok := strings.CutSuffix(segment, "."+format); ok && base != "" {
+			return base, format, true
+		}
+	}
+	return "", "", false
+}
+
+// cardPath returns the card image path for a permalink path, e.g.
+// "/card/TX/austin.png" for "/w/TX/austin".
+func cardPath(permalink string) string {
+	return "/card" + strings.TrimPrefix(permalink, "/w") + ".png"
+}
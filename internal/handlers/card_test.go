@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func newCardMux(h *Handlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /card/{coords}", h.HandleCoordsCard)
+	mux.HandleFunc("GET /card/{state}/{place}", h.HandlePlaceCard)
+	return mux
+}
+
+func TestHandleCard(t *testing.T) {
+	h := &Handlers{
+		db: &mockDB{
+			findPlaceFunc: func(state, slug string) (*db.Place, error) {
+				if state == "CA" && slug == "san-francisco" {
+					return &db.Place{Name: "San Francisco", State: "CA", Latitude: 37.7749, Longitude: -122.4194}, nil
+				}
+				return nil, nil
+			},
+		},
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) { return sampleWeather(), nil },
+		},
+	}
+	mux := newCardMux(h)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/card/37.77,-122.42.png", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a PNG, got %v %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if _, err := png.Decode(w.Body); err != nil {
+		t.Errorf("bad PNG: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/card/CA/san-francisco.svg?units=metric", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("expected an SVG, got %v %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if svg := w.Body.String(); !strings.Contains(svg, ">San Francisco, CA</text>") || !strings.Contains(svg, ">20°C</text>") {
+		t.Errorf("unexpected card %s", svg)
+	}
+
+	// Cards are cached publicly, so the units cookie doesn't apply.
+	req := httptest.NewRequest("GET", "/card/37.77,-122.42.svg", nil)
+	req.AddCookie(&http.Cookie{Name: "units", Value: "metric"})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if svg := w.Body.String(); !strings.Contains(svg, ">68°F</text>") {
+		t.Errorf("expected the card in the URL's units, got %s", svg)
+	}
+
+	for _, target := range []string{"/card/37.77,-122.42", "/card/37.77,-122.42.gif", "/card/95,0.png", "/card/CA/nowhere.png"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status NotFound, got %v", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/digest"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+const defaultDigestHour = 7
+
+// maxDigestNameLength caps the location name given on the signup form,
+// which is repeated in the emails.
+const maxDigestNameLength = 60
+
+// Signups are limited per client IP and per email address so the form
+// can't be used to flood an inbox with confirmation emails. Behind a reverse
+// proxy the client IP comes from CLIENT_IP_HEADER.
+const (
+	digestSignupWindow    = time.Hour
+	digestSignupsPerIP    = 10
+	digestSignupsPerEmail = 3
+)
+
+// HandleDigestSubscribe handles the daily email signup form. It stores an
+// unconfirmed subscription, or a pending change to a confirmed one, and
+// emails a confirmation link; nothing changes until that link is followed.
+// No further link is sent while one is outstanding.
+func (h *Handlers) HandleDigestSubscribe(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil || h.mailer == nil {
+		http.Error(w, "Daily emails unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	page := &LitePageData{Units: weather.UnitsUS}
+
+	addr, err := mail.ParseAddress(strings.TrimSpace(r.FormValue("email")))
+	if err != nil {
+		page.Error = "Please enter a valid email address"
+		h.renderLite(w, r, http.StatusBadRequest, page)
+		return
+	}
+	lat, lon, ok := parseCoords(r.FormValue("lat") + "," + r.FormValue("lon"))
+	if !ok {
+		page.Error = "Invalid coordinates"
+		h.renderLite(w, r, http.StatusBadRequest, page)
+		return
+	}
+	if !h.digestSignups.allow("ip:"+h.clientIP(r), digestSignupsPerIP, digestSignupWindow) ||
+		!h.digestSignups.allow("email:"+strings.ToLower(addr.Address), digestSignupsPerEmail, digestSignupWindow) {
+		page.Error = "Too many signups, please try again later"
+		h.renderLite(w, r, http.StatusTooManyRequests, page)
+		return
+	}
+	hour := defaultDigestHour
+	if v := r.FormValue("hour"); v != "" {
+		hour, err = strconv.Atoi(v)
+		if err != nil || hour < 0 || hour > 23 {
+			page.Error = "Hour must be between 0 and 23"
+			h.renderLite(w, r, http.StatusBadRequest, page)
+			return
+		}
+	}
+	units := weather.UnitsUS
+	if v := r.FormValue("units"); v != "" {
+		units, err = weather.ParseUnits(v)
+		if err != nil {
+			page.Error = err.Error()
+			h.renderLite(w, r, http.StatusBadRequest, page)
+			return
+		}
+	}
+
+	sub := &db.DigestSubscription{
+		Email:     addr.Address,
+		Latitude:  lat,
+		Longitude: lon,
+		Name:      digestName(r.FormValue("name")),
+		TimeZone:  r.FormValue("time_zone"),
+		SendHour:  hour,
+		Units:     string(units),
+	}
+	if _, err := time.LoadLocation(sub.TimeZone); err != nil || sub.TimeZone == "" {
+		// Fall back to the forecast point's own time zone.
+		wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Weather error", "error", err)
+			page.Error = "Failed to look up the location's time zone"
+			h.renderLite(w, r, http.StatusBadGateway, page)
+			return
+		}
+		sub.TimeZone = wd.TimeZone
+		if sub.TimeZone == "" {
+			sub.TimeZone = "UTC"
+		}
+		if sub.Name == "" {
+			sub.Name = wd.Location
+		}
+	}
+	if sub.Name == "" {
+		sub.Name = fmt.Sprintf("%.2f, %.2f", lat, lon)
+	}
+
+	notice := fmt.Sprintf("Check %s for a link to confirm your daily forecast for %s.", sub.Email, sub.Name)
+	err = h.db.SaveDigestSubscription(r.Context(), sub)
+	if errors.Is(err, db.ErrConfirmationPending) {
+		// The earlier link still works; say the same thing as for a new one.
+		page.Notice = notice
+		h.renderLite(w, r, http.StatusOK, page)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Digest subscribe error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.mailer.Send(digest.ConfirmationMessage(*sub, h.baseURL)); err != nil {
+		slog.ErrorContext(r.Context(), "Digest confirmation email error", "error", err)
+		page.Error = "Failed to send the confirmation email, please try again later"
+		h.renderLite(w, r, http.StatusBadGateway, page)
+		return
+	}
+
+	page.Notice = notice
+	h.renderLite(w, r, http.StatusOK, page)
+}
+
+// digestName cleans up a location name from the signup form: control and
+// formatting characters become spaces, runs of space are collapsed and the
+// result is cut to maxDigestNameLength characters.
+func digestName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxDigestNameLength {
+		s = strings.TrimSpace(string(runes[:maxDigestNameLength]))
+	}
+	return s
+}
+
+// HandleDigestConfirm confirms a subscription from the link in the opt-in
+// email.
+func (h *Handlers) HandleDigestConfirm(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Daily emails unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	page := &LitePageData{Units: weather.UnitsUS}
+
+	sub, err := h.db.ConfirmDigestSubscription(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Digest confirm error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if sub == nil {
+		page.Error = "That confirmation link is invalid or has been replaced by a newer one."
+		h.renderLite(w, r, http.StatusNotFound, page)
+		return
+	}
+
+	page.Notice = fmt.Sprintf("You're subscribed. The forecast for %s will arrive around %d:00 each day.", sub.Name, sub.SendHour)
+	h.renderLite(w, r, http.StatusOK, page)
+}
+
+// HandleDigestUnsubscribe shows an unsubscribe button on GET and removes the
+// subscription on POST, which is also what mail clients send for RFC 8058
+// one-click unsubscribe. Unsubscribing twice is not an error.
+func (h *Handlers) HandleDigestUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Daily emails unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	page := &LitePageData{Units: weather.UnitsUS}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Link scanners follow GETs, so only POST unsubscribes.
+		page.Notice = "Stop getting the daily forecast email?"
+		page.UnsubscribeToken = token
+	case http.MethodPost:
+		if _, err := h.db.DeleteDigestSubscription(r.Context(), token); err != nil {
+			slog.ErrorContext(r.Context(), "Digest unsubscribe error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		page.Notice = "You're unsubscribed and won't get any more daily forecast emails."
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.renderLite(w, r, http.StatusOK, page)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/digest"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type recordingMailer struct {
+	sent []digest.Message
+}
+
+func (m *recordingMailer) Send(msg digest.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func postForm(target string, form url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestDigestSubscribeConfirmUnsubscribe(t *testing.T) {
+	mock := &mockDB{}
+	mailer := &recordingMailer{}
+	h := &Handlers{
+		db:     mock,
+		mailer: mailer,
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				return &weather.WeatherData{TimeZone: "America/Chicago", Location: "Oklahoma City, Oklahoma"}, nil
+			},
+		},
+		templates: loadTemplates(t),
+		baseURL:   "https://wthr.example",
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleDigestSubscribe(w, postForm("/digest/subscribe", url.Values{
+		"email": {"Me <me@example.com>"}, "lat": {"35.47"}, "lon": {"-97.52"}, "hour": {"6"}, "units": {"metric"},
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	if len(mock.digests) != 1 {
+		t.Fatalf("expected a stored subscription, got %+v", mock.digests)
+	}
+	sub := mock.digests[0]
+	if sub.Email != "me@example.com" || sub.TimeZone != "America/Chicago" || sub.SendHour != 6 || sub.Units != "metric" || sub.Name != "Oklahoma City, Oklahoma" {
+		t.Errorf("unexpected subscription %+v", sub)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "me@example.com" ||
+		!strings.Contains(mailer.sent[0].Text, "https://wthr.example/digest/confirm?token=confirm1") {
+		t.Fatalf("expected a confirmation email, got %+v", mailer.sent)
+	}
+	if !strings.Contains(w.Body.String(), "Check me@example.com") {
+		t.Errorf("expected check your email notice")
+	}
+
+	// Asking again while the link is outstanding doesn't send another.
+	w = httptest.NewRecorder()
+	h.HandleDigestSubscribe(w, postForm("/digest/subscribe", url.Values{
+		"email": {"me@example.com"}, "lat": {"35.47"}, "lon": {"-97.52"},
+	}))
+	if w.Code != http.StatusOK || len(mailer.sent) != 1 || len(mock.digests) != 1 {
+		t.Fatalf("expected no second confirmation, got %v with %d emails", w.Code, len(mailer.sent))
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleDigestConfirm(w, httptest.NewRequest("GET", "/digest/confirm?token=confirm1", nil))
+	if w.Code != http.StatusOK || !mock.digests[0].Confirmed {
+		t.Fatalf("expected confirmation, got %v", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleDigestConfirm(w, httptest.NewRequest("GET", "/digest/confirm?token=bogus", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status NotFound for unknown token, got %v", w.Code)
+	}
+
+	// GET only shows the button so link scanners can't unsubscribe people.
+	w = httptest.NewRecorder()
+	h.HandleDigestUnsubscribe(w, httptest.NewRequest("GET", "/digest/unsubscribe?token=unsubscribe1", nil))
+	if w.Code != http.StatusOK || len(mock.digests) != 1 {
+		t.Fatalf("expected GET to leave the subscription, got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `action="/digest/unsubscribe?token=unsubscribe1"`) {
+		t.Errorf("expected unsubscribe form")
+	}
+
+	// RFC 8058 one-click POST
+	w = httptest.NewRecorder()
+	h.HandleDigestUnsubscribe(w, postForm("/digest/unsubscribe?token=unsubscribe1", url.Values{"List-Unsubscribe": {"One-Click"}}))
+	if w.Code != http.StatusOK || len(mock.digests) != 0 {
+		t.Errorf("expected POST to unsubscribe, got %v %+v", w.Code, mock.digests)
+	}
+}
+
+func TestDigestSubscribe_BadRequest(t *testing.T) {
+	h := &Handlers{db: &mockDB{}, mailer: &recordingMailer{}, weather: &mockWeather{}, templates: loadTemplates(t)}
+
+	for _, form := range []url.Values{
+		{"email": {"nope"}, "lat": {"35"}, "lon": {"-97"}},
+		{"email": {"me@example.com"}, "lat": {"95"}, "lon": {"-97"}},
+		{"email": {"me@example.com"}, "lat": {"35"}, "lon": {"-97"}, "hour": {"24"}},
+		{"email": {"me@example.com"}, "lat": {"35"}, "lon": {"-97"}, "units": {"kelvin"}},
+	} {
+		w := httptest.NewRecorder()
+		h.HandleDigestSubscribe(w, postForm("/digest/subscribe", form))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status BadRequest for %v, got %v", form, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("expected the error page to be HTML, got %q", ct)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	(&Handlers{db: &mockDB{}}).HandleDigestSubscribe(w, postForm("/digest/subscribe", url.Values{}))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable without a mailer, got %v", w.Code)
+	}
+}
+
+func TestDigestSubscribe_Name(t *testing.T) {
+	mock := &mockDB{}
+	h := &Handlers{db: mock, mailer: &recordingMailer{}, weather: &mockWeather{}, templates: loadTemplates(t)}
+
+	w := httptest.NewRecorder()
+	h.HandleDigestSubscribe(w, postForm("/digest/subscribe", url.Values{
+		"email": {"me@example.com"}, "lat": {"35"}, "lon": {"-97"}, "time_zone": {"America/Chicago"},
+		"name": {"  Home\r\nBcc: you@example.com\u200b " + strings.Repeat("x", 100)},
+	}))
+	if w.Code != http.StatusOK || len(mock.digests) != 1 {
+		t.Fatalf("expected a stored subscription, got %v", w.Code)
+	}
+	name := mock.digests[0].Name
+	if strings.ContainsAny(name, "\r\n\u200b") || !strings.HasPrefix(name, "Home Bcc: you@example.com x") {
+		t.Errorf("expected control characters replaced, got %q", name)
+	}
+	if n := len([]rune(name)); n != maxDigestNameLength {
+		t.Errorf("expected the name cut to %d characters, got %d", maxDigestNameLength, n)
+	}
+}
+
+func TestDigestSubscribe_RateLimit(t *testing.T) {
+	mailer := &recordingMailer{}
+	h := &Handlers{db: &mockDB{}, mailer: mailer, weather: &mockWeather{}, templates: loadTemplates(t)}
+	subscribe := func(email, lat, remote string) int {
+		req := postForm("/digest/subscribe", url.Values{
+			"email": {email}, "lat": {lat}, "lon": {"-97"}, "time_zone": {"UTC"},
+		})
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.HandleDigestSubscribe(w, req)
+		return w.Code
+	}
+
+	// One address, many locations, from many clients.
+	for i := range digestSignupsPerEmail {
+		if code := subscribe("me@example.com", fmt.Sprint(30+i), fmt.Sprintf("192.0.2.%d:1234", i)); code != http.StatusOK {
+			t.Fatalf("signup %d: expected status OK, got %v", i, code)
+		}
+	}
+	if code := subscribe("ME@example.com", "40", "192.0.2.99:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the address to be limited, got %v", code)
+	}
+
+	// One client, many addresses.
+	for i := range digestSignupsPerIP {
+		if code := subscribe(fmt.Sprintf("user%d@example.com", i), "35", "198.51.100.1:1234"); code != http.StatusOK {
+			t.Fatalf("signup %d: expected status OK, got %v", i, code)
+		}
+	}
+	if code := subscribe("other@example.com", "35", "198.51.100.1:5678"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the client to be limited, got %v", code)
+	}
+	if want := digestSignupsPerEmail + digestSignupsPerIP; len(mailer.sent) != want {
+		t.Errorf("expected %d confirmation emails, got %d", want, len(mailer.sent))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+
+	if ip := (&Handlers{}).clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected the connection's address without a trusted header, got %q", ip)
+	}
+	h := &Handlers{clientIPHeader: "X-Forwarded-For"}
+	if ip := h.clientIP(req); ip != "198.51.100.7" {
+		t.Errorf("expected the address the proxy added, got %q", ip)
+	}
+	req.Header.Del("X-Forwarded-For")
+	if ip := h.clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected the connection's address without the header, got %q", ip)
+	}
+}
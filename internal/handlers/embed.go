@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// siteFramePolicy keeps the main site out of other sites' frames; only the
+// embed widget may be framed elsewhere.
+const siteFramePolicy = "frame-ancestors 'self'"
+
+// embedPolicy is the widget's policy: no scripts, no third party requests,
+// and the frame-ancestors sources from EMBED_FRAME_ANCESTORS appended.
+const embedPolicy = "default-src 'none'; style-src 'unsafe-inline'; base-uri 'none'; form-action 'none'; frame-ancestors "
+
+// EmbedSize is the iframe size, in CSS pixels, for a widget size.
+type EmbedSize struct {
+	Name          string
+	Width, Height int
+}
+
+// embedSizes lists the widget sizes, smallest first. static/js/embed.js
+// keeps a copy.
+var embedSizes = []EmbedSize{
+	{"small", 240, 110},
+	{"medium", 320, 180},
+	{"large", 320, 320},
+}
+
+var embedThemes = []string{"auto", "light", "dark"}
+
+// embedIcons stands in for the icon font, which the widget doesn't load.
+var embedIcons = map[string]string{
+	"sunny":               "☀️",
+	"clear_night":         "🌙",
+	"partly_cloudy_day":   "⛅",
+	"partly_cloudy_night": "☁️",
+	"cloud":               "☁️",
+	"rainy":               "🌧️",
+	"thunderstorm":        "⛈️",
+	"weather_snowy":       "🌨️",
+	"foggy":               "🌫️",
+	"air":                 "💨",
+}
+
+// EmbedPageData is the data passed to the embed.html template.
+type EmbedPageData struct {
+	Weather *weather.WeatherData
+	Size    EmbedSize
+	Theme   string
+	// URL is the location's permalink, opened by clicking the widget.
+	URL string
+}
+
+// Icon returns the symbol shown for a mapIcon name.
+func (EmbedPageData) Icon(name string) string {
+	if s, ok := embedIcons[name]; ok {
+		return s
+	}
+	return "🌡️"
+}
+
+// Day abbreviates a forecast period name, e.g. "Tue" for "Tuesday".
+func (EmbedPageData) Day(name string) string {
+	if r := []rune(name); len(r) > 3 {
+		return string(r[:3])
+	}
+	return name
+}
+
+// EmbedBuilderData is the data passed to the embed_builder.html template.
+// EmbedURL and the snippets are empty until a location has been found.
+type EmbedBuilderData struct {
+	Title         string
+	Description   string
+	CanonicalURL  string
+	Location      string
+	Size          EmbedSize
+	Theme         string
+	Units         weather.Units
+	Sizes         []EmbedSize
+	Themes        []string
+	Error         string
+	EmbedURL      string
+	IframeSnippet string
+	ScriptSnippet string
+}
+
+// FramePolicy sets the main site's frame-ancestors policy on every
+// response. HandleEmbed replaces it with the widget's own.
+func FramePolicy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", siteFramePolicy)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleEmbed serves the current conditions at ?lat=&lon= (or ?location=)
+// as a self-contained widget for an iframe: inline styles, no scripts, and
+// a frame-ancestors policy from EMBED_FRAME_ANCESTORS rather than the main
+// site's. size (small, medium or large), theme (auto, light or dark),
+// units and name, which replaces the location's label, are optional; the
+// units cookie is ignored, since the widget is cached publicly.
+func (h *Handlers) HandleEmbed(w http.ResponseWriter, r *http.Request) {
+	ancestors := h.embedAncestors
+	if ancestors == "" {
+		ancestors = "*"
+	}
+	w.Header().Set("Content-Security-Policy", embedPolicy+ancestors)
+
+	q := r.URL.Query()
+	size, theme, ok := embedOptions(q)
+	if !ok {
+		http.Error(w, "size must be small, medium or large and theme auto, light or dark", http.StatusBadRequest)
+		return
+	}
+	// The response is cached publicly, so only the URL picks the units.
+	units, err := weather.ParseUnits(q.Get("units"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lat, lon, status, msg := h.locationFromQuery(r.Context(), q)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+	wd = wd.WithUnits(units)
+	if name := strings.TrimSpace(q.Get("name")); name != "" {
+		wd.Location = name
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+		return
+	}
+	page := &EmbedPageData{
+		Weather: wd,
+		Size:    size,
+		Theme:   theme,
+		URL:     h.baseURL + coordsPath(lat, lon) + "?units=" + string(units),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := h.render(r.Context(), w, "embed.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Template error", "error", err)
+	}
+}
+
+// HandleEmbedBuilder serves a form for choosing a widget's location, size,
+// theme and units, with a preview and the iframe and script snippets that
+// embed it. The location is geocoded once here so embedded widgets don't
+// look it up on every load.
+func (h *Handlers) HandleEmbedBuilder(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page := &EmbedBuilderData{
+		Title:        "Embed the weather - wthr.lol",
+		Description:  "Put the current weather on your own page.",
+		CanonicalURL: h.baseURL + "/embed/builder",
+		Location:     strings.TrimSpace(q.Get("location")),
+		Sizes:        embedSizes,
+		Themes:       embedThemes,
+	}
+
+	status := http.StatusOK
+	size, theme, ok := embedOptions(q)
+	units, err := unitsFromRequest(r)
+	switch {
+	case !ok:
+		status = http.StatusBadRequest
+		page.Error = "Unknown size or theme"
+		size, theme = embedSizes[1], embedThemes[0]
+	case err != nil:
+		status = http.StatusBadRequest
+		page.Error = err.Error()
+		units = weather.UnitsUS
+	}
+	page.Size, page.Theme, page.Units = size, theme, units
+
+	if page.Location != "" && page.Error == "" {
+		lat, lon, err := h.weather.GeocodeContext(r.Context(), page.Location)
+		if err != nil {
+			status = http.StatusNotFound
+			page.Error = fmt.Sprintf("Location not found: %s", page.Location)
+		} else {
+			h.embedSnippets(page, lat, lon)
+		}
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.render(r.Context(), w, "embed_builder.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Template error", "error", err)
+	}
+}
+
+// embedSnippets fills in the widget URL and the snippets embedding it.
+func (h *Handlers) embedSnippets(page *EmbedBuilderData, lat, lon float64) {
+	v := url.Values{}
+	v.Set("lat", fmt.Sprintf("%.2f", lat))
+	v.Set("lon", fmt.Sprintf("%.2f", lon))
+	v.Set("name", page.Location)
+	v.Set("size", page.Size.Name)
+	v.Set("theme", page.Theme)
+	v.Set("units", string(page.Units))
+	page.EmbedURL = h.baseURL + "/embed?" + v.Encode()
+
+	esc := template.HTMLEscapeString
+	page.IframeSnippet = fmt.Sprintf(
+		`<iframe src="%s" width="%d" height="%d" title="Weather for %s" style="border:0" loading="lazy"></iframe>`,
+		esc(page.EmbedURL), page.Size.Width, page.Size.Height, esc(page.Location))
+	page.ScriptSnippet = fmt.Sprintf(
+		`<div data-wthr-embed data-lat="%.2f" data-lon="%.2f" data-name="%s" data-size="%s" data-theme="%s" data-units="%s"></div>
+<script src="%s/static/js/embed.js" async></script>`,
+		lat, lon, esc(page.Location), page.Size.Name, page.Theme, page.Units, h.baseURL)
+}
+
+// embedOptions parses the size and theme query parameters, defaulting to a
+// medium widget following the viewer's color scheme.
+func embedOptions(q url.Values) (EmbedSize, string, bool) {
+	size, theme := embedSizes[1], embedThemes[0]
+	if s := q.Get("size"); s != "" {
+		i := slices.IndexFunc(embedSizes, func(es EmbedSize) bool { return es.Name == s })
+		if i == -1 {
+			return EmbedSize{}, "", false
+		}
+		size = embedSizes[i]
+	}
+	if t := q.Get("theme"); t != "" {
+		if !slices.Contains(embedThemes, t) {
+			return EmbedSize{}, "", false
+		}
+		theme = t
+	}
+	return size, theme, true
+}
+
+// locationFromQuery resolves ?lat=&lon=, or ?location= by geocoding. On
+// failure it returns the HTTP status and message to respond with.
+func (h *Handlers) locationFromQuery(ctx context.Context, q url.Values) (float64, float64, int, string) {
+	if location := q.Get("location"); location != "" {
+		lat, lon, err := h.weather.GeocodeContext(ctx, location)
+		if err != nil {
+			return 0, 0, http.StatusNotFound, "location not found"
+		}
+		return lat, lon, 0, ""
+	}
+	lat, lon, ok := parseCoords(q.Get("lat") + "," + q.Get("lon"))
+	if !ok {
+		return 0, 0, http.StatusBadRequest, "lat and lon, or location, are required"
+	}
+	return lat, lon, 0, ""
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func TestHandleEmbed(t *testing.T) {
+	wd := sampleWeather()
+	wd.Current.Icon = "sunny"
+	wd.Forecast = []weather.DailyForecast{{Name: "Tuesday", HighTemp: 75, LowTemp: 50, TemperatureUnit: "F", Icon: "rainy"}}
+	wd.Alerts = []weather.Alert{{Event: "Heat Advisory"}}
+	h := &Handlers{
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) { return wd, nil },
+		},
+		templates:      loadTemplates(t),
+		baseURL:        "https://wthr.example",
+		embedAncestors: "https://intranet.example",
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleEmbed(w, httptest.NewRequest("GET", "/embed?lat=37.77&lon=-122.42&size=large&theme=dark&units=metric&name=Office", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.HasSuffix(csp, "frame-ancestors https://intranet.example") {
+		t.Errorf("expected the embed frame policy, got %q", csp)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`<div class="location">Office</div>`,
+		`20°C`,
+		`☀️`,
+		`⚠ Heat Advisory`,
+		`<div class="muted">Tue</div>`,
+		`href="https://wthr.example/w/37.77,-122.42?units=metric"`,
+		`--bg: #0f172a`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in widget", want)
+		}
+	}
+	if strings.Contains(body, "<script") || strings.Contains(body, "/static/") {
+		t.Errorf("widget must be self-contained")
+	}
+
+	// The widget is cached publicly, so a visitor's units cookie can't
+	// change it.
+	req := httptest.NewRequest("GET", "/embed?lat=37.77&lon=-122.42", nil)
+	req.AddCookie(&http.Cookie{Name: "units", Value: "metric"})
+	w = httptest.NewRecorder()
+	h.HandleEmbed(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "68°F") {
+		t.Errorf("expected the widget in the URL's units, got %v", w.Code)
+	}
+
+	for _, target := range []string{"/embed", "/embed?lat=1&lon=1&size=huge", "/embed?lat=1&lon=1&theme=pink", "/embed?lat=1&lon=1&units=k"} {
+		w := httptest.NewRecorder()
+		h.HandleEmbed(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status BadRequest, got %v", target, w.Code)
+		}
+	}
+}
+
+func TestFramePolicy(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{}, templates: loadTemplates(t)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /lite", h.HandleLite)
+	mux.HandleFunc("GET /embed", h.HandleEmbed)
+	handler := FramePolicy(mux)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/lite", nil))
+	if csp := w.Header().Get("Content-Security-Policy"); csp != "frame-ancestors 'self'" {
+		t.Errorf("expected the site frame policy, got %q", csp)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/embed?lat=1&lon=1", nil))
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.HasSuffix(csp, "frame-ancestors *") {
+		t.Errorf("expected the embed to allow any site by default, got %q", csp)
+	}
+}
+
+func TestHandleEmbedBuilder(t *testing.T) {
+	h := &Handlers{
+		weather: &mockWeather{
+			geocodeFunc: func(query string) (float64, float64, error) {
+				if query == "Nowhere" {
+					return 0, 0, errors.New("not found")
+				}
+				return 35.4676, -97.5164, nil
+			},
+		},
+		templates: loadTemplates(t),
+		baseURL:   "https://wthr.example",
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleEmbedBuilder(w, httptest.NewRequest("GET", "/embed/builder?location=Oklahoma+City&size=small&theme=light", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", w.Code)
+	}
+	body := w.Body.String()
+	embedURL := "https://wthr.example/embed?lat=35.47&amp;lon=-97.52&amp;name=Oklahoma&#43;City&amp;size=small&amp;theme=light&amp;units=us"
+	if !strings.Contains(body, `src="`+embedURL+`" width="240" height="110"`) {
+		t.Errorf("expected a preview iframe, got %s", body)
+	}
+	if !strings.Contains(body, `data-lat=&#34;35.47&#34; data-lon=&#34;-97.52&#34; data-name=&#34;Oklahoma City&#34; data-size=&#34;small&#34;`) ||
+		!strings.Contains(body, `https://wthr.example/static/js/embed.js`) {
+		t.Errorf("expected the script snippet, got %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleEmbedBuilder(w, httptest.NewRequest("GET", "/embed/builder?location=Nowhere", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "Location not found: Nowhere") {
+		t.Errorf("expected not found, got %v", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("expected the error page to be HTML, got %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleEmbedBuilder(w, httptest.NewRequest("GET", "/embed/builder", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "<iframe") {
+		t.Errorf("expected just the form, got %v", w.Code)
+	}
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// atomFeed and friends are the subset of RFC 4287 the alert feeds use.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published,omitempty"`
+	Link      atomLink   `xml:"link"`
+	Summary   string     `xml:"summary,omitempty"`
+	Content   atomText   `xml:"content"`
+	Category  []atomTerm `xml:"category"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+// rssFeed and friends are RSS 2.0.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          atomLink  `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Category    []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// feedMaxAge is how long feed readers and proxies may cache a feed.
+const feedMaxAge = 5 * time.Minute
+
+// HandleAlertsFeed serves the alert feed for /feeds/alerts?lat=&lon=.
+func (h *Handlers) HandleAlertsFeed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lat, lon, ok := parseCoords(q.Get("lat") + "," + q.Get("lon"))
+	if !ok {
+		http.Error(w, "valid lat and lon are required", http.StatusBadRequest)
+		return
+	}
+	h.renderAlertsFeed(w, r, lat, lon, "", coordsPath(lat, lon))
+}
+
+// HandleCoordsAlertsFeed serves the alert feed for /feeds/alerts/{lat},{lon}.
+func (h *Handlers) HandleCoordsAlertsFeed(w http.ResponseWriter, r *http.Request) {
+	lat, lon, ok := parseCoords(r.PathValue("coords"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	path := coordsPath(lat, lon)
+	if feed := feedPath(path); r.URL.Path != feed {
+		redirectCanonical(w, r, feed)
+		return
+	}
+	h.renderAlertsFeed(w, r, lat, lon, "", path)
+}
+
+// HandlePlaceAlertsFeed serves the alert feed for /feeds/alerts/{state}/{place}.
+func (h *Handlers) HandlePlaceAlertsFeed(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	place, err := h.db.FindPlace(r.Context(), r.PathValue("state"), strings.ToLower(r.PathValue("place")))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Place lookup error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if place == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := placePath(place)
+	if feed := feedPath(path); r.URL.Path != feed {
+		redirectCanonical(w, r, feed)
+		return
+	}
+	h.renderAlertsFeed(w, r, place.Latitude, place.Longitude, placeName(*place), path)
+}
+
+// feedPath returns the alert feed path for a permalink path.
+func feedPath(permalink string) string {
+	return "/feeds/alerts" + strings.TrimPrefix(permalink, "/w")
+}
+
+// renderAlertsFeed writes the active alerts for a point as Atom, or RSS 2.0
+// when format=rss, from the active alerts index. path is the permalink page
+// entries link back to. The feed is dated by its newest alert, or by the
+// index when there are none. The response carries an ETag, and a
+// Last-Modified of the index fetch or the newest alert, whichever is later,
+// so conditional requests get a 304; Last-Modified never goes back when
+// the newest alert expires, which would hide the change from readers that
+// only send If-Modified-Since.
+func (h *Handlers) renderAlertsFeed(w http.ResponseWriter, r *http.Request, lat, lon float64, name, path string) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "atom" && format != "rss" {
+		http.Error(w, "format must be atom or rss", http.StatusBadRequest)
+		return
+	}
+
+	if h.alerts == nil {
+		http.Error(w, "Alerts unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	idx, err := h.alerts.IndexContext(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Alerts feed error", "error", err)
+		http.Error(w, "Failed to retrieve alerts", http.StatusBadGateway)
+		return
+	}
+	alerts, err := h.alerts.GetAlertsContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Alerts feed error", "error", err)
+		http.Error(w, "Failed to retrieve alerts", http.StatusBadGateway)
+		return
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%.2f, %.2f", lat, lon)
+	}
+	page := h.baseURL + path
+	self := h.baseURL + r.URL.RequestURI()
+
+	var updated time.Time
+	for _, a := range alerts {
+		if t := alertUpdated(a); t.After(updated) {
+			updated = t
+		}
+	}
+	if updated.IsZero() {
+		updated = idx.Fetched
+	}
+	lastModified := updated
+	if idx.Fetched.After(lastModified) {
+		lastModified = idx.Fetched
+	}
+
+	var body []byte
+	var contentType string
+	if format == "rss" {
+		body, err = rssAlerts(alerts, name, page, self, updated)
+		contentType = "application/rss+xml; charset=utf-8"
+	} else {
+		body, err = atomAlerts(alerts, name, page, self, updated)
+		contentType = "application/atom+xml; charset=utf-8"
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Alerts feed encoding error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedMaxAge.Seconds())))
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(body))
+}
+
+func atomAlerts(alerts []weather.Alert, name, page, self string, updated time.Time) ([]byte, error) {
+	feed := atomFeed{
+		ID:      page + "#alerts",
+		Title:   "Weather alerts for " + name,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: page},
+		},
+		Author: atomAuthor{Name: "wthr.lol"},
+	}
+	for _, a := range alerts {
+		entry := atomEntry{
+			ID:       alertFeedID(a, page),
+			Title:    alertTitle(a),
+			Updated:  alertUpdated(a).UTC().Format(time.RFC3339),
+			Link:     atomLink{Rel: "alternate", Type: "text/html", Href: page},
+			Summary:  a.Headline,
+			Content:  atomText{Type: "text", Body: alertText(a)},
+			Category: []atomTerm{{Term: a.Event}, {Term: a.Severity}},
+		}
+		if !a.Sent.IsZero() {
+			entry.Published = a.Sent.UTC().Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshalFeed(feed)
+}
+
+func rssAlerts(alerts []weather.Alert, name, page, self string, updated time.Time) ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       "Weather alerts for " + name,
+			Link:        page,
+			Self:        atomLink{Rel: "self", Type: "application/rss+xml", Href: self},
+			Description: "Active National Weather Service alerts for " + name,
+			TTL:         int(feedMaxAge.Minutes()),
+		},
+	}
+	if !updated.IsZero() {
+		feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, a := range alerts {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       alertTitle(a),
+			Link:        page,
+			GUID:        rssGUID{ID: alertFeedID(a, page)},
+			PubDate:     alertUpdated(a).UTC().Format(time.RFC1123Z),
+			Category:    []string{a.Event, a.Severity},
+			Description: alertText(a),
+		})
+	}
+	return marshalFeed(feed)
+}
+
+func marshalFeed(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// alertFeedID is the entry ID: the NWS alert id, which is already a URN and
+// stays the same for a given alert version.
+func alertFeedID(a weather.Alert, page string) string {
+	if a.ID != "" {
+		return a.ID
+	}
+	return fmt.Sprintf("%s#%s-%d", page, strings.ReplaceAll(a.Event, " ", "-"), a.Sent.Unix())
+}
+
+// alertUpdated is when the alert version was issued.
+func alertUpdated(a weather.Alert) time.Time {
+	for _, t := range []time.Time{a.Sent, a.Effective, a.Onset} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Unix(0, 0)
+}
+
+func alertTitle(a weather.Alert) string {
+	if a.Headline != "" {
+		return a.Headline
+	}
+	return a.Event
+}
+
+// alertText is the full alert body: description, then instructions.
+func alertText(a weather.Alert) string {
+	var parts []string
+	if a.AreaDesc != "" {
+		parts = append(parts, a.AreaDesc)
+	}
+	if a.Description != "" {
+		parts = append(parts, a.Description)
+	}
+	if a.Instruction != "" {
+		parts = append(parts, a.Instruction)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func feedHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	var al weather.AlertsResponse
+	err := json.Unmarshal([]byte(`{"features": [
+		{"id": "urn:oid:2.49.0.1.840.0.1", "geometry": {"type": "Polygon", "coordinates": [[[-98,35],[-97,35],[-97,36],[-98,36],[-98,35]]]},
+			"properties": {"event": "Tornado Warning", "headline": "Tornado Warning issued May 6",
+				"description": "A tornado was observed.", "instruction": "TAKE COVER NOW!",
+				"severity": "Extreme", "sent": "2025-05-06T20:00:00Z"}},
+		{"id": "urn:oid:2.49.0.1.840.0.2", "geometry": {"type": "Polygon", "coordinates": [[[-98,35],[-97,35],[-97,36],[-98,36],[-98,35]]]},
+			"properties": {"event": "Heat Advisory", "severity": "Moderate", "sent": "2025-05-06T19:00:00Z"}},
+		{"id": "urn:oid:2.49.0.1.840.0.3", "geometry": {"type": "Polygon", "coordinates": [[[-75,40],[-74,40],[-74,41],[-75,41],[-75,40]]]},
+			"properties": {"event": "Flood Warning", "severity": "Severe", "sent": "2025-05-06T21:00:00Z"}}
+	]}`), &al)
+	if err != nil {
+		t.Fatalf("failed to decode alerts: %v", err)
+	}
+	idx := weather.NewAlertIndex(&al, nil)
+	idx.Fetched = time.Date(2025, 5, 6, 20, 30, 0, 0, time.UTC)
+	return &Handlers{
+		baseURL: "https://wthr.lol",
+		db: &mockDB{
+			findPlaceFunc: func(state, slug string) (*db.Place, error) {
+				if state == "OK" && slug == "oklahoma-city" {
+					return &db.Place{Name: "Oklahoma City", State: "OK", Latitude: 35.4676, Longitude: -97.5164}, nil
+				}
+				return nil, nil
+			},
+		},
+		alerts: &mockAlerts{index: idx},
+	}
+}
+
+func TestHandleAlertsFeed_Atom(t *testing.T) {
+	h := feedHandlers(t)
+
+	w := httptest.NewRecorder()
+	h.HandleAlertsFeed(w, httptest.NewRequest("GET", "/feeds/alerts?lat=35.4676&lon=-97.5164", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	// The fetch is later than the newest alert, and Last-Modified mustn't go
+	// back to an older alert's once that expires.
+	if lm := w.Header().Get("Last-Modified"); lm != "Tue, 06 May 2025 20:30:00 GMT" {
+		t.Errorf("expected Last-Modified of the fetch, got %q", lm)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	if feed.Title != "Weather alerts for 35.47, -97.52" || feed.Updated != "2025-05-06T20:00:00Z" {
+		t.Errorf("unexpected feed %q updated %q", feed.Title, feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.ID != "urn:oid:2.49.0.1.840.0.1" || e.Title != "Tornado Warning issued May 6" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if !strings.Contains(e.Content.Body, "TAKE COVER NOW!") {
+		t.Errorf("expected full instruction text in content, got %q", e.Content.Body)
+	}
+	if e.Link.Href != "https://wthr.lol/w/35.47,-97.52" {
+		t.Errorf("unexpected entry link %q", e.Link.Href)
+	}
+
+	// A repeat request with the ETag is answered from the reader's cache.
+	req := httptest.NewRequest("GET", "/feeds/alerts?lat=35.4676&lon=-97.5164", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w2 := httptest.NewRecorder()
+	h.HandleAlertsFeed(w2, req)
+	if w2.Code != http.StatusNotModified {
+		t.Errorf("expected status NotModified, got %v", w2.Code)
+	}
+}
+
+func TestHandleAlertsFeed_RSS(t *testing.T) {
+	h := feedHandlers(t)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/feeds/alerts/OK/oklahoma-city?format=rss", nil)
+	req.SetPathValue("state", "OK")
+	req.SetPathValue("place", "oklahoma-city")
+	h.HandlePlaceAlertsFeed(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	ch := feed.Channel
+	if feed.Version != "2.0" || ch.Title != "Weather alerts for Oklahoma City, OK" {
+		t.Errorf("unexpected channel %+v", ch)
+	}
+	// The channel link and atom:link share a local name, so check the raw XML.
+	if !strings.Contains(w.Body.String(), "<link>https://wthr.lol/w/OK/oklahoma-city</link>") {
+		t.Errorf("expected channel link to the permalink page")
+	}
+	if len(ch.Items) != 2 || ch.Items[0].GUID.ID != "urn:oid:2.49.0.1.840.0.1" || ch.Items[0].GUID.IsPermaLink {
+		t.Fatalf("unexpected items %+v", ch.Items)
+	}
+	if ch.Items[0].PubDate != "Tue, 06 May 2025 20:00:00 +0000" {
+		t.Errorf("unexpected pubDate %q", ch.Items[0].PubDate)
+	}
+}
+
+func TestHandleAlertsFeed_Paths(t *testing.T) {
+	h := feedHandlers(t)
+
+	tests := []struct {
+		name     string
+		target   string
+		coords   string
+		wantCode int
+		wantLoc  string
+	}{
+		{"canonical coords", "/feeds/alerts/35.47,-97.52", "35.47,-97.52", http.StatusOK, ""},
+		{"coords redirect", "/feeds/alerts/35.4676,-97.5164?format=rss", "35.4676,-97.5164", http.StatusMovedPermanently, "/feeds/alerts/35.47,-97.52?format=rss"},
+		{"bad coords", "/feeds/alerts/north", "north", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.SetPathValue("coords", tt.coords)
+			w := httptest.NewRecorder()
+			h.HandleCoordsAlertsFeed(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %v, got %v", tt.wantCode, w.Code)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.wantLoc {
+				t.Errorf("expected Location %q, got %q", tt.wantLoc, loc)
+			}
+		})
+	}
+
+	for _, target := range []string{"/feeds/alerts", "/feeds/alerts?lat=35&lon=-97&format=json"} {
+		w := httptest.NewRecorder()
+		h.HandleAlertsFeed(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status BadRequest for %s, got %v", target, w.Code)
+		}
+	}
+}
+
+func TestHandleAlertsFeed_Empty(t *testing.T) {
+	h := feedHandlers(t)
+
+	w := httptest.NewRecorder()
+	h.HandleAlertsFeed(w, httptest.NewRequest("GET", "/feeds/alerts?lat=45&lon=-120", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	// With nothing to date it by, the feed is as fresh as the index.
+	if len(feed.Entries) != 0 || feed.Updated != "2025-05-06T20:30:00Z" {
+		t.Errorf("expected an empty feed updated when alerts were fetched, got %d entries updated %q", len(feed.Entries), feed.Updated)
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != "Tue, 06 May 2025 20:30:00 GMT" {
+		t.Errorf("expected Last-Modified of the fetch, got %q", lm)
+	}
+
+	h.alerts = nil
+	w = httptest.NewRecorder()
+	h.HandleAlertsFeed(w, httptest.NewRequest("GET", "/feeds/alerts?lat=45&lon=-120", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable without an index, got %v", w.Code)
+	}
+}
@@ -1,37 +1,118 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/mail"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/digest"
+	"github.com/swelljoe/wthr.lol/internal/live"
 	"github.com/swelljoe/wthr.lol/internal/weather"
 )
 
 // Database defines the interface for database operations needed by handlers
 type Database interface {
-	SearchPlaces(query string) ([]db.Place, error)
+	SearchPlaces(ctx context.Context, query string) ([]db.Place, error)
 	Ping() error
-	SaveAppInterest(email string, android bool, ios bool, country string) error
+	SaveAppInterest(ctx context.Context, email string, android bool, ios bool, country string) error
+	FindPlace(ctx context.Context, state, slug string) (*db.Place, error)
+	CreateSavedLocations(ctx context.Context, locations []db.SavedLocation) (string, error)
+	GetSavedLocations(ctx context.Context, token string) ([]db.SavedLocation, error)
+	UpdateSavedLocations(ctx context.Context, token string, locations []db.SavedLocation) (bool, error)
+	SavePushSubscription(ctx context.Context, sub db.PushSubscription) error
+	DeletePushSubscription(ctx context.Context, endpoint string) error
+	CreateWebhook(ctx context.Context, wh *db.Webhook) error
+	ListWebhooks(ctx context.Context) ([]db.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) (bool, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]db.WebhookDelivery, error)
+	AlertHistory(ctx context.Context, scope string, since time.Time, limit int) ([]db.AlertVersion, error)
+	SaveDigestSubscription(ctx context.Context, sub *db.DigestSubscription) error
+	ConfirmDigestSubscription(ctx context.Context, token string) (*db.DigestSubscription, error)
+	DeleteDigestSubscription(ctx context.Context, token string) (bool, error)
+	GetStation(ctx context.Context, id string) (*db.Station, error)
+	ListStations(ctx context.Context) ([]db.Station, error)
+	ObservationDays(ctx context.Context, station, since string) ([]db.ObservationDay, error)
+	GetForecastSite(ctx context.Context, id string) (*db.ForecastSite, error)
+	ListForecastSites(ctx context.Context) ([]db.ForecastSite, error)
+	IssuedForecasts(ctx context.Context, site, since string) ([]db.IssuedForecast, error)
+	ObservationHours(ctx context.Context, station, since string) ([]db.ObservationHour, error)
+}
+
+// WeatherService defines the weather operations needed by handlers
+type WeatherService interface {
+	GetWeatherContext(ctx context.Context, lat, lon float64) (*weather.WeatherData, error)
+	GeocodeContext(ctx context.Context, query string) (float64, float64, error)
+}
+
+// AlertIndexer provides the current snapshot of active alerts and the
+// alerts covering a point; *weather.ActiveAlerts implements it.
+type AlertIndexer interface {
+	IndexContext(ctx context.Context) (*weather.AlertIndex, error)
+	GetAlertsContext(ctx context.Context, lat, lon float64) ([]weather.Alert, error)
+}
+
+// LiveUpdates streams weather changes for a location; *live.Hub implements
+// it.
+type LiveUpdates interface {
+	Subscribe(client string, lat, lon float64) (<-chan live.Update, func(), error)
+	Current(lat, lon float64) (live.Update, error)
 }
 
 // Handlers holds dependencies for HTTP handlers
 type Handlers struct {
 	db        Database
-	weather   *weather.Service
+	weather   WeatherService
+	alerts    AlertIndexer
+	mailer    digest.Sender // nil when email is not configured
+	live      LiveUpdates
 	templates *template.Template
+	baseURL   string // Public site URL used for canonical and Open Graph links
+	pushKey   string // VAPID public key; empty when Web Push is not configured
+	// webhookToken is the bearer token for the webhook management API, which
+	// is disabled when it is empty.
+	webhookToken string
+	// embedAncestors is the frame-ancestors source list for the embed
+	// widget; empty allows any site.
+	embedAncestors string
+	// clientIPHeader is the header a trusted reverse proxy puts the client's
+	// address in, e.g. X-Forwarded-For; empty uses the connection's address.
+	clientIPHeader string
+	// digestSignups rate limits the daily email signup form.
+	digestSignups rateLimiter
 }
 
+// PageData is the data passed to the index.html template.
+type PageData struct {
+	Title        string
+	Description  string
+	CanonicalURL string
+	// FeedURL is the alert feed for the page's location, if it has one.
+	FeedURL string
+	// ImageURL is the location's weather card, used as the link preview.
+	ImageURL string
+	// Weather is rendered inline for permalink pages and nil on the plain index.
+	Weather *weather.WeatherData
+	Lat     float64
+	Lon     float64
+}
+
+const defaultTitle = "wthr.lol - Just weather"
+const defaultDescription = "No ads, no tracking, no BS, just weather."
+
 // New creates a new Handlers instance
-func New(database *db.DB, wService *weather.Service) *Handlers {
+func New(database *db.DB, wService *weather.Service, activeAlerts *weather.ActiveAlerts, mailer *digest.SMTPSender, hub *live.Hub) *Handlers {
 	// Parse templates
 	tmpl, err := template.ParseGlob("templates/*.html")
 	if err != nil {
-		log.Printf("Warning: Failed to parse templates: %v", err)
+		slog.Warn("Failed to parse templates", "error", err)
 	}
 
 	// Avoid assigning a typed nil *db.DB into the Database interface.
@@ -41,11 +122,48 @@ func New(database *db.DB, wService *weather.Service) *Handlers {
 	if database != nil {
 		dbInterface = database
 	}
+	var weatherInterface WeatherService
+	if wService != nil {
+		weatherInterface = wService
+	}
+	var alertsInterface AlertIndexer
+	if activeAlerts != nil {
+		alertsInterface = activeAlerts
+	}
+	var mailerInterface digest.Sender
+	if mailer != nil {
+		mailerInterface = mailer
+	}
+
+	var liveInterface LiveUpdates
+	if hub != nil {
+		liveInterface = hub
+	}
+
+	baseURL := strings.TrimRight(os.Getenv("BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://wthr.lol"
+	}
+
+	// Push needs both halves of the key pair; cmd/wthr validates them.
+	var pushKey string
+	if os.Getenv("VAPID_PRIVATE_KEY") != "" {
+		pushKey = os.Getenv("VAPID_PUBLIC_KEY")
+	}
 
 	return &Handlers{
 		db:        dbInterface,
-		weather:   wService,
+		weather:   weatherInterface,
+		alerts:    alertsInterface,
+		mailer:    mailerInterface,
+		live:      liveInterface,
 		templates: tmpl,
+		baseURL:   baseURL,
+		pushKey:   pushKey,
+
+		webhookToken:   os.Getenv("WEBHOOK_API_TOKEN"),
+		embedAncestors: strings.TrimSpace(os.Getenv("EMBED_FRAME_ANCESTORS")),
+		clientIPHeader: strings.TrimSpace(os.Getenv("CLIENT_IP_HEADER")),
 	}
 }
 
@@ -57,9 +175,13 @@ func (h *Handlers) HandleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if h.templates != nil {
-		err := h.templates.ExecuteTemplate(w, "index.html", nil)
+		err := h.render(r.Context(), w, "index.html", &PageData{
+			Title:        defaultTitle,
+			Description:  defaultDescription,
+			CanonicalURL: h.baseURL + "/",
+		})
 		if err != nil {
-			log.Printf("Error executing template: %v", err)
+			slog.ErrorContext(r.Context(), "Error executing template", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	} else {
@@ -98,12 +220,19 @@ func (h *Handlers) HandleWeatherAPI(w http.ResponseWriter, r *http.Request) {
 	var lat, lon float64
 	var err error
 
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("<div class='error'>%s</div>", template.HTMLEscapeString(err.Error()))))
+		return
+	}
+
 	location := r.URL.Query().Get("location")
 	latStr := r.URL.Query().Get("lat")
 	lonStr := r.URL.Query().Get("lon")
 
 	if location != "" {
-		lat, lon, err = h.weather.Geocode(location)
+		lat, lon, err = h.weather.GeocodeContext(r.Context(), location)
 		if err != nil {
 			// Return a nice error fragment? Or just text for now
 			w.WriteHeader(http.StatusNotFound)
@@ -127,17 +256,56 @@ func (h *Handlers) HandleWeatherAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	wd, err := h.weather.GetWeather(lat, lon)
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
 	if err != nil {
-		log.Printf("Weather error: %v", err)
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("<div class='error'>Failed to retrieve weather data</div>"))
 		return
 	}
 
-	if err := h.templates.ExecuteTemplate(w, "weather_fragment", wd); err != nil {
-		log.Printf("Template error: %v", err)
+	if err := h.render(r.Context(), w, "weather_fragment", wd.WithUnits(units)); err != nil {
+		slog.ErrorContext(r.Context(), "Template error", "error", err)
+	}
+}
+
+// HandleWeatherJSON returns the weather for ?lat=&lon= (or ?location=) as
+// JSON, including what changed since the previous forecast.
+func (h *Handlers) HandleWeatherJSON(w http.ResponseWriter, r *http.Request) {
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lat, lon, status, msg := h.locationFromQuery(r.Context(), r.URL.Query())
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, wd.WithUnits(units))
+}
+
+// unitsFromRequest returns the unit system requested by the "units" query
+// parameter, falling back to the "units" cookie the UI stores the user's
+// preference in, and then to US units.
+func unitsFromRequest(r *http.Request) (weather.Units, error) {
+	if q := r.URL.Query().Get("units"); q != "" {
+		return weather.ParseUnits(q)
+	}
+	if c, err := r.Cookie("units"); err == nil {
+		if u, err := weather.ParseUnits(c.Value); err == nil {
+			return u, nil
+		}
 	}
+	return weather.UnitsUS, nil
 }
 
 // HandleSearch performs location autocomplete
@@ -149,9 +317,9 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	places, err := h.db.SearchPlaces(q)
+	places, err := h.db.SearchPlaces(r.Context(), q)
 	if err != nil {
-		log.Printf("Search error: %v", err)
+		slog.ErrorContext(r.Context(), "Search error", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -162,14 +330,14 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 
 	data, err := json.Marshal(places)
 	if err != nil {
-		log.Printf("JSON encode error: %v", err)
+		slog.ErrorContext(r.Context(), "JSON encode error", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if _, err := w.Write(data); err != nil {
-		log.Printf("Response write error: %v", err)
+		slog.ErrorContext(r.Context(), "Response write error", "error", err)
 	}
 }
 
@@ -210,18 +378,18 @@ func (h *Handlers) HandleAppInterest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if h.db != nil {
-		if err := h.db.SaveAppInterest(payload.Email, payload.Android, payload.IOS, payload.Country); err != nil {
-			log.Printf("Failed to save app interest: %v", err)
+		if err := h.db.SaveAppInterest(r.Context(), payload.Email, payload.Android, payload.IOS, payload.Country); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save app interest", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 	} else {
 		// No database available; log the interest so it's not lost during development
-		log.Printf("App interest received (no DB): email=%s android=%t ios=%t country=%s", payload.Email, payload.Android, payload.IOS, payload.Country)
+		slog.InfoContext(r.Context(), "App interest received (no DB)", "email", payload.Email, "android", payload.Android, "ios", payload.IOS, "country", payload.Country)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if _, err := w.Write([]byte(`{"status":"ok"}`)); err != nil {
-		log.Printf("failed to write app interest response: %v", err)
+		slog.ErrorContext(r.Context(), "failed to write app interest response", "error", err)
 	}
 }
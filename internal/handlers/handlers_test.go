@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
 )
 
 func TestHandleHealth(t *testing.T) {
@@ -64,9 +69,20 @@ type mockDB struct {
 	searchPlacesFunc    func(query string) ([]db.Place, error)
 	pingFunc            func() error
 	saveAppInterestFunc func(email string, android bool, ios bool, country string) error
+	findPlaceFunc       func(state, slug string) (*db.Place, error)
+	saved               map[string][]db.SavedLocation
+	push                map[string]db.PushSubscription
+	webhooks            []db.Webhook
+	alertHistoryFunc    func(scope string, since time.Time, limit int) ([]db.AlertVersion, error)
+	digests             []db.DigestSubscription
+	stations            []db.Station
+	observationDays     map[string][]db.ObservationDay
+	forecastSites       []db.ForecastSite
+	issuedForecasts     map[string][]db.IssuedForecast
+	observationHours    map[string][]db.ObservationHour
 }
 
-func (m *mockDB) SearchPlaces(query string) ([]db.Place, error) {
+func (m *mockDB) SearchPlaces(ctx context.Context, query string) ([]db.Place, error) {
 	if m.searchPlacesFunc != nil {
 		return m.searchPlacesFunc(query)
 	}
@@ -80,13 +96,190 @@ func (m *mockDB) Ping() error {
 	return nil
 }
 
-func (m *mockDB) SaveAppInterest(email string, android bool, ios bool, country string) error {
+func (m *mockDB) SaveAppInterest(ctx context.Context, email string, android bool, ios bool, country string) error {
 	if m.saveAppInterestFunc != nil {
 		return m.saveAppInterestFunc(email, android, ios, country)
 	}
 	return nil
 }
 
+func (m *mockDB) FindPlace(ctx context.Context, state, slug string) (*db.Place, error) {
+	if m.findPlaceFunc != nil {
+		return m.findPlaceFunc(state, slug)
+	}
+	return nil, nil
+}
+
+func (m *mockDB) CreateSavedLocations(ctx context.Context, locations []db.SavedLocation) (string, error) {
+	if m.saved == nil {
+		m.saved = map[string][]db.SavedLocation{}
+	}
+	token := fmt.Sprintf("token%d", len(m.saved)+1)
+	m.saved[token] = locations
+	return token, nil
+}
+
+func (m *mockDB) GetSavedLocations(ctx context.Context, token string) ([]db.SavedLocation, error) {
+	return m.saved[token], nil
+}
+
+func (m *mockDB) UpdateSavedLocations(ctx context.Context, token string, locations []db.SavedLocation) (bool, error) {
+	if _, ok := m.saved[token]; !ok {
+		return false, nil
+	}
+	m.saved[token] = locations
+	return true, nil
+}
+
+func (m *mockDB) SavePushSubscription(ctx context.Context, sub db.PushSubscription) error {
+	if m.push == nil {
+		m.push = map[string]db.PushSubscription{}
+	}
+	m.push[sub.Endpoint] = sub
+	return nil
+}
+
+func (m *mockDB) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	delete(m.push, endpoint)
+	return nil
+}
+
+func (m *mockDB) CreateWebhook(ctx context.Context, wh *db.Webhook) error {
+	wh.ID = int64(len(m.webhooks) + 1)
+	if wh.Secret == "" {
+		wh.Secret = "generated"
+	}
+	m.webhooks = append(m.webhooks, *wh)
+	return nil
+}
+
+func (m *mockDB) ListWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	return m.webhooks, nil
+}
+
+func (m *mockDB) DeleteWebhook(ctx context.Context, id int64) (bool, error) {
+	for i, wh := range m.webhooks {
+		if wh.ID == id {
+			m.webhooks = append(m.webhooks[:i], m.webhooks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDB) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]db.WebhookDelivery, error) {
+	return []db.WebhookDelivery{{WebhookID: webhookID, AlertID: "urn:oid:1", Attempt: 1, StatusCode: 200}}, nil
+}
+
+func (m *mockDB) AlertHistory(ctx context.Context, scope string, since time.Time, limit int) ([]db.AlertVersion, error) {
+	if m.alertHistoryFunc != nil {
+		return m.alertHistoryFunc(scope, since, limit)
+	}
+	return []db.AlertVersion{}, nil
+}
+
+func (m *mockDB) SaveDigestSubscription(ctx context.Context, sub *db.DigestSubscription) error {
+	for _, d := range m.digests {
+		if d.Email == sub.Email && d.Latitude == sub.Latitude && d.Longitude == sub.Longitude && !d.Confirmed {
+			return db.ErrConfirmationPending
+		}
+	}
+	sub.ID = int64(len(m.digests) + 1)
+	sub.ConfirmToken = fmt.Sprintf("confirm%d", sub.ID)
+	sub.UnsubscribeToken = fmt.Sprintf("unsubscribe%d", sub.ID)
+	m.digests = append(m.digests, *sub)
+	return nil
+}
+
+func (m *mockDB) ConfirmDigestSubscription(ctx context.Context, token string) (*db.DigestSubscription, error) {
+	for i := range m.digests {
+		if m.digests[i].ConfirmToken == token {
+			m.digests[i].Confirmed = true
+			sub := m.digests[i]
+			return &sub, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockDB) DeleteDigestSubscription(ctx context.Context, token string) (bool, error) {
+	for i, sub := range m.digests {
+		if sub.UnsubscribeToken == token {
+			m.digests = append(m.digests[:i], m.digests[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDB) GetStation(ctx context.Context, id string) (*db.Station, error) {
+	for _, st := range m.stations {
+		if st.ID == id {
+			return &st, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockDB) ListStations(ctx context.Context) ([]db.Station, error) {
+	return m.stations, nil
+}
+
+func (m *mockDB) ObservationDays(ctx context.Context, station, since string) ([]db.ObservationDay, error) {
+	return m.observationDays[station], nil
+}
+
+func (m *mockDB) GetForecastSite(ctx context.Context, id string) (*db.ForecastSite, error) {
+	for _, site := range m.forecastSites {
+		if site.ID == id {
+			return &site, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockDB) ListForecastSites(ctx context.Context) ([]db.ForecastSite, error) {
+	return m.forecastSites, nil
+}
+
+func (m *mockDB) IssuedForecasts(ctx context.Context, site, since string) ([]db.IssuedForecast, error) {
+	return m.issuedForecasts[site], nil
+}
+
+func (m *mockDB) ObservationHours(ctx context.Context, station, since string) ([]db.ObservationHour, error) {
+	return m.observationHours[station], nil
+}
+
+// mockWeather is a mock implementation of the weather service for testing
+type mockWeather struct {
+	getWeatherFunc func(lat, lon float64) (*weather.WeatherData, error)
+	geocodeFunc    func(query string) (float64, float64, error)
+}
+
+func (m *mockWeather) GetWeatherContext(ctx context.Context, lat, lon float64) (*weather.WeatherData, error) {
+	if m.getWeatherFunc != nil {
+		return m.getWeatherFunc(lat, lon)
+	}
+	return &weather.WeatherData{}, nil
+}
+
+func (m *mockWeather) GeocodeContext(ctx context.Context, query string) (float64, float64, error) {
+	if m.geocodeFunc != nil {
+		return m.geocodeFunc(query)
+	}
+	return 0, 0, errors.New("location not found")
+}
+
+// loadTemplates parses the real templates so rendering can be tested
+func loadTemplates(t *testing.T) *template.Template {
+	t.Helper()
+	tmpl, err := template.ParseGlob("../../templates/*.html")
+	if err != nil {
+		t.Fatalf("failed to parse templates: %v", err)
+	}
+	return tmpl
+}
+
 func TestHandleSearch_QueryTooShort(t *testing.T) {
 	mock := &mockDB{}
 	h := &Handlers{db: mock}
@@ -390,3 +583,118 @@ func TestHandleAppInterest_EmptyEmail(t *testing.T) {
 		t.Errorf("expected status BadRequest for empty email, got %v", resp.StatusCode)
 	}
 }
+
+func TestUnitsFromRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		cookie    string
+		expected  weather.Units
+		expectErr bool
+	}{
+		{"default", "", "", weather.UnitsUS, false},
+		{"query", "?units=metric", "", weather.UnitsMetric, false},
+		{"cookie", "", "si", weather.UnitsSI, false},
+		{"query overrides cookie", "?units=us", "metric", weather.UnitsUS, false},
+		{"invalid cookie ignored", "", "kelvin", weather.UnitsUS, false},
+		{"invalid query", "?units=kelvin", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/weather"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "units", Value: tt.cookie})
+			}
+
+			u, err := unitsFromRequest(req)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("unitsFromRequest error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if u != tt.expected {
+				t.Errorf("expected units %q, got %q", tt.expected, u)
+			}
+		})
+	}
+}
+
+func changedWeather(lat, lon float64) (*weather.WeatherData, error) {
+	return &weather.WeatherData{
+		Latitude: lat, Longitude: lon,
+		Current: weather.CurrentCondition{Temperature: 70, TemperatureUnit: "F"},
+		Changes: []weather.ForecastChange{
+			{Kind: weather.ChangePrecip, Day: "Tuesday", From: 20, To: 70, Unit: "%", Summary: "Tuesday: precipitation chance up from 20% to 70%"},
+			{Kind: weather.ChangeHigh, Day: "Tuesday", From: 80, To: 72, Unit: "F", Summary: "Tuesday: high down from 80°F to 72°F"},
+		},
+		ChangesSince: time.Date(2025, 5, 6, 9, 4, 0, 0, time.UTC),
+		TimeZone:     "America/Chicago",
+		Units:        weather.UnitsUS,
+	}, nil
+}
+
+func TestHandleWeatherJSON(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{getWeatherFunc: changedWeather}}
+
+	w := httptest.NewRecorder()
+	h.HandleWeatherJSON(w, httptest.NewRequest("GET", "/api/v1/weather?lat=35.47&lon=-97.52&units=metric", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+
+	var wd weather.WeatherData
+	if err := json.Unmarshal(w.Body.Bytes(), &wd); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	if wd.Latitude != 35.47 || wd.Current.TemperatureUnit != "C" || len(wd.Changes) != 2 {
+		t.Fatalf("unexpected weather %+v", wd)
+	}
+	if wd.Changes[1].Summary != "Tuesday: high down from 27°C to 22°C" {
+		t.Errorf("expected the change in metric units, got %q", wd.Changes[1].Summary)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleWeatherJSON(w, httptest.NewRequest("GET", "/api/v1/weather", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status BadRequest without a location, got %v", w.Code)
+	}
+}
+
+func TestHandleWeatherAPI_Changes(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{getWeatherFunc: changedWeather}, templates: loadTemplates(t)}
+
+	w := httptest.NewRecorder()
+	h.HandleWeatherAPI(w, httptest.NewRequest("GET", "/api/weather?lat=35.47&lon=-97.52", nil))
+	body := w.Body.String()
+	for _, want := range []string{"Forecast changes", "Tuesday: precipitation chance up from 20% to 70%", "Since 4:04 AM CDT"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected fragment to contain %q", want)
+		}
+	}
+}
+
+func TestHandleWeatherAPI_Charts(t *testing.T) {
+	start := time.Date(2025, 5, 6, 0, 0, 0, 0, time.UTC)
+	wd := sampleWeather()
+	for i := range 48 {
+		wd.HourlySeries = append(wd.HourlySeries, weather.HourlyPoint{
+			Time: start.Add(time.Duration(i) * time.Hour), Temperature: 60 + i%10, FeelsLike: 60 + i%10,
+			TemperatureUnit: "F", PrecipChance: 40,
+		})
+	}
+	h := &Handlers{weather: &mockWeather{getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) { return wd, nil }}, templates: loadTemplates(t)}
+
+	w := httptest.NewRecorder()
+	h.HandleWeatherAPI(w, httptest.NewRequest("GET", "/api/weather?lat=35.47&lon=-97.52&units=metric", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`aria-labelledby="chart-temperature-title" aria-describedby="chart-temperature-summary"`,
+		`<figcaption id="chart-temperature-summary">Temperature over the next 48 hours ranges from 16°C (Tue 12 AM) to 21°C (Tue 9 AM).</figcaption>`,
+		`<polyline class="chart-temperature" points="40,112.2 `,
+		`<title>Tue 12 AM: 40%</title>`,
+		`<td>21°C</td>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected fragment to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// HandleHomeAssistant returns the weather for lat and lon (or location)
+// shaped for a Home Assistant REST sensor and template weather entity, in
+// units if given.
+func (h *Handlers) HandleHomeAssistant(w http.ResponseWriter, r *http.Request) {
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lat, lon, status, msg := h.locationFromQuery(r.Context(), r.URL.Query())
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, wd.WithUnits(units).HomeAssistant())
+}
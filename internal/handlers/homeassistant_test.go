@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func TestHandleHomeAssistant(t *testing.T) {
+	wd := sampleWeather()
+	wd.Current.Icon = "sunny"
+	wd.Forecast = []weather.DailyForecast{{Name: "Tuesday", Date: "2025-05-06", HighTemp: 75, LowTemp: 50, TemperatureUnit: "F", Icon: "rainy"}}
+	h := &Handlers{weather: &mockWeather{
+		getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+			if lat != 35.47 || lon != -97.52 {
+				return nil, errors.New("unexpected location")
+			}
+			return wd, nil
+		},
+	}}
+
+	w := httptest.NewRecorder()
+	h.HandleHomeAssistant(w, httptest.NewRequest("GET", "/api/v1/homeassistant?lat=35.47&lon=-97.52&units=metric", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	var ha struct {
+		Condition       string `json:"condition"`
+		Temperature     int    `json:"temperature"`
+		TemperatureUnit string `json:"temperature_unit"`
+		Forecast        []struct {
+			Datetime    string `json:"datetime"`
+			Condition   string `json:"condition"`
+			Temperature int    `json:"temperature"`
+			TempLow     int    `json:"templow"`
+		} `json:"forecast"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &ha); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	if ha.Condition != "sunny" || ha.Temperature != 20 || ha.TemperatureUnit != "°C" {
+		t.Errorf("unexpected current conditions %+v", ha)
+	}
+	if len(ha.Forecast) != 1 || ha.Forecast[0].Condition != "rainy" || ha.Forecast[0].Temperature != 24 || ha.Forecast[0].TempLow != 10 || ha.Forecast[0].Datetime != "2025-05-06T00:00:00Z" {
+		t.Errorf("unexpected forecast %+v", ha.Forecast)
+	}
+
+	for _, target := range []string{"/api/v1/homeassistant", "/api/v1/homeassistant?lat=1&lon=1&units=k"} {
+		w := httptest.NewRecorder()
+		h.HandleHomeAssistant(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status BadRequest, got %v", target, w.Code)
+		}
+	}
+}
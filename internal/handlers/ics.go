@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// calendarRefresh is how often subscribed calendars are asked to refetch.
+const calendarRefresh = time.Hour
+
+// HandleCoordsCalendar serves the iCalendar feed for /w/{lat},{lon}/calendar.ics.
+func (h *Handlers) HandleCoordsCalendar(w http.ResponseWriter, r *http.Request) {
+	lat, lon, ok := parseCoords(r.PathValue("coords"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	path := coordsPath(lat, lon)
+	if r.URL.Path != path+"/calendar.ics" {
+		redirectCanonical(w, r, path+"/calendar.ics")
+		return
+	}
+	h.renderCalendar(w, r, lat, lon, "", path)
+}
+
+// HandlePlaceCalendar serves the iCalendar feed for
+// /w/{state}/{place}/calendar.ics.
+func (h *Handlers) HandlePlaceCalendar(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	place, err := h.db.FindPlace(r.Context(), r.PathValue("state"), strings.ToLower(r.PathValue("place")))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Place lookup error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if place == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := placePath(place)
+	if r.URL.Path != path+"/calendar.ics" {
+		redirectCanonical(w, r, path+"/calendar.ics")
+		return
+	}
+	h.renderCalendar(w, r, place.Latitude, place.Longitude, placeName(*place), path)
+}
+
+// renderCalendar writes the daily forecast as all-day events and active
+// alerts as timed events from onset to expiry. UIDs depend only on the
+// location and date, or on the alert's series, so a refreshed calendar
+// replaces events instead of adding new ones. The calendar is cached
+// publicly, so only the URL's units query picks the units, never the cookie.
+func (h *Handlers) renderCalendar(w http.ResponseWriter, r *http.Request, lat, lon float64, name, path string) {
+	units, err := weather.ParseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+	wd = wd.WithUnits(units)
+
+	if name == "" {
+		name = wd.Location
+	}
+	if name == "" {
+		name = fmt.Sprintf("%.2f, %.2f", lat, lon)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(calendarRefresh.Seconds())))
+	w.Write([]byte(calendarICS(wd, name, h.baseURL+path, fmt.Sprintf("%.2f,%.2f", lat, lon))))
+}
+
+// calendarICS builds the VCALENDAR. key identifies the location in UIDs.
+func calendarICS(wd *weather.WeatherData, name, page, key string) string {
+	stamp := wd.CachedAt.UTC()
+	if stamp.IsZero() {
+		stamp = time.Now().UTC()
+	}
+	dtstamp := stamp.Format("20060102T150405Z")
+
+	var b icsBuilder
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//wthr.lol//Weather//EN")
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+	b.line("X-WR-CALNAME:" + icsEscape("Weather for "+name))
+	if wd.TimeZone != "" {
+		b.line("X-WR-TIMEZONE:" + wd.TimeZone)
+	}
+	b.line(fmt.Sprintf("REFRESH-INTERVAL;VALUE=DURATION:PT%dH", int(calendarRefresh.Hours())))
+	b.line(fmt.Sprintf("X-PUBLISHED-TTL:PT%dH", int(calendarRefresh.Hours())))
+
+	for _, day := range wd.Forecast {
+		date, err := time.Parse("2006-01-02", day.Date)
+		if err != nil {
+			continue
+		}
+		b.line("BEGIN:VEVENT")
+		b.line(fmt.Sprintf("UID:forecast-%s-%s@wthr.lol", date.Format("20060102"), key))
+		b.line("DTSTAMP:" + dtstamp)
+		b.line("DTSTART;VALUE=DATE:" + date.Format("20060102"))
+		b.line("DTEND;VALUE=DATE:" + date.AddDate(0, 0, 1).Format("20060102"))
+		b.line("SUMMARY:" + icsEscape(fmt.Sprintf("%d°/%d°%s %s", day.HighTemp, day.LowTemp, day.TemperatureUnit, day.ShortForecast)))
+		b.line("DESCRIPTION:" + icsEscape(fmt.Sprintf("%s: %s. High %d°%s, low %d°%s. %d%% chance of precipitation.",
+			day.Name, day.ShortForecast, day.HighTemp, day.TemperatureUnit, day.LowTemp, day.TemperatureUnit, day.PrecipChance)))
+		b.line("URL:" + page)
+		b.line("TRANSP:TRANSPARENT")
+		b.line("END:VEVENT")
+	}
+
+	superseded := make(map[string]bool)
+	for _, a := range wd.Alerts {
+		for _, ref := range a.References {
+			superseded[ref] = true
+		}
+	}
+	for _, a := range wd.Alerts {
+		if superseded[a.ID] {
+			continue
+		}
+		start := a.Onset
+		if start.IsZero() {
+			start = a.Effective
+		}
+		end := a.Expires
+		if a.Ends.After(end) {
+			end = a.Ends
+		}
+		if start.IsZero() || !end.After(start) {
+			continue
+		}
+
+		b.line("BEGIN:VEVENT")
+		b.line("UID:" + icsEscape(alertUID(a)) + "@wthr.lol")
+		b.line("DTSTAMP:" + dtstamp)
+		b.line(fmt.Sprintf("SEQUENCE:%d", alertSequence(a)))
+		b.line("DTSTART:" + start.UTC().Format("20060102T150405Z"))
+		b.line("DTEND:" + end.UTC().Format("20060102T150405Z"))
+		b.line("SUMMARY:" + icsEscape("⚠️ "+a.Event))
+		b.line("DESCRIPTION:" + icsEscape(alertText(a)))
+		b.line("CATEGORIES:" + icsEscape(a.Severity))
+		b.line("URL:" + page)
+		b.line("END:VEVENT")
+	}
+
+	b.line("END:VCALENDAR")
+	return b.String()
+}
+
+// alertUID identifies an alert across updates by its series, falling back
+// to its own ID for alerts cached before series were recorded.
+func alertUID(a weather.Alert) string {
+	if a.Series != "" {
+		return a.Series
+	}
+	if a.ID != "" {
+		return a.ID
+	}
+	return fmt.Sprintf("%s-%d", strings.ReplaceAll(a.Event, " ", "-"), a.Sent.Unix())
+}
+
+// alertSequence orders the versions of an alert: later versions are sent
+// later, so minutes since the epoch only ever go up along a chain, and fit
+// the 32-bit integer RFC 5545 allows.
+func alertSequence(a weather.Alert) int64 {
+	if a.Sent.IsZero() {
+		return 0
+	}
+	return a.Sent.Unix() / 60
+}
+
+// icsBuilder writes content lines with CRLF endings, folded at 75 octets
+// as RFC 5545 requires.
+type icsBuilder struct {
+	strings.Builder
+}
+
+func (b *icsBuilder) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		// Don't split a multi-byte UTF-8 sequence.
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts to the limit.
+		limit = 74
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsEscape escapes a TEXT property value.
+func icsEscape(s string) string {
+	return icsEscaper.Replace(s)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func calendarHandlers() *Handlers {
+	onset := time.Date(2025, 5, 6, 20, 0, 0, 0, time.UTC)
+	return &Handlers{
+		baseURL: "https://wthr.example",
+		db: &mockDB{
+			findPlaceFunc: func(state, slug string) (*db.Place, error) {
+				if state == "OK" && slug == "oklahoma-city" {
+					return &db.Place{Name: "Oklahoma City", State: "OK", Latitude: 35.4676, Longitude: -97.5164}, nil
+				}
+				return nil, nil
+			},
+		},
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				wd := sampleWeather()
+				wd.TimeZone = "America/Chicago"
+				wd.CachedAt = onset
+				wd.Forecast = []weather.DailyForecast{
+					{Name: "Tuesday", Date: "2025-05-06", HighTemp: 84, LowTemp: 62, TemperatureUnit: "F", ShortForecast: "Severe Thunderstorms", PrecipChance: 80},
+					{Name: "Wednesday", Date: "2025-05-07", HighTemp: 77, LowTemp: 55, TemperatureUnit: "F", ShortForecast: "Sunny"},
+				}
+				wd.Alerts = []weather.Alert{
+					{
+						ID: "urn:oid:1", Event: "Tornado Watch", Severity: "Severe", Series: "KWNS.TO.A.0212.2025",
+						Sent: onset.Add(-time.Hour), Onset: onset, Expires: onset.Add(4 * time.Hour),
+					},
+					{
+						ID: "urn:oid:2", Event: "Tornado Watch", Severity: "Severe", Series: "KWNS.TO.A.0212.2025", References: []string{"urn:oid:1"},
+						Sent: onset, Onset: onset, Expires: onset.Add(6 * time.Hour),
+						Description: "A long description, with commas; semicolons and\nnewlines that needs escaping and folding onto several lines.",
+					},
+				}
+				return wd, nil
+			},
+		},
+	}
+}
+
+func TestHandlePlaceCalendar(t *testing.T) {
+	mux := newPermalinkMux(calendarHandlers())
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/w/OK/oklahoma-city/calendar.ics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	ics := w.Body.String()
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+	// Unfold for content checks.
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Weather for Oklahoma City\\, OK\r\n",
+		"X-WR-TIMEZONE:America/Chicago\r\n",
+		"UID:forecast-20250506-35.47,-97.52@wthr.lol\r\n",
+		"DTSTART;VALUE=DATE:20250506\r\nDTEND;VALUE=DATE:20250507\r\n",
+		"SUMMARY:84°/62°F Severe Thunderstorms\r\n",
+		// The update replaces the original alert under the series' UID, with
+		// a later sequence.
+		"UID:KWNS.TO.A.0212.2025@wthr.lol\r\nDTSTAMP:20250506T200000Z\r\nSEQUENCE:29109360\r\n",
+		"DTSTART:20250506T200000Z\r\nDTEND:20250507T020000Z\r\n",
+		`with commas\; semicolons and\nnewlines`,
+		"URL:https://wthr.example/w/OK/oklahoma-city\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("expected calendar to contain %q", want)
+		}
+	}
+	if n := strings.Count(unfolded, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("expected 2 forecast events and 1 alert event, got %d", n)
+	}
+}
+
+func TestHandleCoordsCalendar(t *testing.T) {
+	mux := newPermalinkMux(calendarHandlers())
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/w/35.4676,-97.5164/calendar.ics?units=metric", nil))
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected redirect, got %v", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/w/35.47,-97.52/calendar.ics?units=metric" {
+		t.Errorf("unexpected Location %q", loc)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/w/35.47,-97.52/calendar.ics?units=metric", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "SUMMARY:29°/17°C Severe Thunderstorms") {
+		t.Errorf("expected metric temperatures, got %s", w.Body.String())
+	}
+
+	// A subscriber's units cookie mustn't end up in the shared cache.
+	req := httptest.NewRequest("GET", "/w/35.47,-97.52/calendar.ics", nil)
+	req.AddCookie(&http.Cookie{Name: "units", Value: "metric"})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "°C") {
+		t.Errorf("expected the calendar in the URL's units, got %v: %s", w.Code, w.Body.String())
+	}
+}
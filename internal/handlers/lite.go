@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// LitePageData is the data passed to the lite.html template.
+type LitePageData struct {
+	Query   string
+	Units   weather.Units
+	Choices []LiteChoice
+	Weather *weather.WeatherData
+	Error   string
+	// Notice is a status message, e.g. from the daily email pages.
+	Notice string
+	// Digest shows the daily email signup form under the weather.
+	Digest bool
+	// UnsubscribeToken shows the unsubscribe button for a daily email.
+	UnsubscribeToken string
+}
+
+// LiteChoice is one entry in the lite mode disambiguation list.
+type LiteChoice struct {
+	Name string
+	URL  string
+}
+
+// HandleLite serves the no-JavaScript version of the site. The search form
+// submits via GET; the query is resolved against the places table (falling
+// back to geocoding), a list is shown when several places match, and weather
+// is rendered entirely server-side.
+func (h *Handlers) HandleLite(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page := &LitePageData{Query: strings.TrimSpace(q.Get("q")), Digest: h.mailer != nil && h.db != nil}
+
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		page.Error = err.Error()
+		h.renderLite(w, r, http.StatusBadRequest, page)
+		return
+	}
+	page.Units = units
+
+	status := http.StatusOK
+	switch {
+	case q.Get("lat") != "" && q.Get("lon") != "":
+		lat, lon, ok := parseCoords(q.Get("lat") + "," + q.Get("lon"))
+		if !ok {
+			status = http.StatusBadRequest
+			page.Error = "Invalid coordinates"
+			break
+		}
+		status = h.liteWeather(r, page, lat, lon, q.Get("name"))
+
+	case page.Query != "":
+		status = h.liteSearch(r, page)
+	}
+
+	h.renderLite(w, r, status, page)
+}
+
+// liteSearch resolves page.Query to a single place, a list of choices, or an
+// error, and returns the status to render the page with.
+func (h *Handlers) liteSearch(r *http.Request, page *LitePageData) int {
+	var places []db.Place
+	if h.db != nil {
+		var err error
+		places, err = h.db.SearchPlaces(r.Context(), page.Query)
+		if err != nil {
+			// Fall through to geocoding rather than failing the page.
+			slog.ErrorContext(r.Context(), "Search error", "error", err)
+		}
+	}
+
+	switch len(places) {
+	case 0:
+		lat, lon, err := h.weather.GeocodeContext(r.Context(), page.Query)
+		if err != nil {
+			page.Error = fmt.Sprintf("Location not found: %s", page.Query)
+			return http.StatusNotFound
+		}
+		return h.liteWeather(r, page, lat, lon, "")
+	case 1:
+		return h.liteWeather(r, page, places[0].Latitude, places[0].Longitude, placeName(places[0]))
+	default:
+		for _, p := range places {
+			name := placeName(p)
+			v := url.Values{}
+			v.Set("lat", fmt.Sprintf("%.4f", p.Latitude))
+			v.Set("lon", fmt.Sprintf("%.4f", p.Longitude))
+			v.Set("name", name)
+			v.Set("units", string(page.Units))
+			page.Choices = append(page.Choices, LiteChoice{Name: name, URL: "/lite?" + v.Encode()})
+		}
+	}
+	return http.StatusOK
+}
+
+// liteWeather fills in the weather for a point, and returns the status to
+// render the page with.
+func (h *Handlers) liteWeather(r *http.Request, page *LitePageData, lat, lon float64, name string) int {
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		page.Error = "Failed to retrieve weather data"
+		return http.StatusBadGateway
+	}
+	wd = wd.WithUnits(page.Units)
+	if name != "" {
+		wd.Location = name
+	}
+	page.Weather = wd
+	return http.StatusOK
+}
+
+// renderLite writes lite.html with status, which is only sent once the
+// headers are set.
+func (h *Handlers) renderLite(w http.ResponseWriter, r *http.Request, status int, page *LitePageData) {
+	if h.templates == nil {
+		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.render(r.Context(), w, "lite.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Template error", "error", err)
+	}
+}
+
+// placeName formats a place for display, e.g. "Austin, TX" or "94102".
+func placeName(p db.Place) string {
+	if p.State == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s, %s", p.Name, p.State)
+}
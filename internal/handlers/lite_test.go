@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func serveLite(t *testing.T, h *Handlers, target string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", target, nil)
+	w := httptest.NewRecorder()
+	h.HandleLite(w, req)
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestHandleLite_EmptyForm(t *testing.T) {
+	h := &Handlers{db: &mockDB{}, weather: &mockWeather{}, templates: loadTemplates(t)}
+
+	resp, body := serveLite(t, h, "/lite")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if !strings.Contains(body, `<form method="get" action="/lite">`) {
+		t.Errorf("expected search form in page")
+	}
+	if strings.Contains(body, "<script") {
+		t.Errorf("lite page must not include scripts")
+	}
+}
+
+func TestHandleLite_SingleMatch(t *testing.T) {
+	var gotLat float64
+	h := &Handlers{
+		db: &mockDB{
+			searchPlacesFunc: func(query string) ([]db.Place, error) {
+				return []db.Place{{Name: "Austin", State: "TX", Latitude: 30.27, Longitude: -97.74}}, nil
+			},
+		},
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				gotLat = lat
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+	}
+
+	resp, body := serveLite(t, h, "/lite?q=Austin&units=metric")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if gotLat != 30.27 {
+		t.Errorf("expected weather for matched place, got lat %f", gotLat)
+	}
+	for _, want := range []string{"<h2>Austin, TX</h2>", "20°C", `<option value="metric" selected>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+}
+
+func TestHandleLite_MultipleMatches(t *testing.T) {
+	h := &Handlers{
+		db: &mockDB{
+			searchPlacesFunc: func(query string) ([]db.Place, error) {
+				return []db.Place{
+					{Name: "Springfield", State: "IL", Latitude: 39.7817, Longitude: -89.6501},
+					{Name: "Springfield", State: "MO", Latitude: 37.2090, Longitude: -93.2923},
+				}, nil
+			},
+		},
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				t.Errorf("weather should not be fetched when disambiguating")
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+	}
+
+	resp, body := serveLite(t, h, "/lite?q=Springfield")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	for _, want := range []string{
+		"Which one?",
+		`<a href="/lite?lat=39.7817&amp;lon=-89.6501&amp;name=Springfield%2C&#43;IL&amp;units=us">Springfield, IL</a>`,
+		"Springfield, MO",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+}
+
+func TestHandleLite_GeocodeFallback(t *testing.T) {
+	geocoded := false
+	h := &Handlers{
+		db: &mockDB{},
+		weather: &mockWeather{
+			geocodeFunc: func(query string) (float64, float64, error) {
+				geocoded = true
+				return 40.0, -105.0, nil
+			},
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+	}
+
+	resp, body := serveLite(t, h, "/lite?q=Boulder+Colorado")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if !geocoded {
+		t.Errorf("expected geocode fallback when no places match")
+	}
+	if !strings.Contains(body, "<h2>Somewhere, California</h2>") {
+		t.Errorf("expected reverse-geocoded location name in page")
+	}
+}
+
+func TestHandleLite_NotFound(t *testing.T) {
+	h := &Handlers{db: &mockDB{}, weather: &mockWeather{}, templates: loadTemplates(t)}
+
+	resp, body := serveLite(t, h, "/lite?q=Atlantis")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status NotFound, got %v", resp.StatusCode)
+	}
+	if !strings.Contains(body, "Location not found: Atlantis") {
+		t.Errorf("expected not found message in page")
+	}
+}
+
+func TestHandleLite_Coordinates(t *testing.T) {
+	h := &Handlers{
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+	}
+
+	resp, body := serveLite(t, h, "/lite?lat=39.7817&lon=-89.6501&name=Springfield%2C+IL")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if !strings.Contains(body, "<h2>Springfield, IL</h2>") {
+		t.Errorf("expected chosen place name in page")
+	}
+}
+
+func TestHandleLite_Errors(t *testing.T) {
+	h := &Handlers{
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				return nil, errors.New("NWS API error")
+			},
+		},
+		templates: loadTemplates(t),
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"invalid coordinates", "/lite?lat=abc&lon=1", http.StatusBadRequest},
+		{"invalid units", "/lite?units=kelvin", http.StatusBadRequest},
+		{"weather failure", "/lite?lat=10&lon=10", http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := serveLite(t, h, tt.target)
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if !strings.Contains(body, `class="error"`) {
+				t.Errorf("expected error message in page")
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+				t.Errorf("expected the error page to be HTML, got %q", ct)
+			}
+		})
+	}
+
+	// Without templates the error is a plain 500, not a 400 with an error
+	// body.
+	h.templates = nil
+	if resp, _ := serveLite(t, h, "/lite?lat=abc&lon=1"); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status InternalServerError without templates, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/logging"
+)
+
+// maxRequestIDLength bounds the X-Request-ID values taken from clients.
+const maxRequestIDLength = 128
+
+// LogRequests gives every request an ID, taken from its X-Request-ID header
+// when that looks sane, and logs each one served with its method, path,
+// route pattern, status, latency and weather cache outcome. The ID is
+// echoed in the response's X-Request-ID header and carried in the
+// request's context, so everything logged while serving it is tagged.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := logging.WithRequestID(r.Context(), id)
+		r2 := r.WithContext(ctx)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r2)
+		// Pass the matched route back out, as the mux does, for Trace.
+		r.Pattern = r2.Pattern
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"handler", r.Pattern,
+			"status", status,
+			"latency_ms", logging.Millis(time.Since(start)),
+		}
+		if cache := logging.CacheOutcome(ctx); cache != "" {
+			attrs = append(attrs, "cache", cache)
+		}
+		slog.InfoContext(ctx, "Request", attrs...)
+	})
+}
+
+// validRequestID reports whether id is a usable client supplied request ID:
+// non-empty, not too long, and printable ASCII so it can't forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/logging"
+)
+
+func TestLogRequests(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewJSONHandler(&buf, nil))))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.NoteCache(r.Context(), "stale")
+		slog.WarnContext(r.Context(), "Inside")
+		http.Error(w, "nope", http.StatusTeapot)
+	})
+	h := LogRequests(mux)
+
+	req := httptest.NewRequest("GET", "/test/1", nil)
+	req.Header.Set("X-Request-ID", "client-id-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "client-id-1" {
+		t.Errorf("expected the client's request ID echoed, got %q", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %q", buf.String())
+	}
+	var inside, rec map[string]any
+	json.Unmarshal([]byte(lines[0]), &inside)
+	json.Unmarshal([]byte(lines[1]), &rec)
+	if inside["request_id"] != "client-id-1" {
+		t.Errorf("expected the handler's log tagged with the request ID, got %v", inside)
+	}
+	for key, want := range map[string]any{
+		"msg":        "Request",
+		"request_id": "client-id-1",
+		"method":     "GET",
+		"path":       "/test/1",
+		"handler":    "GET /test/{id}",
+		"status":     float64(http.StatusTeapot),
+		"cache":      "stale",
+	} {
+		if rec[key] != want {
+			t.Errorf("%s = %v, want %v", key, rec[key], want)
+		}
+	}
+	if _, ok := rec["latency_ms"].(float64); !ok {
+		t.Errorf("expected latency_ms, got %v", rec)
+	}
+
+	// An unusable ID is replaced.
+	req = httptest.NewRequest("GET", "/test/2", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); len(got) != 16 {
+		t.Errorf("expected a generated request ID, got %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/metrics"
+)
+
+var (
+	httpRequests = metrics.NewCounter("wthr_http_requests_total",
+		"HTTP requests served, by route pattern, method and status code.", "handler", "method", "code")
+	httpDuration = metrics.NewHistogram("wthr_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route pattern. Streams count until they close.", nil, "handler")
+)
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g.
+// to flush the event stream.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// metricMethod returns the request method for the metrics' method label,
+// or "other" for anything nonstandard so clients can't add label values.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// Instrument counts and times the requests next serves. next should be the
+// ServeMux, which sets the route pattern the metrics are labelled with;
+// requests no route matched are labelled "unmatched".
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		pattern := r.Pattern
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequests.Inc(pattern, metricMethod(r.Method), strconv.Itoa(status))
+		httpDuration.Observe(time.Since(start).Seconds(), pattern)
+	})
+}
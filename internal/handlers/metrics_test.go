@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusTeapot)
+	})
+	mux.HandleFunc("GET /test/flush", func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("expected the recorder to pass Flush through: %v", err)
+		}
+	})
+	h := Instrument(mux)
+
+	for _, target := range []string{"/test/1", "/test/2", "/test/flush"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	// Made up methods share one label value.
+	for _, method := range []string{"BREW", "WHEN"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/test/1", nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`wthr_http_requests_total{handler="GET /test/{id}",method="GET",code="418"} 2`,
+		`wthr_http_requests_total{handler="GET /test/flush",method="GET",code="200"} 1`,
+		`wthr_http_request_duration_seconds_count{handler="GET /test/{id}"} 2`,
+		`wthr_http_requests_total{handler="unmatched",method="other",code="405"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics", want)
+		}
+	}
+	if strings.Contains(body, `method="BREW"`) {
+		t.Errorf("expected nonstandard methods labelled other")
+	}
+}
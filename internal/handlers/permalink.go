@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+)
+
+// HandlePlacePermalink renders the full page for /w/{state}/{place}, where
+// place is the PlaceSlug of a name in the places table.
+func (h *Handlers) HandlePlacePermalink(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state := r.PathValue("state")
+	slug := strings.ToLower(r.PathValue("place"))
+
+	place, err := h.db.FindPlace(r.Context(), state, slug)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Place lookup error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if place == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := placePath(place)
+	if r.URL.Path != path {
+		redirectCanonical(w, r, path)
+		return
+	}
+
+	h.renderPermalink(w, r, place.Latitude, place.Longitude, placeName(*place), path)
+}
+
+// HandleCoordsPermalink renders the full page for /w/{lat},{lon}.
+func (h *Handlers) HandleCoordsPermalink(w http.ResponseWriter, r *http.Request) {
+	lat, lon, ok := parseCoords(r.PathValue("coords"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Canonicalize to the same 2-decimal precision the weather cache uses so
+	// nearby links share one URL.
+	path := coordsPath(lat, lon)
+	if r.URL.Path != path {
+		redirectCanonical(w, r, path)
+		return
+	}
+
+	h.renderPermalink(w, r, lat, lon, "", path)
+}
+
+func (h *Handlers) renderPermalink(w http.ResponseWriter, r *http.Request, lat, lon float64, name, path string) {
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+	wd = wd.WithUnits(units)
+
+	if name == "" {
+		name = wd.Location
+	}
+	if name == "" {
+		name = fmt.Sprintf("%.2f, %.2f", lat, lon)
+	}
+	// Show the name that was linked to rather than the reverse-geocoded one.
+	wd.Location = name
+
+	page := &PageData{
+		Title: fmt.Sprintf("%s weather - wthr.lol", name),
+		Description: fmt.Sprintf("%d°%s and %s in %s. High %d°, low %d°.",
+			wd.Current.Temperature, wd.Current.TemperatureUnit, wd.Current.ShortForecast,
+			name, wd.Current.HighTemp, wd.Current.LowTemp),
+		CanonicalURL: h.baseURL + path,
+		FeedURL:      h.baseURL + feedPath(path),
+		ImageURL:     h.baseURL + cardPath(path),
+		Weather:      wd,
+		Lat:          lat,
+		Lon:          lon,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+		return
+	}
+
+	// The units preference cookie changes the rendered page.
+	w.Header().Set("Vary", "Cookie")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.render(r.Context(), w, "index.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Template error", "error", err)
+	}
+}
+
+// placePath returns the canonical permalink path for a place.
+func placePath(p *db.Place) string {
+	return fmt.Sprintf("/w/%s/%s", strings.ToUpper(p.State), db.PlaceSlug(p.Name))
+}
+
+// coordsPath returns the canonical permalink path for a coordinate pair.
+func coordsPath(lat, lon float64) string {
+	return fmt.Sprintf("/w/%.2f,%.2f", lat, lon)
+}
+
+// parseCoords parses "lat,lon" and validates the ranges.
+func parseCoords(s string) (float64, float64, bool) {
+	latStr, lonStr, found := strings.Cut(s, ",")
+	if !found {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+func redirectCanonical(w http.ResponseWriter, r *http.Request, path string) {
+	target := path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
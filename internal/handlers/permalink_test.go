@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// newPermalinkMux routes requests the same way cmd/wthr does so PathValue works
+func newPermalinkMux(h *Handlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /w/{state}/{place}", h.HandlePlacePermalink)
+	mux.HandleFunc("GET /w/{coords}", h.HandleCoordsPermalink)
+	mux.HandleFunc("GET /w/{coords}/calendar.ics", h.HandleCoordsCalendar)
+	mux.HandleFunc("GET /w/{state}/{place}/calendar.ics", h.HandlePlaceCalendar)
+	return mux
+}
+
+func sampleWeather() *weather.WeatherData {
+	return &weather.WeatherData{
+		Current: weather.CurrentCondition{
+			Temperature:     68,
+			TemperatureUnit: "F",
+			ShortForecast:   "Sunny",
+			HighTemp:        72,
+			LowTemp:         55,
+		},
+		Location: "Somewhere, California",
+		Units:    weather.UnitsUS,
+	}
+}
+
+func TestHandlePlacePermalink(t *testing.T) {
+	var gotLat, gotLon float64
+	h := &Handlers{
+		db: &mockDB{
+			findPlaceFunc: func(state, slug string) (*db.Place, error) {
+				if state == "CA" && slug == "san-francisco" {
+					return &db.Place{Name: "San Francisco", State: "CA", Latitude: 37.7749, Longitude: -122.4194}, nil
+				}
+				return nil, nil
+			},
+		},
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				gotLat, gotLon = lat, lon
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+		baseURL:   "https://wthr.example",
+	}
+
+	req := httptest.NewRequest("GET", "/w/CA/san-francisco", nil)
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if gotLat != 37.7749 || gotLon != -122.4194 {
+		t.Errorf("expected weather for place coordinates, got %f,%f", gotLat, gotLon)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	html := string(body)
+	for _, want := range []string{
+		"<title>San Francisco, CA weather - wthr.lol</title>",
+		`<link rel="canonical" href="https://wthr.example/w/CA/san-francisco" />`,
+		`<link rel="alternate" type="application/atom+xml" title="Weather alerts" href="https://wthr.example/feeds/alerts/CA/san-francisco" />`,
+		`<meta property="og:title" content="San Francisco, CA weather - wthr.lol" />`,
+		`<meta property="og:description" content="68°F and Sunny in San Francisco, CA. High 72°, low 55°." />`,
+		`<meta property="og:image" content="https://wthr.example/card/CA/san-francisco.png" />`,
+		`<h3 class="location-name">San Francisco, CA</h3>`,
+		"data-permalink",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+}
+
+func TestHandlePlacePermalink_RedirectsToCanonical(t *testing.T) {
+	h := &Handlers{
+		db: &mockDB{
+			findPlaceFunc: func(state, slug string) (*db.Place, error) {
+				return &db.Place{Name: "St. Louis", State: "MO"}, nil
+			},
+		},
+		weather: &mockWeather{},
+	}
+
+	req := httptest.NewRequest("GET", "/w/mo/St-Louis?units=metric", nil)
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("expected status MovedPermanently, got %v", resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/w/MO/st-louis?units=metric" {
+		t.Errorf("expected redirect to canonical path, got %q", loc)
+	}
+}
+
+func TestHandlePlacePermalink_NotFound(t *testing.T) {
+	h := &Handlers{db: &mockDB{}, weather: &mockWeather{}}
+
+	req := httptest.NewRequest("GET", "/w/CA/nowhere", nil)
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status NotFound, got %v", w.Result().StatusCode)
+	}
+}
+
+func TestHandlePlacePermalink_DatabaseError(t *testing.T) {
+	h := &Handlers{
+		db: &mockDB{
+			findPlaceFunc: func(state, slug string) (*db.Place, error) {
+				return nil, errors.New("database connection failed")
+			},
+		},
+		weather: &mockWeather{},
+	}
+
+	req := httptest.NewRequest("GET", "/w/CA/san-francisco", nil)
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status InternalServerError, got %v", w.Result().StatusCode)
+	}
+}
+
+func TestHandleCoordsPermalink(t *testing.T) {
+	h := &Handlers{
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+		baseURL:   "https://wthr.example",
+	}
+
+	req := httptest.NewRequest("GET", "/w/37.77,-122.42", nil)
+	req.AddCookie(&http.Cookie{Name: "units", Value: "metric"})
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	html := string(body)
+	for _, want := range []string{
+		"<title>Somewhere, California weather - wthr.lol</title>",
+		`<link rel="canonical" href="https://wthr.example/w/37.77,-122.42" />`,
+		"20°C",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+}
+
+func TestHandleCoordsPermalink_Redirect(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{}}
+
+	req := httptest.NewRequest("GET", "/w/37.7749,-122.4194", nil)
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("expected status MovedPermanently, got %v", resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/w/37.77,-122.42" {
+		t.Errorf("expected redirect to rounded coordinates, got %q", loc)
+	}
+}
+
+func TestHandleCoordsPermalink_Invalid(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{}}
+
+	for _, path := range []string{"/w/abc", "/w/91.00,0.00", "/w/10.00,181.00", "/w/10.00"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			newPermalinkMux(h).ServeHTTP(w, req)
+
+			if w.Result().StatusCode != http.StatusNotFound {
+				t.Errorf("expected status NotFound for %s, got %v", path, w.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandleCoordsPermalink_WeatherError(t *testing.T) {
+	h := &Handlers{
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				return nil, errors.New("NWS API error")
+			},
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/w/37.77,-122.42", nil)
+	w := httptest.NewRecorder()
+	newPermalinkMux(h).ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status BadGateway, got %v", w.Result().StatusCode)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/push"
+)
+
+// pushSubscribeRequest is the body of a subscribe request: the browser's
+// PushSubscription.toJSON() plus the location to watch.
+type pushSubscribeRequest struct {
+	Subscription struct {
+		Endpoint string `json:"endpoint"`
+		Keys     struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		} `json:"keys"`
+	} `json:"subscription"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// HandlePushKey returns the VAPID public key browsers need to subscribe.
+func (h *Handlers) HandlePushKey(w http.ResponseWriter, r *http.Request) {
+	if h.pushKey == "" || h.db == nil {
+		http.Error(w, "Push notifications unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"publicKey": h.pushKey})
+}
+
+// HandlePushSubscribe stores (POST) or removes (DELETE) a push subscription.
+// DELETE only needs the subscription endpoint.
+func (h *Handlers) HandlePushSubscribe(w http.ResponseWriter, r *http.Request) {
+	if h.pushKey == "" || h.db == nil {
+		http.Error(w, "Push notifications unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 4<<10)
+	var req pushSubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	sub := push.Subscription{
+		Endpoint: req.Subscription.Endpoint,
+		P256dh:   req.Subscription.Keys.P256dh,
+		Auth:     req.Subscription.Keys.Auth,
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if err := sub.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
+			http.Error(w, "invalid coordinates", http.StatusBadRequest)
+			return
+		}
+		err := h.db.SavePushSubscription(r.Context(), db.PushSubscription{
+			Endpoint:  sub.Endpoint,
+			P256dh:    sub.P256dh,
+			Auth:      sub.Auth,
+			Latitude:  req.Latitude,
+			Longitude: req.Longitude,
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save push subscription", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		if sub.Endpoint == "" {
+			http.Error(w, "endpoint is required", http.StatusBadRequest)
+			return
+		}
+		if err := h.db.DeletePushSubscription(r.Context(), sub.Endpoint); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to delete push subscription", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
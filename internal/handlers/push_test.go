@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/push"
+)
+
+func subscribeBody(t *testing.T, endpoint string, lat float64) string {
+	t.Helper()
+	// Any P-256 public key will do for the browser's p256dh key
+	p256dh, _, err := push.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef"))
+	return fmt.Sprintf(`{"subscription":{"endpoint":%q,"keys":{"p256dh":%q,"auth":%q}},"latitude":%f,"longitude":-97.52}`,
+		endpoint, p256dh, auth, lat)
+}
+
+func TestHandlePushKey(t *testing.T) {
+	h := &Handlers{db: &mockDB{}, pushKey: "BPublicKey"}
+
+	w := httptest.NewRecorder()
+	h.HandlePushKey(w, httptest.NewRequest("GET", "/api/push/key", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"publicKey":"BPublicKey"`) {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	h.pushKey = ""
+	w = httptest.NewRecorder()
+	h.HandlePushKey(w, httptest.NewRequest("GET", "/api/push/key", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable without keys, got %v", w.Code)
+	}
+}
+
+func TestHandlePushSubscribe(t *testing.T) {
+	mock := &mockDB{}
+	h := &Handlers{db: mock, pushKey: "BPublicKey"}
+
+	req := httptest.NewRequest("POST", "/api/push/subscribe", strings.NewReader(subscribeBody(t, "https://push.example/abc", 35.47)))
+	w := httptest.NewRecorder()
+	h.HandlePushSubscribe(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status Created, got %v: %s", w.Code, w.Body.String())
+	}
+	if sub, ok := mock.push["https://push.example/abc"]; !ok || sub.Latitude != 35.47 {
+		t.Errorf("expected subscription to be stored, got %+v", mock.push)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/push/subscribe", strings.NewReader(`{"subscription":{"endpoint":"https://push.example/abc"}}`))
+	w = httptest.NewRecorder()
+	h.HandlePushSubscribe(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status NoContent, got %v", w.Code)
+	}
+	if len(mock.push) != 0 {
+		t.Errorf("expected subscription to be removed, got %+v", mock.push)
+	}
+}
+
+func TestHandlePushSubscribe_Invalid(t *testing.T) {
+	h := &Handlers{db: &mockDB{}, pushKey: "BPublicKey"}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", "nope"},
+		{"http endpoint", subscribeBody(t, "http://push.example/abc", 35.47)},
+		{"bad latitude", subscribeBody(t, "https://push.example/abc", 95)},
+		{"missing keys", `{"subscription":{"endpoint":"https://push.example/abc"},"latitude":1,"longitude":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.HandlePushSubscribe(w, httptest.NewRequest("POST", "/api/push/subscribe", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status BadRequest, got %v", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLimiterKeys is how many keys a rateLimiter holds before it sweeps out
+// the ones with no recent events.
+const maxLimiterKeys = 10000
+
+// rateLimiter counts events per key over a sliding window. The zero value
+// is ready to use.
+type rateLimiter struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+// allow records an event for key and reports whether there have been no
+// more than limit of them in the last window. Refused events aren't
+// counted.
+func (l *rateLimiter) allow(key string, limit int, window time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.hits == nil {
+		l.hits = make(map[string][]time.Time)
+	}
+	if len(l.hits) >= maxLimiterKeys {
+		for k, times := range l.hits {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= window {
+				delete(l.hits, k)
+			}
+		}
+	}
+
+	times := l.hits[key]
+	for len(times) > 0 && now.Sub(times[0]) >= window {
+		times = times[1:]
+	}
+	if len(times) >= limit {
+		l.hits[key] = times
+		return false
+	}
+	l.hits[key] = append(times, now)
+	return true
+}
+
+// clientIP is the address the request came from, without its port. Behind
+// a reverse proxy every request comes from the proxy, so with clientIPHeader
+// set the address is taken from that header instead: the last entry of a
+// list such as X-Forwarded-For, which is the one the proxy added. Requests
+// without the header fall back to the connection's address.
+func (h *Handlers) clientIP(r *http.Request) string {
+	if h.clientIPHeader != "" {
+		if v := r.Header.Values(h.clientIPHeader); len(v) > 0 {
+			list := strings.Split(v[len(v)-1], ",")
+			if ip := strings.TrimSpace(list[len(list)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
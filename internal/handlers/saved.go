@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+const (
+	// maxSavedLocations caps how many places one sync token can hold.
+	maxSavedLocations = 20
+	// maxOverviewLocations caps how many places one overview request fetches.
+	maxOverviewLocations = 10
+	// overviewConcurrency limits parallel weather lookups per overview request
+	// so a cold cache doesn't burst the NWS API.
+	overviewConcurrency = 4
+)
+
+// OverviewCard is one place in the overview_fragment template.
+type OverviewCard struct {
+	Name    string
+	Path    string // Permalink to the full page for the place
+	Weather *weather.WeatherData
+	Error   string
+}
+
+// HandleCreateSavedLocations stores a saved locations list and returns a new
+// opaque sync token. Expects a POST with a JSON array of SavedLocation.
+func (h *Handlers) HandleCreateSavedLocations(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Sync unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	locations, err := decodeSavedLocations(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.db.CreateSavedLocations(r.Context(), locations)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create saved locations", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"token": token, "locations": locations})
+}
+
+// HandleSavedLocations reads (GET) or replaces (PUT) the list stored under
+// the {token} path value.
+func (h *Handlers) HandleSavedLocations(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Sync unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	token := r.PathValue("token")
+
+	switch r.Method {
+	case http.MethodGet:
+		locations, err := h.db.GetSavedLocations(r.Context(), token)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to get saved locations", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if locations == nil {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"token": token, "locations": locations})
+
+	case http.MethodPut:
+		locations, err := decodeSavedLocations(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		found, err := h.db.UpdateSavedLocations(r.Context(), token, locations)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to update saved locations", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"token": token, "locations": locations})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleOverview renders a compact card per place for repeated
+// loc=lat,lon[,name] query parameters, fetching them concurrently.
+func (h *Handlers) HandleOverview(w http.ResponseWriter, r *http.Request) {
+	if h.templates == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("<div class='error'>%s</div>", template.HTMLEscapeString(err.Error()))))
+		return
+	}
+
+	locs := r.URL.Query()["loc"]
+	if len(locs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("<div class='error'>Please provide at least one location</div>"))
+		return
+	}
+	if len(locs) > maxOverviewLocations {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("<div class='error'>At most %d locations are allowed</div>", maxOverviewLocations)))
+		return
+	}
+
+	cards := make([]OverviewCard, len(locs))
+	sem := make(chan struct{}, overviewConcurrency)
+	var wg sync.WaitGroup
+	for i, loc := range locs {
+		parts := strings.SplitN(loc, ",", 3)
+		if len(parts) < 2 {
+			cards[i].Error = "Invalid location"
+			continue
+		}
+		lat, lon, ok := parseCoords(parts[0] + "," + parts[1])
+		if !ok {
+			cards[i].Error = "Invalid location"
+			continue
+		}
+		if len(parts) == 3 {
+			cards[i].Name = strings.TrimSpace(parts[2])
+		}
+		cards[i].Path = coordsPath(lat, lon)
+
+		wg.Add(1)
+		go func(card *OverviewCard) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			wd, err := h.weather.GetWeatherContext(r.Context(), lat, lon)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Overview weather error", "location", db.PointKey(lat, lon), "error", err)
+				card.Error = "Failed to retrieve weather data"
+				return
+			}
+			card.Weather = wd.WithUnits(units)
+			if card.Name == "" {
+				card.Name = wd.Location
+			}
+		}(&cards[i])
+	}
+	wg.Wait()
+
+	if err := h.render(r.Context(), w, "overview_fragment", cards); err != nil {
+		slog.ErrorContext(r.Context(), "Template error", "error", err)
+	}
+}
+
+// decodeSavedLocations reads and validates a JSON array of saved locations.
+func decodeSavedLocations(w http.ResponseWriter, r *http.Request) ([]db.SavedLocation, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, 16<<10)
+
+	var locations []db.SavedLocation
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&locations); err != nil {
+		return nil, fmt.Errorf("invalid request body")
+	}
+
+	if len(locations) > maxSavedLocations {
+		return nil, fmt.Errorf("at most %d locations can be saved", maxSavedLocations)
+	}
+	for _, l := range locations {
+		if len(l.Name) > 100 {
+			return nil, fmt.Errorf("location name too long")
+		}
+		if _, _, ok := parseCoords(fmt.Sprintf("%f,%f", l.Latitude, l.Longitude)); !ok {
+			return nil, fmt.Errorf("invalid coordinates")
+		}
+	}
+	if locations == nil {
+		locations = []db.SavedLocation{}
+	}
+	return locations, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("JSON encode error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(data); err != nil {
+		slog.Error("Response write error", "error", err)
+	}
+}
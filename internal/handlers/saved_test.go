@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+func newSavedMux(h *Handlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/saved", h.HandleCreateSavedLocations)
+	mux.HandleFunc("/api/saved/{token}", h.HandleSavedLocations)
+	return mux
+}
+
+type savedResponse struct {
+	Token     string `json:"token"`
+	Locations []struct {
+		Name      string  `json:"name"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"locations"`
+}
+
+func TestSavedLocations_RoundTrip(t *testing.T) {
+	mux := newSavedMux(&Handlers{db: &mockDB{}})
+
+	req := httptest.NewRequest("POST", "/api/saved", strings.NewReader(`[{"name":"Austin, TX","latitude":30.27,"longitude":-97.74}]`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status Created, got %v: %s", w.Code, w.Body.String())
+	}
+	var created savedResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Token == "" {
+		t.Fatal("expected a sync token")
+	}
+
+	req = httptest.NewRequest("PUT", "/api/saved/"+created.Token, strings.NewReader(`[{"name":"Boise, ID","latitude":43.61,"longitude":-116.2}]`))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK from PUT, got %v: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/saved/"+created.Token, nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK from GET, got %v", w.Code)
+	}
+	var got savedResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got.Locations) != 1 || got.Locations[0].Name != "Boise, ID" {
+		t.Errorf("expected updated list, got %+v", got.Locations)
+	}
+}
+
+func TestSavedLocations_UnknownToken(t *testing.T) {
+	mux := newSavedMux(&Handlers{db: &mockDB{}})
+
+	for _, method := range []string{"GET", "PUT"} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/api/saved/missing", strings.NewReader(`[]`))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("expected status NotFound, got %v", w.Code)
+			}
+		})
+	}
+}
+
+func TestSavedLocations_InvalidBody(t *testing.T) {
+	mux := newSavedMux(&Handlers{db: &mockDB{}})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", `nope`},
+		{"unknown field", `[{"name":"X","latitude":1,"longitude":1,"extra":true}]`},
+		{"bad latitude", `[{"name":"X","latitude":100,"longitude":1}]`},
+		{"long name", `[{"name":"` + strings.Repeat("x", 101) + `","latitude":1,"longitude":1}]`},
+		{"too many", `[` + strings.TrimSuffix(strings.Repeat(`{"name":"X","latitude":1,"longitude":1},`, maxSavedLocations+1), ",") + `]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/saved", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status BadRequest, got %v", w.Code)
+			}
+		})
+	}
+}
+
+func TestSavedLocations_NoDatabase(t *testing.T) {
+	mux := newSavedMux(&Handlers{})
+
+	req := httptest.NewRequest("POST", "/api/saved", strings.NewReader(`[]`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable, got %v", w.Code)
+	}
+}
+
+func TestHandleOverview(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []float64
+	h := &Handlers{
+		weather: &mockWeather{
+			getWeatherFunc: func(lat, lon float64) (*weather.WeatherData, error) {
+				mu.Lock()
+				fetched = append(fetched, lat)
+				mu.Unlock()
+				if lat == 10 {
+					return nil, errors.New("NWS API error")
+				}
+				return sampleWeather(), nil
+			},
+		},
+		templates: loadTemplates(t),
+	}
+
+	req := httptest.NewRequest("GET", "/api/overview?loc=30.27,-97.74,Austin,+TX&loc=10,10&loc=bogus&loc=43.61,-116.2&units=metric", nil)
+	w := httptest.NewRecorder()
+	h.HandleOverview(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if len(fetched) != 3 {
+		t.Errorf("expected 3 weather lookups, got %d", len(fetched))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	html := string(body)
+	for _, want := range []string{
+		`<a href="/w/30.27,-97.74" class="overview-name">Austin, TX</a>`,
+		`<a href="/w/43.61,-116.20" class="overview-name">Somewhere, California</a>`,
+		"20°C",
+		"Failed to retrieve weather data",
+		"Invalid location",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected overview to contain %q", want)
+		}
+	}
+	if strings.Index(html, "Austin, TX") > strings.Index(html, "Somewhere, California") {
+		t.Errorf("expected cards in request order")
+	}
+}
+
+func TestHandleOverview_BadRequest(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{}, templates: loadTemplates(t)}
+
+	tooMany := strings.Repeat("loc=1,1&", maxOverviewLocations+1)
+	for _, target := range []string{"/api/overview", "/api/overview?" + tooMany, "/api/overview?loc=1,1&units=kelvin"} {
+		t.Run(target, func(t *testing.T) {
+			req := httptest.NewRequest("GET", target, nil)
+			w := httptest.NewRecorder()
+			h.HandleOverview(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status BadRequest, got %v", w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleOverview_NoTemplates(t *testing.T) {
+	h := &Handlers{weather: &mockWeather{}}
+	w := httptest.NewRecorder()
+	h.HandleOverview(w, httptest.NewRequest("GET", "/api/overview?loc=30.27,-97.74", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status InternalServerError, got %v", w.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/live"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment, so proxies
+// don't time it out and dead clients are noticed.
+var streamHeartbeat = 30 * time.Second
+
+// HandleWeatherStream is a Server-Sent Events stream of the weather for
+// ?lat=&lon=. Each "weather" event carries the whole WeatherData as JSON, or
+// the rendered weather fragment with format=html, and its ID is the
+// snapshot's version. A client reconnecting with Last-Event-ID (or
+// last_event_id on the first connection) only gets an event once something
+// has changed.
+func (h *Handlers) HandleWeatherStream(w http.ResponseWriter, r *http.Request) {
+	if h.live == nil {
+		http.Error(w, "Live updates unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	lat, lon, ok := parseCoords(q.Get("lat") + "," + q.Get("lon"))
+	if !ok {
+		http.Error(w, "invalid coordinates", http.StatusBadRequest)
+		return
+	}
+	units, err := unitsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "html" {
+		http.Error(w, "format must be json or html", http.StatusBadRequest)
+		return
+	}
+	if format == "html" && h.templates == nil {
+		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("last_event_id")
+	}
+
+	updates, cancel, err := h.live.Subscribe(h.clientIP(r), lat, lon)
+	if err != nil {
+		// The hub is full, either overall or for this client.
+		http.Error(w, "Too many live streams, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer cancel()
+
+	current, err := h.live.Current(lat, lon)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Weather error", "error", err)
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+		return
+	}
+
+	// Streams outlive the server's write timeout.
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	io.WriteString(w, "retry: 10000\n\n")
+
+	send := func(u live.Update) error {
+		if u.ID == lastID {
+			return nil
+		}
+		data, err := h.streamData(u.Weather.WithUnits(units), format)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: weather\nid: %s\n%s\n", u.ID, data); err != nil {
+			return err
+		}
+		lastID = u.ID
+		return rc.Flush()
+	}
+	if err := send(current); err != nil {
+		slog.ErrorContext(r.Context(), "Stream error", "error", err)
+		return
+	}
+	rc.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case u := <-updates:
+			if err := send(u); err != nil {
+				slog.ErrorContext(r.Context(), "Stream error", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// streamData renders an event's data field, one "data:" line per line of
+// the payload.
+func (h *Handlers) streamData(wd *weather.WeatherData, format string) (string, error) {
+	var payload []byte
+	if format == "html" {
+		var buf bytes.Buffer
+		if err := h.templates.ExecuteTemplate(&buf, "weather_fragment", wd); err != nil {
+			return "", err
+		}
+		payload = buf.Bytes()
+	} else {
+		var err error
+		if payload, err = json.Marshal(wd); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(string(payload), "\n"), "\n") {
+		b.WriteString("data: ")
+		b.WriteString(strings.TrimSuffix(line, "\r"))
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/live"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type mockLive struct {
+	current live.Update
+	updates chan live.Update
+	full    bool
+}
+
+func (m *mockLive) Subscribe(client string, lat, lon float64) (<-chan live.Update, func(), error) {
+	if m.full {
+		return nil, nil, live.ErrTooManyStreams
+	}
+	return m.updates, func() {}, nil
+}
+
+func (m *mockLive) Current(lat, lon float64) (live.Update, error) {
+	return m.current, nil
+}
+
+// sseEvent is one parsed Server-Sent Event, or a comment when Comment is set.
+type sseEvent struct {
+	Event, ID, Data, Comment string
+}
+
+// readEvent reads the next event or comment from a stream.
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	var data []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if ev.Event == "" && ev.Comment == "" && data == nil {
+				continue // retry: block
+			}
+			ev.Data = strings.Join(data, "\n")
+			return ev
+		case strings.HasPrefix(line, ": "):
+			ev.Comment = strings.TrimPrefix(line, ": ")
+		case strings.HasPrefix(line, "event: "):
+			ev.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "id: "):
+			ev.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+}
+
+func newStreamServer(t *testing.T, m *mockLive) *httptest.Server {
+	t.Helper()
+	h := &Handlers{live: m, templates: loadTemplates(t)}
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWeatherStream))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openStream(t *testing.T, url string, header http.Header) *bufio.Reader {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK, got %v", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestWeatherStream(t *testing.T) {
+	wd := &weather.WeatherData{
+		Current:  weather.CurrentCondition{Temperature: 68, TemperatureUnit: "F", ShortForecast: "Sunny"},
+		Location: "Oklahoma City, Oklahoma",
+		Units:    weather.UnitsUS,
+	}
+	m := &mockLive{current: live.Update{ID: "v1", Weather: wd}, updates: make(chan live.Update, 1)}
+	server := newStreamServer(t, m)
+
+	r := openStream(t, server.URL+"?lat=35.47&lon=-97.52&units=metric", nil)
+	ev := readEvent(t, r)
+	if ev.Event != "weather" || ev.ID != "v1" {
+		t.Fatalf("expected the current weather first, got %+v", ev)
+	}
+	var got weather.WeatherData
+	if err := json.Unmarshal([]byte(ev.Data), &got); err != nil {
+		t.Fatalf("bad event data: %v", err)
+	}
+	if got.Current.Temperature != 20 || got.Current.TemperatureUnit != "C" {
+		t.Errorf("expected metric units, got %+v", got.Current)
+	}
+
+	updated := *wd
+	updated.Alerts = []weather.Alert{{ID: "urn:oid:1", Event: "Tornado Warning"}}
+	m.updates <- live.Update{ID: "v2", Weather: &updated}
+	ev = readEvent(t, r)
+	if ev.ID != "v2" || !strings.Contains(ev.Data, "Tornado Warning") {
+		t.Errorf("expected the update, got %+v", ev)
+	}
+}
+
+func TestWeatherStream_ResumeAndHTML(t *testing.T) {
+	defer func(d time.Duration) { streamHeartbeat = d }(streamHeartbeat)
+	streamHeartbeat = 20 * time.Millisecond
+
+	wd := &weather.WeatherData{Location: "Oklahoma City, Oklahoma", Units: weather.UnitsUS}
+	m := &mockLive{current: live.Update{ID: "v1", Weather: wd}, updates: make(chan live.Update, 1)}
+	server := newStreamServer(t, m)
+
+	// Already up to date, so only heartbeats until something changes.
+	r := openStream(t, server.URL+"?lat=35.47&lon=-97.52&format=html", http.Header{"Last-Event-Id": {"v1"}})
+	if ev := readEvent(t, r); ev.Comment != "heartbeat" {
+		t.Fatalf("expected a heartbeat, got %+v", ev)
+	}
+
+	m.updates <- live.Update{ID: "v2", Weather: wd}
+	ev := readEvent(t, r)
+	for ev.Comment != "" {
+		ev = readEvent(t, r)
+	}
+	if ev.ID != "v2" || !strings.Contains(ev.Data, `<h3 class="location-name">Oklahoma City, Oklahoma</h3>`) {
+		t.Errorf("expected the rendered fragment, got %+v", ev)
+	}
+}
+
+func TestWeatherStream_BadRequest(t *testing.T) {
+	h := &Handlers{live: &mockLive{}}
+	for _, target := range []string{
+		"/api/weather/stream",
+		"/api/weather/stream?lat=95&lon=0",
+		"/api/weather/stream?lat=35&lon=-97&format=xml",
+		"/api/weather/stream?lat=35&lon=-97&units=kelvin",
+	} {
+		w := httptest.NewRecorder()
+		h.HandleWeatherStream(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status BadRequest, got %v", target, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	(&Handlers{}).HandleWeatherStream(w, httptest.NewRequest("GET", "/api/weather/stream?lat=35&lon=-97", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable without live updates, got %v", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	(&Handlers{live: &mockLive{full: true}}).HandleWeatherStream(w, httptest.NewRequest("GET", "/api/weather/stream?lat=35&lon=-97", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable when the hub is full, got %v", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleWeatherStream(w, httptest.NewRequest("GET", "/api/weather/stream?lat=35&lon=-97&format=html", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status InternalServerError without templates, got %v", w.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/swelljoe/wthr.lol/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace records a server span for each request, continuing the trace from
+// an incoming traceparent header. The span is named for the route pattern
+// the mux matched, which the middleware between here and the mux must pass
+// back out on the request.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)))
+		defer span.End()
+
+		r2 := r.WithContext(ctx)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r2)
+		r.Pattern = r2.Pattern
+
+		if r2.Pattern != "" {
+			method, route, ok := strings.Cut(r2.Pattern, " ")
+			if !ok {
+				method, route = r.Method, r2.Pattern
+			}
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// render executes the named template into w within a span.
+func (h *Handlers) render(ctx context.Context, w io.Writer, name string, data any) (err error) {
+	_, span := tracing.Start(ctx, "render "+name)
+	defer func() { tracing.End(span, err) }()
+	return h.templates.ExecuteTemplate(w, name, data)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTrace(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	h := &Handlers{templates: loadTemplates(t)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := h.render(r.Context(), w, "weather_fragment", sampleWeather()); err != nil {
+			t.Errorf("render failed: %v", err)
+		}
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Failed to retrieve weather data", http.StatusBadGateway)
+	})
+	// LogRequests sits between Trace and the mux in main, so it has to pass
+	// the route back out.
+	server := Trace(LogRequests(mux))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/test/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	spans := exp.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	render, handler, failed := spans[0], spans[1], spans[2]
+	if render.Name != "render weather_fragment" || render.Parent.SpanID() != handler.SpanContext.SpanID() {
+		t.Errorf("expected a render span under the handler's, got %q", render.Name)
+	}
+	if handler.Name != "GET /test/{id}" || handler.SpanContext.TraceID().String() != traceID {
+		t.Errorf("expected the handler span to continue the incoming trace, got %q in %s", handler.Name, handler.SpanContext.TraceID())
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("http.route", "/test/{id}"),
+		attribute.Int("http.response.status_code", http.StatusOK),
+	} {
+		found := false
+		for _, a := range handler.Attributes {
+			found = found || a == want
+		}
+		if !found {
+			t.Errorf("expected %v in %v", want, handler.Attributes)
+		}
+	}
+	if failed.Name != "GET /fail" || failed.Status.Code != codes.Error {
+		t.Errorf("expected the 502 span to be marked failed, got %q %v", failed.Name, failed.Status)
+	}
+}
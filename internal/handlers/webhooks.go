@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// webhookRequest is the body of a create webhook request.
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"` // Optional; generated when empty
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+	MinSeverity string   `json:"min_severity"`
+	Events      []string `json:"events"`
+}
+
+// HandleWebhooks lists (GET) or creates (POST) alert webhooks. The signing
+// secret is only returned by POST.
+func (h *Handlers) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !h.webhookAuthorized(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := h.db.ListWebhooks(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to list webhooks", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		for i := range hooks {
+			hooks[i].Secret = ""
+		}
+		if hooks == nil {
+			hooks = []db.Webhook{}
+		}
+		writeJSON(w, http.StatusOK, hooks)
+
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, 16<<10)
+		var req webhookRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			http.Error(w, "url must be an http or https URL", http.StatusBadRequest)
+			return
+		}
+		if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
+			http.Error(w, "invalid coordinates", http.StatusBadRequest)
+			return
+		}
+		if req.MinSeverity != "" && weather.SeverityRank(req.MinSeverity) < 0 {
+			http.Error(w, "min_severity must be one of Minor, Moderate, Severe or Extreme", http.StatusBadRequest)
+			return
+		}
+
+		wh := &db.Webhook{
+			URL:         req.URL,
+			Secret:      req.Secret,
+			Latitude:    req.Latitude,
+			Longitude:   req.Longitude,
+			MinSeverity: req.MinSeverity,
+		}
+		for _, e := range req.Events {
+			if e = strings.TrimSpace(e); e != "" {
+				wh.Events = append(wh.Events, e)
+			}
+		}
+		if err := h.db.CreateWebhook(r.Context(), wh); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to create webhook", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, wh)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleDeleteWebhook deletes the webhook with the {id} path value.
+func (h *Handlers) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.webhookAuthorized(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	found, err := h.db.DeleteWebhook(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete webhook", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleWebhookDeliveries returns the delivery log for the webhook with the
+// {id} path value, newest first. limit defaults to 50.
+func (h *Handlers) HandleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.webhookAuthorized(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	deliveries, err := h.db.ListWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list webhook deliveries", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// webhookAuthorized checks the bearer token, writing an error response if
+// the request may not manage webhooks.
+func (h *Handlers) webhookAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	if h.webhookToken == "" || h.db == nil {
+		http.Error(w, "Webhooks unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.webhookToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
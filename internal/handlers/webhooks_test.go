@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+)
+
+func newWebhookMux(h *Handlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/webhooks", h.HandleWebhooks)
+	mux.HandleFunc("DELETE /api/webhooks/{id}", h.HandleDeleteWebhook)
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", h.HandleWebhookDeliveries)
+	return mux
+}
+
+func authedRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-token")
+	return req
+}
+
+func TestHandleWebhooks(t *testing.T) {
+	mock := &mockDB{}
+	mux := newWebhookMux(&Handlers{db: mock, webhookToken: "admin-token"})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, authedRequest("POST", "/api/webhooks",
+		`{"url":"https://hooks.example/wthr","latitude":35.47,"longitude":-97.52,"min_severity":"Severe","events":["Tornado Warning"," "]}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status Created, got %v: %s", w.Code, w.Body.String())
+	}
+	var created db.Webhook
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.ID != 1 || created.Secret != "generated" || len(created.Events) != 1 {
+		t.Errorf("unexpected webhook %+v", created)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, authedRequest("GET", "/api/webhooks", ""))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "generated") {
+		t.Errorf("expected list without secrets, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, authedRequest("GET", "/api/webhooks/1/deliveries", ""))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"alert_id":"urn:oid:1"`) {
+		t.Errorf("unexpected deliveries response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, authedRequest("DELETE", "/api/webhooks/1", ""))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status NoContent, got %v", w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, authedRequest("DELETE", "/api/webhooks/1", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status NotFound for deleted webhook, got %v", w.Code)
+	}
+}
+
+func TestHandleWebhooks_Auth(t *testing.T) {
+	mux := newWebhookMux(&Handlers{db: &mockDB{}, webhookToken: "admin-token"})
+
+	req := httptest.NewRequest("GET", "/api/webhooks", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status Unauthorized, got %v", w.Code)
+	}
+
+	mux = newWebhookMux(&Handlers{db: &mockDB{}})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, authedRequest("GET", "/api/webhooks", ""))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status ServiceUnavailable without a token configured, got %v", w.Code)
+	}
+}
+
+func TestHandleWebhooks_Invalid(t *testing.T) {
+	mux := newWebhookMux(&Handlers{db: &mockDB{}, webhookToken: "admin-token"})
+
+	for _, body := range []string{
+		`nope`,
+		`{"url":"ftp://hooks.example","latitude":1,"longitude":1}`,
+		`{"url":"https://hooks.example","latitude":91,"longitude":1}`,
+		`{"url":"https://hooks.example","latitude":1,"longitude":1,"min_severity":"Apocalyptic"}`,
+		`{"url":"https://hooks.example","latitude":1,"longitude":1,"colour":"red"}`,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, authedRequest("POST", "/api/webhooks", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status BadRequest for %s, got %v", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package live pushes weather updates for a location to connected clients
+// as the cache is refreshed or alerts change.
+package live
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// WeatherSource fetches the weather for a point; *weather.Service
+// implements it.
+type WeatherSource interface {
+	GetWeather(lat, lon float64) (*weather.WeatherData, error)
+}
+
+// AlertSource fetches active alerts for a point; *weather.ActiveAlerts and
+// *weather.Service implement it.
+type AlertSource interface {
+	GetAlerts(lat, lon float64) ([]weather.Alert, error)
+}
+
+// Subscribe fails with these when the Hub is full. Each new location adds
+// upstream polling, so both are capped.
+var (
+	ErrTooManyLocations = errors.New("too many locations watched")
+	ErrTooManyStreams   = errors.New("too many streams for this client")
+)
+
+// Update is a new snapshot of the weather for a location. ID is the
+// snapshot's Version, which clients send back as Last-Event-ID.
+type Update struct {
+	ID      string
+	Weather *weather.WeatherData
+}
+
+// Hub tracks the locations clients are watching and polls each one,
+// sending an Update to its subscribers whenever the weather changes.
+type Hub struct {
+	weather  WeatherSource
+	alerts   AlertSource
+	Interval time.Duration
+	// MaxLocations caps the locations watched at once, and
+	// MaxStreamsPerClient the subscriptions any one client holds.
+	MaxLocations        int
+	MaxStreamsPerClient int
+
+	mu      sync.Mutex
+	topics  map[string]*topic
+	clients map[string]int // Subscriptions held by each client
+}
+
+// topic is one watched location, keyed by the weather cache's point key.
+type topic struct {
+	lat, lon float64
+	subs     map[chan Update]struct{}
+	last     string // ID of the last Update sent
+}
+
+// NewHub creates a Hub that checks watched locations every minute, up to
+// 500 of them with at most 10 streams per client. Weather comes from the
+// cache, so NWS is still only asked when it expires; alerts come from
+// alerts so new ones show up without waiting for that.
+func NewHub(weather WeatherSource, alerts AlertSource) *Hub {
+	return &Hub{
+		weather:             weather,
+		alerts:              alerts,
+		Interval:            time.Minute,
+		MaxLocations:        500,
+		MaxStreamsPerClient: 10,
+		topics:              make(map[string]*topic),
+		clients:             make(map[string]int),
+	}
+}
+
+// Subscribe starts watching a location for client, e.g. its IP address.
+// Updates arrive on the returned channel until cancel is called. A slow
+// reader only ever gets the newest Update. It fails with
+// ErrTooManyStreams if client already has MaxStreamsPerClient
+// subscriptions, or ErrTooManyLocations if the location isn't watched yet
+// and MaxLocations are.
+func (h *Hub) Subscribe(client string, lat, lon float64) (<-chan Update, func(), error) {
+	key := db.PointKey(lat, lon)
+	ch := make(chan Update, 1)
+
+	h.mu.Lock()
+	if h.clients[client] >= h.MaxStreamsPerClient {
+		h.mu.Unlock()
+		return nil, nil, ErrTooManyStreams
+	}
+	t := h.topics[key]
+	if t == nil {
+		if len(h.topics) >= h.MaxLocations {
+			h.mu.Unlock()
+			return nil, nil, ErrTooManyLocations
+		}
+		t = &topic{lat: lat, lon: lon, subs: make(map[chan Update]struct{})}
+		h.topics[key] = t
+	}
+	t.subs[ch] = struct{}{}
+	h.clients[client]++
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(t.subs, ch)
+			if len(t.subs) == 0 && h.topics[key] == t {
+				delete(h.topics, key)
+			}
+			if h.clients[client]--; h.clients[client] <= 0 {
+				delete(h.clients, client)
+			}
+		})
+	}
+	return ch, cancel, nil
+}
+
+// Current returns the latest snapshot for a location.
+func (h *Hub) Current(lat, lon float64) (Update, error) {
+	wd, err := h.weather.GetWeather(lat, lon)
+	if err != nil {
+		return Update{}, err
+	}
+	if h.alerts != nil {
+		alerts, err := h.alerts.GetAlerts(lat, lon)
+		if err != nil {
+			// Keep the cached alerts rather than dropping them.
+			log.Printf("Live: failed to get alerts for %s: %v", db.PointKey(lat, lon), err)
+		} else {
+			fresh := *wd
+			fresh.Alerts = alerts
+			wd = &fresh
+		}
+	}
+	return Update{ID: wd.Version(), Weather: wd}, nil
+}
+
+// Run polls until ctx is cancelled.
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Poll()
+		}
+	}
+}
+
+// Poll checks every watched location once and sends an Update to its
+// subscribers if the weather has changed since the last one.
+func (h *Hub) Poll() {
+	h.mu.Lock()
+	watched := make(map[string]*topic, len(h.topics))
+	for key, t := range h.topics {
+		watched[key] = t
+	}
+	h.mu.Unlock()
+
+	for key, t := range watched {
+		u, err := h.Current(t.lat, t.lon)
+		if err != nil {
+			log.Printf("Live: failed to get weather for %s: %v", key, err)
+			continue
+		}
+
+		h.mu.Lock()
+		if u.ID != t.last {
+			t.last = u.ID
+			for ch := range t.subs {
+				send(ch, u)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Watching returns the number of locations with at least one subscriber.
+func (h *Hub) Watching() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics)
+}
+
+// send replaces any Update still waiting in ch with u. Only Poll sends, and
+// it holds h.mu, so the second send can't block.
+func send(ch chan Update, u Update) {
+	select {
+	case ch <- u:
+	default:
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- u
+	}
+}
@@ -0,0 +1,167 @@
+package live
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type fakeWeather struct {
+	cachedAt time.Time
+	err      error
+	calls    int
+}
+
+func (f *fakeWeather) GetWeather(lat, lon float64) (*weather.WeatherData, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &weather.WeatherData{
+		CachedAt: f.cachedAt,
+		Alerts:   []weather.Alert{{ID: "cached"}},
+		Latitude: lat, Longitude: lon,
+	}, nil
+}
+
+type fakeAlerts struct {
+	alerts []weather.Alert
+	err    error
+}
+
+func (f *fakeAlerts) GetAlerts(lat, lon float64) ([]weather.Alert, error) {
+	return f.alerts, f.err
+}
+
+func receive(t *testing.T, ch <-chan Update) (Update, bool) {
+	t.Helper()
+	select {
+	case u := <-ch:
+		return u, true
+	default:
+		return Update{}, false
+	}
+}
+
+func subscribe(t *testing.T, h *Hub, client string, lat, lon float64) (<-chan Update, func()) {
+	t.Helper()
+	ch, cancel, err := h.Subscribe(client, lat, lon)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	return ch, cancel
+}
+
+func TestHubPoll(t *testing.T) {
+	ws := &fakeWeather{cachedAt: time.Date(2025, 5, 6, 13, 0, 0, 0, time.UTC)}
+	as := &fakeAlerts{}
+	h := NewHub(ws, as)
+
+	a, cancelA := subscribe(t, h, "a", 35.4676, -97.5164)
+	b, cancelB := subscribe(t, h, "b", 35.4712, -97.5199) // same rounded point
+	defer cancelB()
+	if h.Watching() != 1 {
+		t.Fatalf("expected one watched location, got %d", h.Watching())
+	}
+
+	h.Poll()
+	first, ok := receive(t, a)
+	if !ok {
+		t.Fatal("expected an initial update")
+	}
+	if _, ok := receive(t, b); !ok {
+		t.Fatal("expected both subscribers to get the update")
+	}
+	if len(first.Weather.Alerts) != 0 {
+		t.Errorf("expected fresh alerts to replace cached ones, got %+v", first.Weather.Alerts)
+	}
+	if ws.calls != 1 {
+		t.Errorf("expected one fetch per location, got %d", ws.calls)
+	}
+
+	// Nothing changed
+	h.Poll()
+	if u, ok := receive(t, a); ok {
+		t.Errorf("expected no update, got %+v", u)
+	}
+
+	// A new alert
+	as.alerts = []weather.Alert{{ID: "urn:oid:1", Event: "Tornado Warning"}}
+	h.Poll()
+	u, ok := receive(t, a)
+	if !ok || u.ID == first.ID || u.Weather.Alerts[0].Event != "Tornado Warning" {
+		t.Errorf("expected an alert update, got %+v", u)
+	}
+
+	// A cache refresh, twice without reading: only the newest is kept.
+	ws.cachedAt = ws.cachedAt.Add(time.Hour)
+	h.Poll()
+	ws.cachedAt = ws.cachedAt.Add(time.Hour)
+	h.Poll()
+	u, _ = receive(t, b)
+	if !u.Weather.CachedAt.Equal(ws.cachedAt) {
+		t.Errorf("expected the newest update, got %v", u.Weather.CachedAt)
+	}
+
+	cancelA()
+	if h.Watching() != 1 {
+		t.Errorf("expected the location to stay watched by the other subscriber")
+	}
+}
+
+func TestHubCurrent_AlertError(t *testing.T) {
+	ws := &fakeWeather{}
+	h := NewHub(ws, &fakeAlerts{err: errors.New("unavailable")})
+
+	u, err := h.Current(35.47, -97.52)
+	if err != nil {
+		t.Fatalf("Current failed: %v", err)
+	}
+	if len(u.Weather.Alerts) != 1 || u.Weather.Alerts[0].ID != "cached" {
+		t.Errorf("expected the cached alerts to be kept, got %+v", u.Weather.Alerts)
+	}
+
+	ws.err = errors.New("nws down")
+	if _, err := h.Current(35.47, -97.52); err == nil {
+		t.Error("expected an error when the weather is unavailable")
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	h := NewHub(&fakeWeather{}, &fakeAlerts{})
+	_, cancel := subscribe(t, h, "a", 35.47, -97.52)
+	cancel()
+	if h.Watching() != 0 {
+		t.Errorf("expected no watched locations, got %d", h.Watching())
+	}
+}
+
+func TestHubLimits(t *testing.T) {
+	h := NewHub(&fakeWeather{}, &fakeAlerts{})
+	h.MaxLocations = 2
+	h.MaxStreamsPerClient = 2
+
+	_, cancelA := subscribe(t, h, "a", 35.47, -97.52)
+	subscribe(t, h, "a", 35.47, -97.52)
+	if _, _, err := h.Subscribe("a", 36.15, -95.99); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("expected ErrTooManyStreams, got %v", err)
+	}
+
+	subscribe(t, h, "b", 36.15, -95.99)
+	if _, _, err := h.Subscribe("b", 34.6, -98.4); !errors.Is(err, ErrTooManyLocations) {
+		t.Errorf("expected ErrTooManyLocations, got %v", err)
+	}
+	// A watched location can still be joined.
+	subscribe(t, h, "b", 35.47, -97.52)
+
+	// Cancelling frees the client's stream, and doing it twice doesn't free
+	// another.
+	cancelA()
+	cancelA()
+	subscribe(t, h, "a", 36.15, -95.99)
+	if _, _, err := h.Subscribe("a", 35.47, -97.52); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("expected ErrTooManyStreams, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package logging sets up structured logging with log/slog and carries a
+// request's ID, and what it found in the weather cache, through its
+// context.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup makes a text or JSON slog handler writing to w the default, which
+// also routes the log package's output through it. format is "text" (the
+// default) or "json"; level is "debug", "info" (the default), "warn" or
+// "error".
+func Setup(w io.Writer, format, level string) error {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("invalid log level %q", level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+	slog.SetDefault(slog.New(NewHandler(h)))
+	return nil
+}
+
+// NewHandler wraps h to add request_id and trace_id attributes to records
+// logged with a context carrying a request ID or a span.
+func NewHandler(h slog.Handler) slog.Handler {
+	return contextHandler{h}
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestKey struct{}
+
+// request is what's carried in a request's context.
+type request struct {
+	id string
+
+	mu    sync.Mutex
+	cache []string
+}
+
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{id: id})
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		return r.id
+	}
+	return ""
+}
+
+// NoteCache records the result of a weather cache lookup made for the
+// request ctx belongs to, for its request log. It does nothing outside a
+// request.
+func NoteCache(ctx context.Context, result string) {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		r.mu.Lock()
+		r.cache = append(r.cache, result)
+		r.mu.Unlock()
+	}
+}
+
+// CacheOutcome returns the results NoteCache recorded for the request,
+// comma separated in order, or "" if it made no lookups.
+func CacheOutcome(ctx context.Context) string {
+	r, ok := ctx.Value(requestKey{}).(*request)
+	if !ok {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.cache, ",")
+}
+
+// Millis returns d in milliseconds, for latency attributes.
+func Millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	defer log.SetFlags(log.Flags())
+	defer log.SetOutput(log.Writer())
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, "json", "debug"); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	ctx := WithRequestID(context.Background(), "abc123")
+	slog.DebugContext(ctx, "upstream request", "endpoint", "points")
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("expected JSON, got %q: %v", buf.String(), err)
+	}
+	if rec["msg"] != "upstream request" || rec["level"] != "DEBUG" || rec["endpoint"] != "points" || rec["request_id"] != "abc123" {
+		t.Errorf("unexpected record %v", rec)
+	}
+
+	buf.Reset()
+	slog.With("component", "test").InfoContext(ctx, "hello")
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil || rec["request_id"] != "abc123" || rec["component"] != "test" {
+		t.Errorf("expected the request ID through With, got %q", buf.String())
+	}
+
+	buf.Reset()
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanCtx := trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	slog.InfoContext(spanCtx, "traced")
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil || rec["trace_id"] != traceID.String() {
+		t.Errorf("expected the trace ID, got %q", buf.String())
+	}
+
+	for _, bad := range [][2]string{{"xml", ""}, {"", "loud"}} {
+		if err := Setup(&buf, bad[0], bad[1]); err == nil {
+			t.Errorf("expected Setup(%q, %q) to fail", bad[0], bad[1])
+		}
+	}
+}
+
+func TestCacheOutcome(t *testing.T) {
+	NoteCache(context.Background(), "hit") // No request; ignored
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	if CacheOutcome(ctx) != "" {
+		t.Errorf("expected no outcome before a lookup")
+	}
+	NoteCache(ctx, "hit")
+	NoteCache(ctx, "stale")
+	if got := CacheOutcome(ctx); got != "hit,stale" {
+		t.Errorf("got %q, want hit,stale", got)
+	}
+	if RequestID(context.Background()) != "" || len(NewRequestID()) != 16 {
+		t.Errorf("unexpected request IDs")
+	}
+}
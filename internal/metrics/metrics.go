@@ -0,0 +1,282 @@
+// Package metrics keeps counters, gauges and histograms and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are histogram buckets, in seconds, suited to request and
+// query latencies.
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry is a set of metrics written out together.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// Default is the registry the New functions register with and Handler
+// serves.
+var Default = NewRegistry()
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// register adds m under name, panicking on a duplicate like an init-time
+// mistake should.
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.metrics[name] = m
+}
+
+// Write writes every metric, sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	metrics := make([]metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the Default registry.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Default.Write(w)
+	})
+}
+
+// family is what every metric type shares: a name, help text and label
+// names, and one series per combination of label values.
+type family[T any] struct {
+	name, help, kind string
+	labels           []string
+
+	mu     sync.Mutex
+	series map[string]*T // Keyed by the encoded label values
+}
+
+func newFamily[T any](name, help, kind string, labels []string) *family[T] {
+	return &family[T]{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*T)}
+}
+
+// get returns the series for values, creating it with create if it's new.
+// The caller must hold f.mu.
+func (f *family[T]) get(values []string, create func() *T) *T {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+	}
+	return s
+}
+
+// each calls fn for every series in label order, with the series' labels
+// formatted for the exposition format. The caller must hold f.mu.
+func (f *family[T]) each(fn func(labels string, s *T)) {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		var values []string
+		if len(f.labels) > 0 {
+			values = strings.Split(key, "\xff")
+		}
+		fn(formatLabels(f.labels, values), f.series[key])
+	}
+}
+
+func (f *family[T]) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+}
+
+// Counter is a count that only goes up, such as requests served.
+type Counter struct {
+	*family[float64]
+}
+
+// NewCounter registers a Counter with the Default registry.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newFamily[float64](name, help, "counter", labels)}
+	Default.register(name, c)
+	return c
+}
+
+// Inc adds one to the series for values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v, which must not be negative, to the series for values.
+func (c *Counter) Add(v float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.get(values, func() *float64 { return new(float64) }) += v
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	c.each(func(labels string, v *float64) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatValue(*v))
+	})
+}
+
+// Gauge is a value that can go up and down, such as a temperature.
+type Gauge struct {
+	*family[float64]
+}
+
+// NewGauge registers a Gauge with the Default registry.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newFamily[float64](name, help, "gauge", labels)}
+	Default.register(name, g)
+	return g
+}
+
+// Set sets the series for values to v.
+func (g *Gauge) Set(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.get(values, func() *float64 { return new(float64) }) = v
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.header(w)
+	g.each(func(labels string, v *float64) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatValue(*v))
+	})
+}
+
+// Histogram counts observations, such as latencies, into buckets.
+type Histogram struct {
+	*family[histogramSeries]
+	buckets []float64
+}
+
+type histogramSeries struct {
+	counts []uint64 // Per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram registers a Histogram with the Default registry. Buckets
+// are upper bounds in increasing order; nil means DefBuckets.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &Histogram{newFamily[histogramSeries](name, help, "histogram", labels), buckets}
+	Default.register(name, h)
+	return h
+}
+
+// Observe records v in the series for values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(values, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(h.buckets))}
+	})
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	h.each(func(labels string, s *histogramSeries) {
+		// le goes last in the bucket labels.
+		prefix := "{"
+		if labels != "" {
+			prefix = strings.TrimSuffix(labels, "}") + ","
+		}
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%sle=\"%s\"} %d\n", h.name, prefix, formatValue(le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%sle=\"+Inf\"} %d\n", h.name, prefix, s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, s.count)
+	})
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
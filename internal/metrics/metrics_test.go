@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	requests := NewCounter("test_requests_total", "Requests served.", "handler", "code")
+	requests.Inc("GET /w/{coords}", "200")
+	requests.Inc("GET /w/{coords}", "200")
+	requests.Add(3, `say "hi"`, "500")
+	temp := NewGauge("test_temperature_celsius", "Current temperature.", "location")
+	temp.Set(-2.5, "35.47,-97.52")
+	latency := NewHistogram("test_duration_seconds", "Latency.", []float64{0.1, 1}, "endpoint")
+	latency.Observe(0.05, "points")
+	latency.Observe(0.1, "points")
+	latency.Observe(3, "points")
+	NewCounter("test_unlabelled_total", "No labels.").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# HELP test_requests_total Requests served.\n# TYPE test_requests_total counter\n",
+		`test_requests_total{handler="GET /w/{coords}",code="200"} 2` + "\n",
+		`test_requests_total{handler="say \"hi\"",code="500"} 3` + "\n",
+		"# TYPE test_temperature_celsius gauge\n" + `test_temperature_celsius{location="35.47,-97.52"} -2.5` + "\n",
+		`test_duration_seconds_bucket{endpoint="points",le="0.1"} 2` + "\n" +
+			`test_duration_seconds_bucket{endpoint="points",le="1"} 2` + "\n" +
+			`test_duration_seconds_bucket{endpoint="points",le="+Inf"} 3` + "\n" +
+			`test_duration_seconds_sum{endpoint="points"} 3.15` + "\n" +
+			`test_duration_seconds_count{endpoint="points"} 3` + "\n",
+		"test_unlabelled_total 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "test_duration_seconds") > strings.Index(body, "test_requests_total") {
+		t.Errorf("expected metrics sorted by name")
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	c := NewCounter("test_label_count_total", "Help.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic on the wrong number of label values")
+		}
+	}()
+	c.Inc("only one")
+}
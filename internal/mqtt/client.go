@@ -0,0 +1,158 @@
+// Package mqtt publishes current conditions and alerts for configured
+// locations to an MQTT broker, with Home Assistant discovery.
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types, already shifted into the high nibble.
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetDisconnect = 0xe0
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	// ioTimeout bounds each packet exchange, so a stalled broker can't hang
+	// a poll.
+	ioTimeout = 10 * time.Second
+	// keepAlive is sent in CONNECT. Connections only last for one poll, so
+	// this just has to cover publishing a batch.
+	keepAlive = 60
+	// maxRemaining is the largest remaining length MQTT can encode.
+	maxRemaining = 268435455
+)
+
+// connackErrors are the CONNACK return codes that refuse a connection.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Client is a minimal MQTT 3.1.1 client that can only publish, at QoS 0.
+// It isn't safe for concurrent use.
+type Client struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// Dial connects to the broker at addr (host:port) and logs in. Username
+// and password are optional. The session is clean, so nothing is kept for
+// clientID between connections.
+func Dial(addr, clientID, username, password string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, w: bufio.NewWriter(conn)}
+	if err := c.connect(clientID, username, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) connect(clientID, username, password string) error {
+	flags := byte(0x02) // Clean session
+	var payload []byte
+	payload = appendString(payload, clientID)
+	if username != "" {
+		flags |= 0x80
+		payload = appendString(payload, username)
+		if password != "" {
+			flags |= 0x40
+			payload = appendString(payload, password)
+		}
+	}
+
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, keepAlive)
+	body = append(body, payload...)
+	if err := c.write(packetConnect, body); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	var ack [4]byte
+	if _, err := io.ReadFull(c.conn, ack[:]); err != nil {
+		return fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if ack[0] != packetConnack || ack[1] != 2 {
+		return errors.New("unexpected reply to CONNECT")
+	}
+	if ack[3] != 0 {
+		if msg, ok := connackErrors[ack[3]]; ok {
+			return fmt.Errorf("connection refused: %s", msg)
+		}
+		return fmt.Errorf("connection refused: code %d", ack[3])
+	}
+	return nil
+}
+
+// Publish sends payload to topic at QoS 0. The broker keeps retained
+// messages and hands the latest one to each new subscriber.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	header := byte(packetPublish)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.write(header, body)
+}
+
+// Close disconnects from the broker.
+func (c *Client) Close() error {
+	err := c.write(packetDisconnect, nil)
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// write sends one control packet.
+func (c *Client) write(header byte, body []byte) error {
+	if len(body) > maxRemaining {
+		return errors.New("packet too large")
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+	c.w.WriteByte(header)
+	c.w.Write(appendLength(nil, len(body)))
+	c.w.Write(body)
+	return c.w.Flush()
+}
+
+// appendLength appends n as an MQTT variable byte integer.
+func appendLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+// appendString appends s as a length-prefixed MQTT UTF-8 string.
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
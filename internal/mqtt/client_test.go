@@ -0,0 +1,182 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// published is a PUBLISH the test broker received.
+type published struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// testBroker is a local stand-in for an MQTT broker. It accepts any
+// client unless refuse is set, and records what is published.
+type testBroker struct {
+	ln     net.Listener
+	refuse byte // CONNACK return code
+
+	mu       sync.Mutex
+	connects []connectPacket
+	messages []published
+	done     chan struct{} // Receives when a client disconnects
+}
+
+type connectPacket struct {
+	clientID, username, password string
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{ln: ln, done: make(chan struct{}, 10)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch header & 0xf0 {
+		case packetConnect:
+			// Skip the protocol name, level, flags and keep alive.
+			flags := body[7]
+			rest := body[10:]
+			var cp connectPacket
+			cp.clientID, rest = readString(rest)
+			if flags&0x80 != 0 {
+				cp.username, rest = readString(rest)
+			}
+			if flags&0x40 != 0 {
+				cp.password, _ = readString(rest)
+			}
+			b.mu.Lock()
+			b.connects = append(b.connects, cp)
+			b.mu.Unlock()
+			conn.Write([]byte{packetConnack, 2, 0, b.refuse})
+		case packetPublish:
+			topic, payload := readString(body)
+			b.mu.Lock()
+			b.messages = append(b.messages, published{topic, string(payload), header&0x01 != 0})
+			b.mu.Unlock()
+		case packetDisconnect:
+			b.done <- struct{}{}
+			return
+		}
+	}
+}
+
+func (b *testBroker) published() []published {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]published(nil), b.messages...)
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(digit&0x7f) * mult
+		mult *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+func readString(b []byte) (string, []byte) {
+	n := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+func TestAppendLength(t *testing.T) {
+	tests := map[int][]byte{
+		0:         {0x00},
+		127:       {0x7f},
+		128:       {0x80, 0x01},
+		16383:     {0xff, 0x7f},
+		2097152:   {0x80, 0x80, 0x80, 0x01},
+		268435455: {0xff, 0xff, 0xff, 0x7f},
+	}
+	for n, want := range tests {
+		if got := appendLength(nil, n); string(got) != string(want) {
+			t.Errorf("appendLength(%d) = % x, want % x", n, got, want)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	b := newTestBroker(t)
+
+	c, err := Dial(b.addr(), "wthr-test", "user", "secret")
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'x'
+	}
+	if err := c.Publish("wthr/home/current", long, true); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := c.Publish("wthr/home/ping", []byte("{}"), false); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	<-b.done
+
+	if len(b.connects) != 1 || b.connects[0] != (connectPacket{"wthr-test", "user", "secret"}) {
+		t.Errorf("unexpected CONNECT %+v", b.connects)
+	}
+	msgs := b.published()
+	if len(msgs) != 2 || msgs[0].topic != "wthr/home/current" || msgs[0].payload != string(long) || !msgs[0].retain || msgs[1].retain {
+		t.Errorf("unexpected messages %+v", msgs)
+	}
+}
+
+func TestClient_Refused(t *testing.T) {
+	b := newTestBroker(t)
+	b.refuse = 5
+
+	if _, err := Dial(b.addr(), "wthr", "", ""); err == nil || err.Error() != "connection refused: not authorized" {
+		t.Errorf("expected the connection to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package mqtt
+
+import "github.com/swelljoe/wthr.lol/internal/weather"
+
+// sensorConfig is a Home Assistant MQTT discovery config for one sensor.
+// Home Assistant has no MQTT weather platform, so each location is a
+// device with a sensor per value.
+type sensorConfig struct {
+	key                 string
+	Name                string `json:"name"`
+	UniqueID            string `json:"unique_id"`
+	StateTopic          string `json:"state_topic"`
+	ValueTemplate       string `json:"value_template"`
+	JSONAttributesTopic string `json:"json_attributes_topic,omitempty"`
+	DeviceClass         string `json:"device_class,omitempty"`
+	StateClass          string `json:"state_class,omitempty"`
+	Unit                string `json:"unit_of_measurement,omitempty"`
+	Icon                string `json:"icon,omitempty"`
+	Device              device `json:"device"`
+}
+
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// discovery returns the sensor configs for a location. Units are taken
+// from its first payload, which is in p.Units like every later one.
+func (p *Publisher) discovery(loc Location, current *weather.HAWeather) []sensorConfig {
+	dev := device{
+		Identifiers:  []string{"wthr_" + loc.Name},
+		Name:         "wthr " + loc.Name,
+		Manufacturer: "wthr.lol",
+		Model:        "National Weather Service forecast",
+	}
+	currentTopic := p.topic(loc, "current")
+	sensors := []sensorConfig{
+		{key: "condition", Name: "Condition", StateTopic: currentTopic,
+			ValueTemplate: "{{ value_json.condition }}", Icon: "mdi:weather-partly-cloudy"},
+		{key: "temperature", Name: "Temperature", StateTopic: currentTopic,
+			ValueTemplate: "{{ value_json.temperature }}", DeviceClass: "temperature", StateClass: "measurement",
+			Unit: current.TemperatureUnit},
+		{key: "apparent_temperature", Name: "Feels like", StateTopic: currentTopic,
+			ValueTemplate: "{{ value_json.apparent_temperature }}", DeviceClass: "temperature", StateClass: "measurement",
+			Unit: current.TemperatureUnit},
+		{key: "precipitation_probability", Name: "Chance of precipitation", StateTopic: currentTopic,
+			ValueTemplate: "{{ value_json.precipitation_probability }}", StateClass: "measurement", Unit: "%",
+			Icon: "mdi:water-percent"},
+		{key: "wind_speed", Name: "Wind speed", StateTopic: currentTopic,
+			ValueTemplate: "{{ value_json.wind_speed }}", DeviceClass: "wind_speed", StateClass: "measurement",
+			Unit: current.WindSpeedUnit},
+		{key: "alerts", Name: "Alerts", StateTopic: p.topic(loc, "alerts"),
+			ValueTemplate: "{{ value_json.alerts | count }}", JSONAttributesTopic: p.topic(loc, "alerts"),
+			Icon: "mdi:alert"},
+	}
+	for i := range sensors {
+		sensors[i].UniqueID = "wthr_" + loc.Name + "_" + sensors[i].key
+		sensors[i].Device = dev
+	}
+	return sensors
+}
@@ -0,0 +1,247 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/alerts"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// WeatherSource fetches the weather for a point; *weather.Service
+// implements it.
+type WeatherSource interface {
+	GetWeather(lat, lon float64) (*weather.WeatherData, error)
+}
+
+// AlertSource fetches active alerts for a point; *weather.ActiveAlerts and
+// *weather.Service implement it.
+type AlertSource interface {
+	GetAlerts(lat, lon float64) ([]weather.Alert, error)
+}
+
+// Location is a point published under its Name, which is used in topics
+// and Home Assistant entity IDs.
+type Location struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+var locationName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// ParseLocations parses a semicolon separated list of name=lat,lon
+// locations, e.g. "home=35.47,-97.52; cabin=36.15,-95.99". Names may use
+// lowercase letters, digits, "_" and "-".
+func ParseLocations(s string) ([]Location, error) {
+	var locations []Location
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, coords, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !locationName.MatchString(name) {
+			return nil, fmt.Errorf("invalid location %q", entry)
+		}
+		points, err := alerts.ParsePoints(coords)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) != 1 {
+			return nil, fmt.Errorf("invalid location %q", entry)
+		}
+		locations = append(locations, Location{Name: name, Latitude: points[0].Latitude, Longitude: points[0].Longitude})
+	}
+	return locations, nil
+}
+
+// Publisher periodically publishes the weather for each of Locations to
+// Prefix/{name}/current, in the Home Assistant shape of
+// weather.HAWeather, and its active alerts to Prefix/{name}/alerts. Both
+// are retained and only sent when they change. The first time a location
+// is published, Home Assistant discovery configs for its sensors are sent
+// under DiscoveryPrefix; an empty DiscoveryPrefix turns that off.
+//
+// Each poll opens its own connection to the broker, so there is no
+// session to keep alive in between.
+type Publisher struct {
+	weather         WeatherSource
+	alerts          AlertSource
+	Addr            string // Broker host:port
+	ClientID        string
+	Username        string
+	Password        string
+	Locations       []Location
+	Prefix          string
+	DiscoveryPrefix string
+	Units           weather.Units
+	Interval        time.Duration
+
+	last       map[string]string // Location name to the weather Version last published
+	discovered map[string]bool
+}
+
+// alertsPayload is what's published to a location's alerts topic.
+type alertsPayload struct {
+	Alerts []weather.Alert `json:"alerts"`
+}
+
+// NewPublisher creates a Publisher for the broker at addr that polls every
+// five minutes, publishing under "wthr" in US units with discovery under
+// "homeassistant". Weather comes from the cache through source; alerts come
+// from alertSource so new ones are published without waiting for it to
+// expire.
+func NewPublisher(source WeatherSource, alertSource AlertSource, addr string) *Publisher {
+	return &Publisher{
+		weather:         source,
+		alerts:          alertSource,
+		Addr:            addr,
+		ClientID:        "wthr",
+		Prefix:          "wthr",
+		DiscoveryPrefix: "homeassistant",
+		Units:           weather.UnitsUS,
+		Interval:        5 * time.Minute,
+		last:            make(map[string]string),
+		discovered:      make(map[string]bool),
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// message is one retained publish.
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// Poll fetches the weather for each location and publishes what has
+// changed since the last poll.
+func (p *Publisher) Poll() {
+	var msgs []message
+	published := make(map[string]string)
+	for _, loc := range p.Locations {
+		wd, err := p.current(loc)
+		if err != nil {
+			log.Printf("MQTT: failed to get weather for %s: %v", loc.Name, err)
+			continue
+		}
+		version := wd.Version()
+		if p.last[loc.Name] == version {
+			continue
+		}
+
+		locMsgs, err := p.messages(loc, wd.WithUnits(p.Units))
+		if err != nil {
+			log.Printf("MQTT: failed to encode %s: %v", loc.Name, err)
+			continue
+		}
+		msgs = append(msgs, locMsgs...)
+		published[loc.Name] = version
+	}
+	if len(msgs) == 0 {
+		return
+	}
+
+	if err := p.publish(msgs); err != nil {
+		log.Printf("MQTT: %v", err)
+		return
+	}
+	for name, version := range published {
+		p.last[name] = version
+		p.discovered[name] = true
+	}
+}
+
+// current returns the cached weather for loc with fresh alerts.
+func (p *Publisher) current(loc Location) (*weather.WeatherData, error) {
+	wd, err := p.weather.GetWeather(loc.Latitude, loc.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	if p.alerts != nil {
+		alerts, err := p.alerts.GetAlerts(loc.Latitude, loc.Longitude)
+		if err != nil {
+			// Keep the cached alerts rather than dropping them.
+			log.Printf("MQTT: failed to get alerts for %s: %v", loc.Name, err)
+		} else {
+			fresh := *wd
+			fresh.Alerts = alerts
+			wd = &fresh
+		}
+	}
+	return wd, nil
+}
+
+// messages returns the discovery configs, if not yet sent, and state for
+// one location.
+func (p *Publisher) messages(loc Location, wd *weather.WeatherData) ([]message, error) {
+	current := wd.HomeAssistant()
+	var msgs []message
+	if p.DiscoveryPrefix != "" && !p.discovered[loc.Name] {
+		for _, s := range p.discovery(loc, current) {
+			payload, err := json.Marshal(s)
+			if err != nil {
+				return nil, err
+			}
+			topic := fmt.Sprintf("%s/sensor/wthr_%s/%s/config", p.DiscoveryPrefix, loc.Name, s.key)
+			msgs = append(msgs, message{topic, payload})
+		}
+	}
+
+	payload, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, message{p.topic(loc, "current"), payload})
+
+	active := alertsPayload{Alerts: []weather.Alert{}}
+	for _, a := range wd.Alerts {
+		if a.MessageType != "Cancel" {
+			active.Alerts = append(active.Alerts, a)
+		}
+	}
+	payload, err = json.Marshal(active)
+	if err != nil {
+		return nil, err
+	}
+	return append(msgs, message{p.topic(loc, "alerts"), payload}), nil
+}
+
+func (p *Publisher) topic(loc Location, kind string) string {
+	return p.Prefix + "/" + loc.Name + "/" + kind
+}
+
+// publish sends msgs, retained, over one connection.
+func (p *Publisher) publish(msgs []message) error {
+	c, err := Dial(p.Addr, p.ClientID, p.Username, p.Password)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", p.Addr, err)
+	}
+	for _, m := range msgs {
+		if err := c.Publish(m.topic, m.payload, true); err != nil {
+			c.conn.Close()
+			return fmt.Errorf("failed to publish %s: %w", m.topic, err)
+		}
+	}
+	return c.Close()
+}
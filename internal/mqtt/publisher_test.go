@@ -0,0 +1,121 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type fakeWeather struct {
+	wd *weather.WeatherData
+}
+
+func (f *fakeWeather) GetWeather(lat, lon float64) (*weather.WeatherData, error) {
+	if lat == 0 {
+		return nil, errors.New("upstream error")
+	}
+	return f.wd, nil
+}
+
+type fakeAlerts struct {
+	alerts []weather.Alert
+}
+
+func (f *fakeAlerts) GetAlerts(lat, lon float64) ([]weather.Alert, error) {
+	return f.alerts, nil
+}
+
+func TestParseLocations(t *testing.T) {
+	got, err := ParseLocations(" home=35.47,-97.52; cabin = 36.15, -95.99 ;")
+	if err != nil {
+		t.Fatalf("ParseLocations failed: %v", err)
+	}
+	want := []Location{{"home", 35.47, -97.52}, {"cabin", 36.15, -95.99}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"35.47,-97.52", "Home=35.47,-97.52", "home=95,0", "home=", "a/b=1,1"} {
+		if _, err := ParseLocations(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPublisherPoll(t *testing.T) {
+	b := newTestBroker(t)
+	source := &fakeWeather{wd: &weather.WeatherData{
+		Current: weather.CurrentCondition{
+			Temperature: 68, TemperatureUnit: "F", Icon: "sunny",
+			Wind: weather.Wind{Max: 10, Unit: weather.UnitMPH},
+		},
+	}}
+	alerts := &fakeAlerts{}
+	p := NewPublisher(source, alerts, b.addr())
+	p.Units = weather.UnitsMetric
+	p.Locations = []Location{{"home", 35.47, -97.52}, {"broken", 0, 0}}
+
+	p.Poll()
+	<-b.done
+	msgs := b.published()
+	topics := make(map[string]published)
+	for _, m := range msgs {
+		if !m.retain {
+			t.Errorf("expected %s to be retained", m.topic)
+		}
+		topics[m.topic] = m
+	}
+	if len(msgs) != 8 {
+		t.Fatalf("expected six discovery configs and two states, got %+v", msgs)
+	}
+
+	var current weather.HAWeather
+	if err := json.Unmarshal([]byte(topics["wthr/home/current"].payload), &current); err != nil {
+		t.Fatalf("bad current payload: %v", err)
+	}
+	if current.Condition != "sunny" || current.Temperature != 20 || current.TemperatureUnit != "°C" {
+		t.Errorf("unexpected current payload %+v", current)
+	}
+	if got := topics["wthr/home/alerts"].payload; got != `{"alerts":[]}` {
+		t.Errorf("unexpected alerts payload %s", got)
+	}
+
+	var config sensorConfig
+	if err := json.Unmarshal([]byte(topics["homeassistant/sensor/wthr_home/temperature/config"].payload), &config); err != nil {
+		t.Fatalf("bad discovery payload: %v", err)
+	}
+	if config.UniqueID != "wthr_home_temperature" || config.StateTopic != "wthr/home/current" || config.Unit != "°C" || config.Device.Identifiers[0] != "wthr_home" {
+		t.Errorf("unexpected discovery config %+v", config)
+	}
+
+	// Nothing has changed, so nothing is sent.
+	p.Poll()
+	if n := len(b.published()); n != 8 {
+		t.Errorf("expected no new messages, got %d", n-8)
+	}
+
+	// A new alert republishes the state, without discovery.
+	alerts.alerts = []weather.Alert{{ID: "urn:1", Event: "Tornado Warning"}, {ID: "urn:0", Event: "Flood Warning", MessageType: "Cancel"}}
+	p.Poll()
+	<-b.done
+	msgs = b.published()[8:]
+	if len(msgs) != 2 || msgs[1].topic != "wthr/home/alerts" || !strings.Contains(msgs[1].payload, "Tornado Warning") || strings.Contains(msgs[1].payload, "Flood Warning") {
+		t.Errorf("unexpected messages after a new alert %+v", msgs)
+	}
+}
+
+func TestPublisherPoll_BrokerDown(t *testing.T) {
+	b := newTestBroker(t)
+	b.refuse = 3
+	p := NewPublisher(&fakeWeather{wd: &weather.WeatherData{}}, nil, b.addr())
+	p.Locations = []Location{{"home", 35.47, -97.52}}
+
+	p.Poll()
+	if len(p.last) != 0 || len(p.discovered) != 0 {
+		t.Errorf("expected nothing to be marked published, got %v %v", p.last, p.discovered)
+	}
+}
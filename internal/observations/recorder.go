@@ -0,0 +1,144 @@
+// Package observations records station observations for the almanac.
+package observations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// Store is the observation storage the Recorder needs; *db.DB implements
+// it.
+type Store interface {
+	GetStation(ctx context.Context, id string) (*db.Station, error)
+	SaveStation(st db.Station) error
+	ListStations(ctx context.Context) ([]db.Station, error)
+	RecordObservation(o db.Observation) error
+}
+
+// Source fetches stations and their latest observations; *weather.Service
+// implements it.
+type Source interface {
+	GetStation(id string) (*weather.Station, error)
+	GetObservation(id string) (*weather.Observation, error)
+}
+
+// Recorder periodically records the latest observation from each station in
+// Stations, and from any other station already saved, such as those the
+// forecast accuracy tracker verifies against. Stations report about once an
+// hour, so polling more often only catches the extra reports they send
+// during bad weather.
+type Recorder struct {
+	store    Store
+	source   Source
+	Stations []string
+	Interval time.Duration
+}
+
+// NewRecorder creates a Recorder that polls every ten minutes.
+func NewRecorder(store Store, source Source) *Recorder {
+	return &Recorder{
+		store:    store,
+		source:   source,
+		Interval: 10 * time.Minute,
+	}
+}
+
+// ParseStations parses a comma separated list of station IDs, e.g.
+// "KOKC, KTUL".
+func ParseStations(s string) []string {
+	var stations []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.ToUpper(strings.TrimSpace(id)); id != "" {
+			stations = append(stations, id)
+		}
+	}
+	return stations
+}
+
+// Run polls until ctx is cancelled.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll records the latest observation from each station.
+func (r *Recorder) Poll() {
+	ids := append([]string(nil), r.Stations...)
+	saved, err := r.store.ListStations(context.Background())
+	if err != nil {
+		log.Printf("Observations: failed to list stations: %v", err)
+	}
+	for _, st := range saved {
+		if !slices.Contains(ids, st.ID) {
+			ids = append(ids, st.ID)
+		}
+	}
+
+	for _, id := range ids {
+		if err := r.record(id); err != nil {
+			log.Printf("Observations: %s: %v", id, err)
+		}
+	}
+}
+
+func (r *Recorder) record(id string) error {
+	st, err := r.station(id)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(st.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	obs, err := r.source.GetObservation(id)
+	if err != nil {
+		return fmt.Errorf("failed to get observation: %w", err)
+	}
+	return r.store.RecordObservation(db.Observation{
+		Station:        st.ID,
+		Time:           obs.Time,
+		LocalDate:      obs.Time.In(loc).Format("2006-01-02"),
+		Temperature:    obs.Temperature,
+		PrecipLastHour: obs.PrecipLastHour,
+		Description:    obs.Description,
+	})
+}
+
+// station returns a station's metadata, fetching and saving it the first
+// time the station is recorded.
+func (r *Recorder) station(id string) (*db.Station, error) {
+	st, err := r.store.GetStation(context.Background(), id)
+	if err != nil || st != nil {
+		return st, err
+	}
+
+	ws, err := r.source.GetStation(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get station: %w", err)
+	}
+	st = &db.Station{ID: strings.ToUpper(id), Name: ws.Name, TimeZone: ws.TimeZone}
+	if st.TimeZone == "" {
+		st.TimeZone = "UTC"
+	}
+	if err := r.store.SaveStation(*st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
@@ -0,0 +1,110 @@
+package observations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type memoryStore struct {
+	stations map[string]db.Station
+	obs      []db.Observation
+}
+
+func (m *memoryStore) GetStation(ctx context.Context, id string) (*db.Station, error) {
+	if st, ok := m.stations[id]; ok {
+		return &st, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) SaveStation(st db.Station) error {
+	m.stations[st.ID] = st
+	return nil
+}
+
+func (m *memoryStore) ListStations(ctx context.Context) ([]db.Station, error) {
+	var stations []db.Station
+	for _, st := range m.stations {
+		stations = append(stations, st)
+	}
+	return stations, nil
+}
+
+func (m *memoryStore) RecordObservation(o db.Observation) error {
+	m.obs = append(m.obs, o)
+	return nil
+}
+
+type fakeSource struct {
+	stationCalls int
+}
+
+func (f *fakeSource) GetStation(id string) (*weather.Station, error) {
+	f.stationCalls++
+	if id == "KBAD" {
+		return nil, errors.New("not found")
+	}
+	return &weather.Station{ID: id, Name: "Oklahoma City", TimeZone: "America/Chicago"}, nil
+}
+
+func (f *fakeSource) GetObservation(id string) (*weather.Observation, error) {
+	temp := 18.3
+	return &weather.Observation{
+		Station:     id,
+		Time:        time.Date(2025, 5, 6, 3, 53, 0, 0, time.UTC),
+		Temperature: &temp,
+		Description: "Clear",
+	}, nil
+}
+
+func TestParseStations(t *testing.T) {
+	got := ParseStations(" kokc, KTUL ,,")
+	if !reflect.DeepEqual(got, []string{"KOKC", "KTUL"}) {
+		t.Errorf("unexpected stations %v", got)
+	}
+}
+
+func TestRecorderPoll(t *testing.T) {
+	store := &memoryStore{stations: map[string]db.Station{}}
+	source := &fakeSource{}
+	r := NewRecorder(store, source)
+	r.Stations = []string{"KOKC", "KBAD"}
+
+	r.Poll()
+	r.Poll()
+
+	if len(store.obs) != 2 {
+		t.Fatalf("expected two recorded observations, got %+v", store.obs)
+	}
+	o := store.obs[0]
+	// 03:53 UTC is still the previous evening in Chicago.
+	if o.Station != "KOKC" || o.LocalDate != "2025-05-05" || *o.Temperature != 18.3 {
+		t.Errorf("unexpected observation %+v", o)
+	}
+	if store.stations["KOKC"].TimeZone != "America/Chicago" {
+		t.Errorf("expected the station to be saved, got %+v", store.stations)
+	}
+	// KOKC is fetched once; KBAD is retried each poll.
+	if source.stationCalls != 3 {
+		t.Errorf("expected 3 station lookups, got %d", source.stationCalls)
+	}
+}
+
+func TestRecorderPoll_SavedStations(t *testing.T) {
+	store := &memoryStore{stations: map[string]db.Station{
+		"KTUL": {ID: "KTUL", Name: "Tulsa", TimeZone: "America/Chicago"},
+	}}
+	r := NewRecorder(store, &fakeSource{})
+
+	r.Poll()
+
+	if len(store.obs) != 1 || store.obs[0].Station != "KTUL" {
+		t.Errorf("expected the saved station to be recorded, got %+v", store.obs)
+	}
+}
@@ -0,0 +1,104 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// recordSize is the aes128gcm record size. Payloads are sent as a single
+	// record, so it also bounds the payload size.
+	recordSize = 4096
+	// headerSize is salt (16) + rs (4) + idlen (1) + uncompressed key (65).
+	headerSize = 16 + 4 + 1 + 65
+	// MaxPayloadSize is the largest payload that fits in one record.
+	MaxPayloadSize = recordSize - headerSize - 16 - 1
+)
+
+// encrypt encrypts payload for a subscription's p256dh public key and auth
+// secret as described in RFC 8291, returning an aes128gcm message body.
+func encrypt(payload, uaPublic, authSecret []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, fmt.Errorf("payload too large: %d bytes", len(payload))
+	}
+
+	curve := ecdh.P256()
+	uaKey, err := curve.NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %w", err)
+	}
+	asKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	cek, nonce, err := deriveKeys(secret, authSecret, salt, uaPublic, asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// A single, final record: the payload followed by the 0x02 delimiter.
+	record := make([]byte, 0, len(payload)+1)
+	record = append(record, payload...)
+	record = append(record, 0x02)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// deriveKeys derives the content encryption key and nonce from the ECDH
+// shared secret. It is shared by both ends of the exchange.
+func deriveKeys(secret, authSecret, salt, uaPublic, asPublic []byte) ([]byte, []byte, error) {
+	keyInfo := make([]byte, 0, 14+len(uaPublic)+len(asPublic))
+	keyInfo = append(keyInfo, "WebPush: info\x00"...)
+	keyInfo = append(keyInfo, uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+
+	ikm, err := hkdf.Key(sha256.New, secret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
@@ -0,0 +1,169 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+// Store is the subscription storage the Poller needs; *db.DB implements it.
+type Store interface {
+	ListPushSubscriptions() ([]db.PushSubscription, error)
+	SetPushSeenAlerts(id int64, alertIDs []string) error
+	DeletePushSubscription(ctx context.Context, endpoint string) error
+}
+
+// AlertSource fetches active alerts for a point; *weather.ActiveAlerts and
+// *weather.Service implement it.
+type AlertSource interface {
+	GetAlerts(lat, lon float64) ([]weather.Alert, error)
+}
+
+// Message is the JSON payload sw.js turns into a notification.
+type Message struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+	Tag   string `json:"tag"` // Alert series, so updates replace the earlier notification
+}
+
+// Poller periodically checks alerts for every subscribed location and pushes
+// the ones a subscriber has not seen yet.
+type Poller struct {
+	store    Store
+	alerts   AlertSource
+	sender   *Sender
+	Interval time.Duration
+}
+
+// NewPoller creates a Poller that checks every five minutes.
+func NewPoller(store Store, alerts AlertSource, sender *Sender) *Poller {
+	return &Poller{
+		store:    store,
+		alerts:   alerts,
+		sender:   sender,
+		Interval: 5 * time.Minute,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(); err != nil {
+			log.Printf("Push poll error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll runs one pass over all subscriptions. Subscriptions are grouped by
+// the same rounded coordinates the weather cache uses so each location is
+// fetched from NWS once.
+func (p *Poller) Poll() error {
+	subs, err := p.store.ListPushSubscriptions()
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	groups := make(map[string][]db.PushSubscription)
+	var keys []string
+	for _, sub := range subs {
+		key := fmt.Sprintf("%.2f,%.2f", sub.Latitude, sub.Longitude)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], sub)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		alerts, err := p.alerts.GetAlerts(group[0].Latitude, group[0].Longitude)
+		if err != nil {
+			// Leave seen alerts alone so nothing is lost; retry next poll.
+			log.Printf("Push poll: failed to get alerts for %s: %v", key, err)
+			continue
+		}
+		for _, sub := range group {
+			p.notify(sub, alerts)
+		}
+	}
+	return nil
+}
+
+// notify pushes the alerts sub has not seen and records the current set.
+func (p *Poller) notify(sub db.PushSubscription, alerts []weather.Alert) {
+	seen := make(map[string]bool, len(sub.SeenAlerts))
+	for _, id := range sub.SeenAlerts {
+		seen[id] = true
+	}
+
+	current := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		current = append(current, a.ID)
+		if seen[a.ID] || !notifiable(a) {
+			continue
+		}
+
+		err := p.send(sub, a)
+		if errors.Is(err, ErrGone) {
+			if err := p.store.DeletePushSubscription(context.Background(), sub.Endpoint); err != nil {
+				log.Printf("Failed to delete expired push subscription: %v", err)
+			}
+			return
+		}
+		if err != nil {
+			// Don't mark it seen so delivery is retried next poll.
+			log.Printf("Push delivery error: %v", err)
+			current = current[:len(current)-1]
+		}
+	}
+
+	if err := p.store.SetPushSeenAlerts(sub.ID, current); err != nil {
+		log.Printf("Failed to record seen alerts: %v", err)
+	}
+}
+
+func (p *Poller) send(sub db.PushSubscription, a weather.Alert) error {
+	body := a.Headline
+	if body == "" {
+		body = a.AreaDesc
+	}
+	tag := a.Series
+	if tag == "" {
+		tag = a.ID
+	}
+	payload, err := json.Marshal(Message{
+		Title: a.Event,
+		Body:  body,
+		URL:   fmt.Sprintf("/w/%.2f,%.2f", sub.Latitude, sub.Longitude),
+		Tag:   tag,
+	})
+	if err != nil {
+		return err
+	}
+
+	urgency := "normal"
+	if a.Severity == "Extreme" {
+		urgency = "high"
+	}
+	return p.sender.Send(Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth}, payload, urgency, 6*time.Hour)
+}
+
+// notifiable reports whether an alert is serious enough to push. Advisories
+// and statements would be too noisy as notifications.
+func notifiable(a weather.Alert) bool {
+	return a.ID != "" && weather.SeverityRank(a.Severity) >= weather.SeverityRank("Severe")
+}
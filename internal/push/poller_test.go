@@ -0,0 +1,144 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type memoryStore struct {
+	subs []db.PushSubscription
+}
+
+func (m *memoryStore) ListPushSubscriptions() ([]db.PushSubscription, error) {
+	return append([]db.PushSubscription(nil), m.subs...), nil
+}
+
+func (m *memoryStore) SetPushSeenAlerts(id int64, alertIDs []string) error {
+	for i := range m.subs {
+		if m.subs[i].ID == id {
+			m.subs[i].SeenAlerts = alertIDs
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	for i := range m.subs {
+		if m.subs[i].Endpoint == endpoint {
+			m.subs = append(m.subs[:i], m.subs[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+type fakeAlerts struct {
+	alerts []weather.Alert
+	err    error
+	calls  int
+}
+
+func (f *fakeAlerts) GetAlerts(lat, lon float64) ([]weather.Alert, error) {
+	f.calls++
+	return f.alerts, f.err
+}
+
+func newTestPoller(t *testing.T) (*Poller, *memoryStore, *fakeAlerts, *pushService) {
+	t.Helper()
+	keys := testKeys(t)
+	ps := newPushService(t, keys.PublicKey)
+	sub := ps.subscription()
+
+	store := &memoryStore{subs: []db.PushSubscription{
+		{ID: 1, Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth, Latitude: 35.4676, Longitude: -97.5164},
+		{ID: 2, Endpoint: sub.Endpoint + "b", P256dh: sub.P256dh, Auth: sub.Auth, Latitude: 35.4712, Longitude: -97.5199},
+	}}
+	alerts := &fakeAlerts{}
+	return NewPoller(store, alerts, ps.sender(keys)), store, alerts, ps
+}
+
+func TestPoll_NotifiesNewSevereAlerts(t *testing.T) {
+	p, store, alerts, ps := newTestPoller(t)
+	alerts.alerts = []weather.Alert{
+		{ID: "urn:oid:1", Series: "KOUN.TO.W.0042.2025", Event: "Tornado Warning", Headline: "Tornado Warning until 5 PM", Severity: "Extreme"},
+		{ID: "urn:oid:2", Event: "Wind Advisory", Severity: "Minor"},
+	}
+
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if alerts.calls != 1 {
+		t.Errorf("expected nearby subscriptions to share one alerts lookup, got %d", alerts.calls)
+	}
+
+	msgs := ps.received()
+	if len(msgs) != 2 {
+		t.Fatalf("expected one message per subscription, got %d", len(msgs))
+	}
+	var msg Message
+	if err := json.Unmarshal(msgs[0].payload, &msg); err != nil {
+		t.Fatalf("bad payload: %v", err)
+	}
+	want := Message{Title: "Tornado Warning", Body: "Tornado Warning until 5 PM", URL: "/w/35.47,-97.52", Tag: "KOUN.TO.W.0042.2025"}
+	if msg != want {
+		t.Errorf("expected %+v, got %+v", want, msg)
+	}
+	if msgs[0].header.Get("Urgency") != "high" {
+		t.Errorf("expected high urgency for extreme alert")
+	}
+	if len(store.subs[0].SeenAlerts) != 2 {
+		t.Errorf("expected all active alerts to be recorded as seen, got %v", store.subs[0].SeenAlerts)
+	}
+
+	// Nothing new: nothing sent
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(ps.received()) != 2 {
+		t.Errorf("expected no repeat notifications, got %d messages", len(ps.received()))
+	}
+
+	// A new alert is sent once
+	alerts.alerts = append(alerts.alerts, weather.Alert{ID: "urn:oid:3", Event: "Severe Thunderstorm Warning", Severity: "Severe"})
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(ps.received()) != 4 {
+		t.Errorf("expected new alert to be sent to both subscriptions, got %d messages", len(ps.received()))
+	}
+}
+
+func TestPoll_UpstreamErrorKeepsSeen(t *testing.T) {
+	p, store, alerts, ps := newTestPoller(t)
+	store.subs[0].SeenAlerts = []string{"urn:oid:1"}
+	alerts.err = errors.New("NWS API error")
+
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(ps.received()) != 0 {
+		t.Errorf("expected nothing sent")
+	}
+	if len(store.subs[0].SeenAlerts) != 1 {
+		t.Errorf("expected seen alerts to be left alone, got %v", store.subs[0].SeenAlerts)
+	}
+}
+
+func TestPoll_RemovesGoneSubscriptions(t *testing.T) {
+	p, store, alerts, ps := newTestPoller(t)
+	ps.status = http.StatusGone
+	alerts.alerts = []weather.Alert{{ID: "urn:oid:1", Event: "Tornado Warning", Severity: "Extreme"}}
+
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(store.subs) != 0 {
+		t.Errorf("expected expired subscriptions to be deleted, got %+v", store.subs)
+	}
+}
@@ -0,0 +1,274 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pushService is a local stand-in for a browser vendor's push service. It
+// plays the user agent's side: it owns the subscription keys, checks the
+// VAPID signature and decrypts what it receives.
+type pushService struct {
+	t        *testing.T
+	server   *httptest.Server
+	key      *ecdh.PrivateKey
+	auth     []byte
+	vapidKey string
+	status   int
+
+	mu       sync.Mutex
+	messages []received
+}
+
+type received struct {
+	payload []byte
+	header  http.Header
+}
+
+func newPushService(t *testing.T, vapidKey string) *pushService {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := &pushService{t: t, key: key, auth: make([]byte, 16), vapidKey: vapidKey, status: http.StatusCreated}
+	rand.Read(ps.auth)
+	ps.server = httptest.NewTLSServer(http.HandlerFunc(ps.handle))
+	t.Cleanup(ps.server.Close)
+	return ps
+}
+
+func (ps *pushService) subscription() Subscription {
+	return Subscription{
+		Endpoint: ps.server.URL + "/push/device1",
+		P256dh:   encode(ps.key.PublicKey().Bytes()),
+		Auth:     encode(ps.auth),
+	}
+}
+
+func (ps *pushService) sender(keys *VAPIDKeys) *Sender {
+	s := NewSender(keys, "mailto:ops@wthr.example")
+	s.HTTPClient = ps.server.Client()
+	return s
+}
+
+func (ps *pushService) handle(w http.ResponseWriter, r *http.Request) {
+	if err := ps.verifyVAPID(r); err != nil {
+		ps.t.Errorf("VAPID verification failed: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Encoding") != "aes128gcm" {
+		ps.t.Errorf("unexpected Content-Encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	payload, err := ps.decrypt(body)
+	if err != nil {
+		ps.t.Errorf("decrypt failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ps.mu.Lock()
+	ps.messages = append(ps.messages, received{payload: payload, header: r.Header.Clone()})
+	ps.mu.Unlock()
+	w.WriteHeader(ps.status)
+}
+
+func (ps *pushService) verifyVAPID(r *http.Request) error {
+	var token, k string
+	for _, part := range strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "vapid "), ", ") {
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			token = v
+		}
+		if v, ok := strings.CutPrefix(part, "k="); ok {
+			k = v
+		}
+	}
+	if k != ps.vapidKey {
+		return fmt.Errorf("unexpected key %q", k)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed token")
+	}
+	pub, _ := decode(k)
+	x, y := elliptic.Unmarshal(elliptic.P256(), pub)
+	sig, _ := decode(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if len(sig) != 64 || !ecdsa.Verify(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, digest[:],
+		new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return fmt.Errorf("bad signature")
+	}
+
+	claimsJSON, _ := decode(parts[1])
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return err
+	}
+	if claims.Aud != ps.server.URL {
+		return fmt.Errorf("unexpected audience %q", claims.Aud)
+	}
+	if claims.Exp <= time.Now().Unix() || claims.Sub == "" {
+		return fmt.Errorf("bad claims %+v", claims)
+	}
+	return nil
+}
+
+func (ps *pushService) decrypt(body []byte) ([]byte, error) {
+	if len(body) < headerSize {
+		return nil, fmt.Errorf("short body")
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		return nil, fmt.Errorf("unexpected record size %d", rs)
+	}
+	idlen := int(body[20])
+	asPublic := body[21 : 21+idlen]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ps.key.ECDH(asKey)
+	if err != nil {
+		return nil, err
+	}
+	cek, nonce, err := deriveKeys(secret, ps.auth, salt, ps.key.PublicKey().Bytes(), asPublic)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plain, err := gcm.Open(nil, nonce, body[21+idlen:], nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(plain) == 0 || plain[len(plain)-1] != 0x02 {
+		return nil, fmt.Errorf("missing last record delimiter")
+	}
+	return plain[:len(plain)-1], nil
+}
+
+func (ps *pushService) received() []received {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return append([]received(nil), ps.messages...)
+}
+
+func testKeys(t *testing.T) *VAPIDKeys {
+	t.Helper()
+	pub, priv, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys failed: %v", err)
+	}
+	keys, err := ParseVAPIDKeys(pub, priv)
+	if err != nil {
+		t.Fatalf("ParseVAPIDKeys failed: %v", err)
+	}
+	return keys
+}
+
+func TestParseVAPIDKeys_Mismatch(t *testing.T) {
+	pub, _, _ := GenerateVAPIDKeys()
+	_, priv, _ := GenerateVAPIDKeys()
+	if _, err := ParseVAPIDKeys(pub, priv); err == nil {
+		t.Error("expected error for mismatched key pair")
+	}
+	if _, err := ParseVAPIDKeys("", "not-a-key"); err == nil {
+		t.Error("expected error for invalid private key")
+	}
+}
+
+func TestSend(t *testing.T) {
+	keys := testKeys(t)
+	ps := newPushService(t, keys.PublicKey)
+
+	err := ps.sender(keys).Send(ps.subscription(), []byte(`{"title":"Tornado Warning"}`), "high", time.Hour)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	msgs := ps.received()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	if string(msgs[0].payload) != `{"title":"Tornado Warning"}` {
+		t.Errorf("unexpected payload %q", msgs[0].payload)
+	}
+	if msgs[0].header.Get("Urgency") != "high" || msgs[0].header.Get("TTL") != "3600" {
+		t.Errorf("unexpected headers %v", msgs[0].header)
+	}
+}
+
+func TestSend_Gone(t *testing.T) {
+	keys := testKeys(t)
+	ps := newPushService(t, keys.PublicKey)
+	ps.status = http.StatusGone
+
+	err := ps.sender(keys).Send(ps.subscription(), []byte("hi"), "", time.Hour)
+	if err != ErrGone {
+		t.Errorf("expected ErrGone, got %v", err)
+	}
+}
+
+func TestSend_PayloadTooLarge(t *testing.T) {
+	keys := testKeys(t)
+	ps := newPushService(t, keys.PublicKey)
+
+	err := ps.sender(keys).Send(ps.subscription(), make([]byte, MaxPayloadSize+1), "", time.Hour)
+	if err == nil {
+		t.Error("expected error for oversized payload")
+	}
+	if len(ps.received()) != 0 {
+		t.Error("expected nothing to be sent")
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	keys := testKeys(t)
+	ps := newPushService(t, keys.PublicKey)
+	valid := ps.subscription()
+
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid subscription, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		sub  Subscription
+	}{
+		{"http endpoint", Subscription{Endpoint: "http://push.example/x", P256dh: valid.P256dh, Auth: valid.Auth}},
+		{"short key", Subscription{Endpoint: valid.Endpoint, P256dh: encode([]byte("short")), Auth: valid.Auth}},
+		{"bad auth", Subscription{Endpoint: valid.Endpoint, P256dh: valid.P256dh, Auth: "!!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sub.Validate(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
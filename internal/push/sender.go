@@ -0,0 +1,112 @@
+package push
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrGone is returned by Send when the push service reports that the
+// subscription no longer exists and should be deleted.
+var ErrGone = errors.New("push subscription expired or unsubscribed")
+
+// Subscription is the part of a browser PushSubscription needed to deliver
+// a message.
+type Subscription struct {
+	Endpoint string
+	P256dh   string // base64url encoded P-256 public key
+	Auth     string // base64url encoded 16 byte auth secret
+}
+
+// Validate checks that the subscription can be delivered to.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("endpoint must be an https URL")
+	}
+	if key, err := decode(s.P256dh); err != nil || len(key) != 65 {
+		return fmt.Errorf("invalid p256dh key")
+	}
+	if auth, err := decode(s.Auth); err != nil || len(auth) != 16 {
+		return fmt.Errorf("invalid auth secret")
+	}
+	return nil
+}
+
+// Sender delivers encrypted messages to push services.
+type Sender struct {
+	keys       *VAPIDKeys
+	subject    string
+	HTTPClient *http.Client
+}
+
+// NewSender creates a Sender. subject is the contact URI (mailto: or https:)
+// sent to push services in the VAPID claims.
+func NewSender(keys *VAPIDKeys, subject string) *Sender {
+	return &Sender{
+		keys:    keys,
+		subject: subject,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Send encrypts payload and posts it to the subscription's push service.
+// urgency is one of "very-low", "low", "normal" or "high"; ttl is how long
+// the push service should hold the message for an offline device.
+func (s *Sender) Send(sub Subscription, payload []byte, urgency string, ttl time.Duration) error {
+	uaPublic, err := decode(sub.P256dh)
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decode(sub.Auth)
+	if err != nil {
+		return fmt.Errorf("invalid auth secret: %w", err)
+	}
+	body, err := encrypt(payload, uaPublic, authSecret)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	auth, err := s.keys.authorization(endpoint.Scheme+"://"+endpoint.Host, s.subject, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	if urgency != "" {
+		req.Header.Set("Urgency", urgency)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("push service error: %d %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
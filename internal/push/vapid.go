@@ -0,0 +1,103 @@
+// Package push delivers Web Push notifications (RFC 8030) with VAPID
+// authentication (RFC 8292) and aes128gcm payload encryption (RFC 8291).
+package push
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// VAPIDKeys is the application server key pair used to sign push requests.
+type VAPIDKeys struct {
+	// PublicKey is the uncompressed P-256 public key, base64url encoded. It is
+	// handed to the browser as the applicationServerKey.
+	PublicKey string
+	private   *ecdsa.PrivateKey
+}
+
+// GenerateVAPIDKeys creates a new key pair and returns the public and
+// private keys base64url encoded, the format ParseVAPIDKeys expects.
+func GenerateVAPIDKeys() (string, string, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return encode(key.PublicKey().Bytes()), encode(key.Bytes()), nil
+}
+
+// ParseVAPIDKeys parses a base64url encoded key pair as produced by
+// GenerateVAPIDKeys (or the usual web-push tooling).
+func ParseVAPIDKeys(publicKey, privateKey string) (*VAPIDKeys, error) {
+	raw, err := decode(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	pub := key.PublicKey().Bytes()
+	if publicKey != "" {
+		given, err := decode(publicKey)
+		if err != nil || string(given) != string(pub) {
+			return nil, fmt.Errorf("VAPID public key does not match private key")
+		}
+	}
+
+	return &VAPIDKeys{
+		PublicKey: encode(pub),
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(pub[1:33]),
+				Y:     new(big.Int).SetBytes(pub[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+	}, nil
+}
+
+// authorization returns the VAPID Authorization header value for a push
+// service origin (scheme://host of the subscription endpoint).
+func (k *VAPIDKeys) authorization(audience, subject string, now time.Time) (string, error) {
+	header := encode([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": audience,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := header + "." + encode(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, encode(sig), k.PublicKey), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode accepts base64url with or without padding, as browsers differ.
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
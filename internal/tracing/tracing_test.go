@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), &buf, "console")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	_, span := Start(context.Background(), "test span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	for _, want := range []string{`"Name":"test span"`, `"Value":"wthr"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in %q", want, buf.String())
+		}
+	}
+
+	if _, err := Setup(context.Background(), &buf, "none"); err != nil {
+		t.Errorf("expected none to be accepted: %v", err)
+	}
+	if _, err := Setup(context.Background(), &buf, "zipkin"); err == nil {
+		t.Errorf("expected an unknown exporter to fail")
+	}
+}
+
+func TestEnd(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("upstream down"))
+	End(parent, nil)
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "child" || spans[0].Status.Code != codes.Error || spans[0].Status.Description != "upstream down" {
+		t.Errorf("unexpected child span %+v", spans[0])
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("expected child to be parented by parent")
+	}
+	if spans[1].Status.Code != codes.Unset {
+		t.Errorf("expected parent status unset, got %v", spans[1].Status)
+	}
+}
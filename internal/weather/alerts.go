@@ -0,0 +1,377 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+)
+
+// severityRanks orders the CAP severities NWS uses, from least to most severe.
+var severityRanks = map[string]int{
+	"unknown":  0,
+	"minor":    1,
+	"moderate": 2,
+	"severe":   3,
+	"extreme":  4,
+}
+
+// SeverityRank returns the rank of a CAP severity ("Minor" through
+// "Extreme"), or -1 if it isn't one. Unknown ranks below Minor.
+func SeverityRank(severity string) int {
+	if r, ok := severityRanks[strings.ToLower(severity)]; ok {
+		return r
+	}
+	return -1
+}
+
+// IsUpdate reports whether the alert replaces an earlier version of itself.
+func (a Alert) IsUpdate() bool {
+	return a.MessageType == "Update" || a.MessageType == "Cancel" || len(a.References) > 0
+}
+
+// AlertIndex is a snapshot of active alerts that can be matched against any
+// point locally.
+type AlertIndex struct {
+	Fetched time.Time
+	alerts  []indexedAlert
+	// unresolved maps the IDs of alerts issued for zones whose polygons
+	// couldn't be loaded, which Match can't place, to those zones' UGC codes.
+	unresolved map[string][]string
+}
+
+// indexedAlert is an alert with the areas it covers: its own polygon, or
+// the polygons of the zones it was issued for.
+type indexedAlert struct {
+	alert Alert
+	areas []*Geometry
+	ugc   []string
+}
+
+// NewAlertIndex indexes a set of alerts. Alerts without their own geometry
+// cover the polygons of their affected zones found in zones, keyed by zone
+// URL; those with a zone missing from zones are marked unresolved.
+func NewAlertIndex(al *AlertsResponse, zones map[string]*Geometry) *AlertIndex {
+	idx := &AlertIndex{Fetched: time.Now(), unresolved: make(map[string][]string)}
+	for i, alert := range al.Alerts() {
+		f := al.Features[i]
+		ia := indexedAlert{alert: alert, ugc: f.Properties.Geocode.UGC}
+		if f.Geometry != nil && len(f.Geometry.polygons) > 0 {
+			ia.areas = []*Geometry{f.Geometry}
+		} else {
+			for _, z := range f.Properties.AffectedZones {
+				if g := zones[z]; g != nil {
+					ia.areas = append(ia.areas, g)
+				} else {
+					idx.unresolved[alert.ID] = append(idx.unresolved[alert.ID], path.Base(z))
+				}
+			}
+		}
+		idx.alerts = append(idx.alerts, ia)
+	}
+	return idx
+}
+
+// Match returns the alerts whose area contains the point.
+func (idx *AlertIndex) Match(lat, lon float64) []Alert {
+	alerts := []Alert{}
+	for _, ia := range idx.alerts {
+		for _, g := range ia.areas {
+			if g.Contains(lat, lon) {
+				alerts = append(alerts, ia.alert)
+				break
+			}
+		}
+	}
+	return alerts
+}
+
+// MayMiss reports whether the point could be inside an alert that Match
+// can't place, judging by the bounds of the states or marine areas of its
+// unresolved zones.
+func (idx *AlertIndex) MayMiss(lat, lon float64) bool {
+	for _, codes := range idx.unresolved {
+		for _, code := range codes {
+			if ugcMayContain(code, lat, lon) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InArea returns the alerts issued for zones or counties in a state or
+// marine area, identified by the two letter prefix of their UGC codes.
+func (idx *AlertIndex) InArea(area string) []Alert {
+	area = strings.ToUpper(area)
+	alerts := []Alert{}
+	for _, ia := range idx.alerts {
+		for _, code := range ia.ugc {
+			if strings.HasPrefix(code, area) {
+				alerts = append(alerts, ia.alert)
+				break
+			}
+		}
+	}
+	return alerts
+}
+
+// ActiveAlerts answers point and area alert lookups from a single
+// nationwide fetch of active alerts, refreshed at most once per TTL. Alerts
+// issued by zone are resolved to zone polygons, which are cached in memory
+// and in the database since they rarely change. While a refresh runs, or
+// after one fails, the previous snapshot is served.
+type ActiveAlerts struct {
+	client *Client
+	db     *db.DB
+	TTL    time.Duration
+	// ZoneMaxAge is how long a cached zone polygon is trusted.
+	ZoneMaxAge time.Duration
+
+	mu      sync.Mutex
+	index   *AlertIndex
+	checked time.Time // When a refresh was last tried
+
+	// refresh is held while refreshing, and guards zones and zoneFailures.
+	refresh      sync.Mutex
+	zones        map[string]*Geometry
+	zoneFailures map[string]zoneFailure
+}
+
+// zoneFailure is a zone whose polygon couldn't be fetched, and when to try
+// again.
+type zoneFailure struct {
+	count   int
+	retryAt time.Time
+}
+
+const (
+	// zoneConcurrency limits parallel zone fetches when many zones are
+	// uncached.
+	zoneConcurrency = 4
+	// zoneRetryDelay is the wait before refetching a failed zone; it doubles
+	// with each failure up to zoneRetryMax.
+	zoneRetryDelay = time.Minute
+	zoneRetryMax   = time.Hour
+)
+
+// NewActiveAlerts creates an ActiveAlerts backed by the service's client and
+// database.
+func (s *Service) NewActiveAlerts(ttl time.Duration) *ActiveAlerts {
+	return &ActiveAlerts{
+		client:       s.client,
+		db:           s.db,
+		TTL:          ttl,
+		ZoneMaxAge:   30 * 24 * time.Hour,
+		zones:        make(map[string]*Geometry),
+		zoneFailures: make(map[string]zoneFailure),
+	}
+}
+
+// GetAlerts returns the active alerts covering a point. If the point could
+// be in a zone that couldn't be resolved, NWS is asked for the point's
+// alerts too so those aren't missed; if that fails, the alerts matched
+// locally are still returned.
+func (a *ActiveAlerts) GetAlerts(lat, lon float64) ([]Alert, error) {
+	return a.GetAlertsContext(context.Background(), lat, lon)
+}
+
+// GetAlertsContext is GetAlerts for a request.
+func (a *ActiveAlerts) GetAlertsContext(ctx context.Context, lat, lon float64) ([]Alert, error) {
+	idx, err := a.IndexContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	alerts := idx.Match(lat, lon)
+	if !idx.MayMiss(lat, lon) {
+		return alerts, nil
+	}
+
+	al, err := a.client.GetAlerts(ctx, lat, lon)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get alerts for unresolved zones", "lat", lat, "lon", lon, "error", err)
+		return alerts, nil
+	}
+	matched := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		matched[alert.ID] = true
+	}
+	for _, alert := range al.Alerts() {
+		if len(idx.unresolved[alert.ID]) > 0 && !matched[alert.ID] {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+
+// GetAlertsByArea returns the active alerts for a state or marine area code.
+func (a *ActiveAlerts) GetAlertsByArea(area string) ([]Alert, error) {
+	return a.GetAlertsByAreaContext(context.Background(), area)
+}
+
+// GetAlertsByAreaContext is GetAlertsByArea for a request.
+func (a *ActiveAlerts) GetAlertsByAreaContext(ctx context.Context, area string) ([]Alert, error) {
+	idx, err := a.IndexContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return idx.InArea(area), nil
+}
+
+// Index returns the current snapshot, refreshing it if it is older than
+// TTL. The refresh happens outside the lock: while it runs, other callers
+// get the previous snapshot, and if it fails that snapshot is kept until
+// the next TTL. Only the first fetch, with no snapshot to fall back on,
+// makes callers wait or returns an error.
+func (a *ActiveAlerts) Index() (*AlertIndex, error) {
+	return a.IndexContext(context.Background())
+}
+
+// IndexContext is Index for a request. A refresh it starts is traced and
+// logged as part of the request, but isn't cut short if the request is
+// cancelled, since every caller shares its result.
+func (a *ActiveAlerts) IndexContext(ctx context.Context) (*AlertIndex, error) {
+	idx, current := a.snapshot()
+	if current {
+		return idx, nil
+	}
+	if idx != nil {
+		if !a.refresh.TryLock() {
+			return idx, nil // Another caller is refreshing
+		}
+	} else {
+		a.refresh.Lock()
+	}
+	defer a.refresh.Unlock()
+
+	// A refresh may have finished while this caller waited for the lock.
+	if idx, current = a.snapshot(); current {
+		return idx, nil
+	}
+
+	a.mu.Lock()
+	a.checked = time.Now()
+	a.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	al, err := a.client.GetAllAlerts(ctx)
+	if err != nil {
+		if idx != nil {
+			slog.WarnContext(ctx, "Failed to refresh active alerts, keeping the previous snapshot", "fetched", idx.Fetched, "error", err)
+			return idx, nil
+		}
+		return nil, fmt.Errorf("failed to get active alerts: %w", err)
+	}
+	a.resolveZones(ctx, al)
+	idx = NewAlertIndex(al, a.zones)
+
+	a.mu.Lock()
+	a.index = idx
+	a.mu.Unlock()
+	return idx, nil
+}
+
+// snapshot returns the current index, and whether it's new enough to use
+// without refreshing.
+func (a *ActiveAlerts) snapshot() (*AlertIndex, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.index, a.index != nil && time.Since(a.checked) < a.TTL
+}
+
+// resolveZones loads the polygons of every zone referenced by an alert
+// without its own geometry into a.zones. Zones that recently failed to load
+// are skipped until their retry time. A zone NWS has no polygon for is
+// stored as an empty geometry, which contains nothing, rather than counted
+// as a failure. The caller must hold a.refresh.
+func (a *ActiveAlerts) resolveZones(ctx context.Context, al *AlertsResponse) {
+	var missing []string
+	queued := make(map[string]bool)
+	now := time.Now()
+	for _, f := range al.Features {
+		if f.Geometry != nil && len(f.Geometry.polygons) > 0 {
+			continue
+		}
+		for _, z := range f.Properties.AffectedZones {
+			if _, ok := a.zones[z]; ok || queued[z] {
+				continue
+			}
+			queued[z] = true
+			if g := a.cachedZone(ctx, z); g != nil {
+				a.zones[z] = g
+				continue
+			}
+			if now.Before(a.zoneFailures[z].retryAt) {
+				continue
+			}
+			missing = append(missing, z)
+		}
+	}
+
+	fetched := make([]*Geometry, len(missing))
+	sem := make(chan struct{}, zoneConcurrency)
+	var wg sync.WaitGroup
+	for i, z := range missing {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			zone, err := a.client.GetZone(ctx, z)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to get zone", "zone", z, "error", err)
+				return
+			}
+			if zone.Geometry == nil {
+				zone.Geometry = &Geometry{}
+			}
+			fetched[i] = zone.Geometry
+		}()
+	}
+	wg.Wait()
+
+	for i, z := range missing {
+		g := fetched[i]
+		if g == nil {
+			fail := a.zoneFailures[z]
+			fail.count++
+			fail.retryAt = now.Add(min(zoneRetryDelay<<(fail.count-1), zoneRetryMax))
+			a.zoneFailures[z] = fail
+			continue
+		}
+		delete(a.zoneFailures, z)
+		a.zones[z] = g
+		if data, err := json.Marshal(g); err == nil && a.db != nil {
+			if err := a.db.SetZone(ctx, z, string(data)); err != nil {
+				slog.ErrorContext(ctx, "Failed to cache zone", "zone", z, "error", err)
+			}
+		}
+	}
+}
+
+func (a *ActiveAlerts) cachedZone(ctx context.Context, url string) *Geometry {
+	if a.db == nil {
+		return nil
+	}
+	data, err := a.db.GetZone(ctx, url, time.Now().Add(-a.ZoneMaxAge))
+	if err != nil {
+		slog.Error("Zone cache error", "error", err)
+		return nil
+	}
+	if data == "" {
+		return nil
+	}
+	var g Geometry
+	if err := json.Unmarshal([]byte(data), &g); err != nil {
+		slog.Error("Zone cache decode error", "error", err)
+		return nil
+	}
+	return &g
+}
@@ -0,0 +1,202 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const activeAlertsJSON = `{"features": [
+	{
+		"id": "urn:oid:polygon",
+		"geometry": {"type": "Polygon", "coordinates": [[[-98,35],[-97,35],[-97,36],[-98,36],[-98,35]]]},
+		"properties": {"id": "urn:oid:polygon", "event": "Tornado Warning", "severity": "Extreme",
+			"geocode": {"UGC": ["OKC109"]}}
+	},
+	{
+		"id": "urn:oid:nogeometry",
+		"geometry": null,
+		"properties": {"id": "urn:oid:nogeometry", "event": "Red Flag Warning", "severity": "Moderate",
+			"affectedZones": ["https://api.weather.gov/zones/fire/OKZ002"],
+			"geocode": {"UGC": ["OKZ002"]}}
+	},
+	{
+		"id": "urn:oid:zone",
+		"geometry": null,
+		"properties": {"id": "urn:oid:zone", "event": "Heat Advisory", "severity": "Moderate",
+			"affectedZones": ["https://api.weather.gov/zones/forecast/TXZ001", "https://api.weather.gov/zones/forecast/TXZ404"],
+			"geocode": {"UGC": ["TXZ001", "TXZ404"]}}
+	}
+]}`
+
+// pointAlertsJSON is the per-point answer inside TXZ404, the zone that
+// can't be fetched.
+var pointAlertsJSON = `{"features": [` + activeAlertsJSON[strings.Index(activeAlertsJSON, `{
+		"id": "urn:oid:zone"`):]
+
+func newActiveAlertsClient(t *testing.T, calls *atomic.Int32, down *atomic.Bool) *Client {
+	t.Helper()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if err := r.Context().Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/alerts/active":
+			switch point := r.URL.Query().Get("point"); {
+			case point == "30.0000,-100.0000":
+				w.Write([]byte(pointAlertsJSON))
+			case point != "":
+				w.Write([]byte(`{"features": []}`))
+			case down.Load():
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			default:
+				w.Write([]byte(activeAlertsJSON))
+			}
+		case "/zones/fire/OKZ002":
+			w.Write([]byte(`{"id": "OKZ002", "geometry": null}`))
+		case "/zones/forecast/TXZ001":
+			w.Write([]byte(`{"id": "TXZ001", "geometry": {"type": "Polygon", "coordinates": [[[-103,36],[-102,36],[-102,37],[-103,37],[-103,36]]]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return &Client{
+		UserAgent: "test-agent",
+		HTTPClient: &http.Client{
+			Transport: &mockRoundTripper{handler: handler},
+		},
+	}
+}
+
+func TestActiveAlerts(t *testing.T) {
+	var calls atomic.Int32
+	var down atomic.Bool
+	s := &Service{client: newActiveAlertsClient(t, &calls, &down)}
+	active := s.NewActiveAlerts(time.Minute)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"inside alert polygon", 35.5, -97.5, "Tornado Warning"},
+		{"inside zone polygon", 36.5, -102.5, "Heat Advisory"},
+		{"inside unresolved zone", 30, -100, "Heat Advisory"},
+		{"outside everything", 40, -90, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts, err := active.GetAlerts(tt.lat, tt.lon)
+			if err != nil {
+				t.Fatalf("GetAlerts failed: %v", err)
+			}
+			if tt.want == "" {
+				if len(alerts) != 0 {
+					t.Errorf("expected no alerts, got %+v", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 || alerts[0].Event != tt.want {
+				t.Errorf("expected %s, got %+v", tt.want, alerts)
+			}
+		})
+	}
+
+	// One alerts call and three zone lookups (one of which 404s and one has
+	// no polygon), plus a point lookup for each point that could be in
+	// Texas, where the Heat Advisory isn't fully resolved.
+	if n := int(calls.Load()); n != 4+3 {
+		t.Errorf("expected %d upstream calls, got %d", 4+3, n)
+	}
+
+	tx, err := active.GetAlertsByArea("tx")
+	if err != nil || len(tx) != 1 || tx[0].ID != "urn:oid:zone" {
+		t.Errorf("expected the Texas alert, got %+v err=%v", tx, err)
+	}
+
+	// A stale snapshot is refetched; zones stay cached in memory, and the
+	// one that failed isn't retried until its backoff is up. The zone
+	// without a polygon isn't a failure.
+	active.TTL = 0
+	calls.Store(0)
+	idx, err := active.Index()
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected only the alerts fetch, got %d calls", n)
+	}
+	if _, ok := idx.unresolved["urn:oid:nogeometry"]; ok {
+		t.Errorf("expected the zone without a polygon to be resolved")
+	}
+
+	// A failed refresh keeps the previous snapshot.
+	down.Store(true)
+	stale, err := active.Index()
+	if err != nil || stale != idx {
+		t.Errorf("expected the previous snapshot, got %p err=%v", stale, err)
+	}
+}
+
+func TestActiveAlerts_ColdFailure(t *testing.T) {
+	var calls atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	s := &Service{client: newActiveAlertsClient(t, &calls, &down)}
+	active := s.NewActiveAlerts(time.Minute)
+
+	if _, err := active.GetAlerts(35.5, -97.5); err == nil {
+		t.Errorf("expected an error with no snapshot to fall back on")
+	}
+	down.Store(false)
+	if alerts, err := active.GetAlerts(35.5, -97.5); err != nil || len(alerts) != 1 {
+		t.Errorf("expected a retry to succeed, got %+v err=%v", alerts, err)
+	}
+}
+
+func TestActiveAlerts_Context(t *testing.T) {
+	var calls atomic.Int32
+	var down atomic.Bool
+	s := &Service{client: newActiveAlertsClient(t, &calls, &down)}
+	active := s.NewActiveAlerts(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The refresh is shared, so one caller going away doesn't abort it...
+	if _, err := active.IndexContext(ctx); err != nil {
+		t.Fatalf("expected the refresh to outlive the request, got %v", err)
+	}
+	// ...but the caller's own lookups use its context. A failed point
+	// lookup still returns what matched locally.
+	if alerts, err := active.GetAlertsContext(ctx, 30, -100); err != nil || len(alerts) != 0 {
+		t.Errorf("expected the point lookup for unresolved zones to be cancelled, got %+v err=%v", alerts, err)
+	}
+	if alerts, err := active.GetAlertsContext(context.Background(), 30, -100); err != nil || len(alerts) == 0 {
+		t.Errorf("expected the unresolved alert, got %+v err=%v", alerts, err)
+	}
+}
+
+func TestUGCMayContain(t *testing.T) {
+	tests := []struct {
+		code     string
+		lat, lon float64
+		want     bool
+	}{
+		{"TXZ404", 30, -100, true},
+		{"TXZ404", 40, -90, false},
+		{"OKC109", 37.2, -97.5, true}, // Within the margin of the border
+		{"AKZ191", 52.9, 173.1, true}, // Attu, past the antimeridian
+		{"AKZ191", 52.9, 150, false},
+		{"XXZ001", 0, 0, true}, // Unknown area
+	}
+	for _, tt := range tests {
+		if got := ugcMayContain(tt.code, tt.lat, tt.lon); got != tt.want {
+			t.Errorf("ugcMayContain(%q, %v, %v) = %v, want %v", tt.code, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
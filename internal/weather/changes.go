@@ -0,0 +1,213 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of ForecastChange.
+const (
+	ChangePrecip         = "precip"          // Precipitation chance for a day
+	ChangeHigh           = "high"            // High temperature for a day
+	ChangeLow            = "low"             // Low temperature for a day
+	ChangeCondition      = "condition"       // Short forecast for a day
+	ChangeAlertNew       = "alert_new"       // An alert was issued
+	ChangeAlertCancelled = "alert_cancelled" // An alert went away before it expired
+)
+
+// Thresholds below which a forecast change isn't worth mentioning.
+const (
+	precipChangeThreshold = 20 // percentage points
+	tempChangeThreshold   = 3  // °F
+)
+
+// ForecastChange is one notable difference between a forecast and the
+// previous one for the same location. From and To hold the old and new
+// precipitation chance or temperature, in Unit; condition changes use
+// FromCondition and ToCondition instead.
+type ForecastChange struct {
+	Kind          string `json:"kind"`
+	Day           string `json:"day,omitempty"`  // e.g. "Tuesday"
+	Date          string `json:"date,omitempty"` // Local date, "2006-01-02"
+	Event         string `json:"event,omitempty"`
+	From          int    `json:"from,omitempty"`
+	To            int    `json:"to,omitempty"`
+	Unit          string `json:"unit,omitempty"` // "%" or the temperature unit
+	FromCondition string `json:"from_condition,omitempty"`
+	ToCondition   string `json:"to_condition,omitempty"`
+	Summary       string `json:"summary"` // e.g. "Tuesday: precipitation chance up from 20% to 70%"
+}
+
+// ChangesSinceLabel is the time of the previous forecast in the location's
+// time zone, e.g. "4:04 AM CDT", falling back to UTC when it isn't known.
+func (wd *WeatherData) ChangesSinceLabel() string {
+	loc := time.UTC
+	if l, err := time.LoadLocation(wd.TimeZone); err == nil && wd.TimeZone != "" {
+		loc = l
+	}
+	return wd.ChangesSince.In(loc).Format("3:04 PM MST")
+}
+
+// DiffForecasts lists what changed between prev and cur: precipitation
+// chance jumps, high and low shifts and condition changes for the days both
+// cover, and alerts issued or cancelled since. Both must be in the same
+// units. now decides whether a missing alert was cancelled or just expired.
+func DiffForecasts(prev, cur *WeatherData, now time.Time) []ForecastChange {
+	var changes []ForecastChange
+
+	for _, day := range cur.Forecast {
+		old, ok := findDay(prev.Forecast, day)
+		if !ok {
+			continue
+		}
+		if abs(day.PrecipChance-old.PrecipChance) >= precipChangeThreshold {
+			changes = append(changes, dayChange(ChangePrecip, day, old.PrecipChance, day.PrecipChance, "%"))
+		}
+		threshold := tempChangeThreshold
+		if day.TemperatureUnit == UnitCelsius {
+			threshold = 2
+		}
+		if old.TemperatureUnit == day.TemperatureUnit {
+			if abs(day.HighTemp-old.HighTemp) >= threshold {
+				changes = append(changes, dayChange(ChangeHigh, day, old.HighTemp, day.HighTemp, day.TemperatureUnit))
+			}
+			if abs(day.LowTemp-old.LowTemp) >= threshold {
+				changes = append(changes, dayChange(ChangeLow, day, old.LowTemp, day.LowTemp, day.TemperatureUnit))
+			}
+		}
+		if !strings.EqualFold(day.ShortForecast, old.ShortForecast) && old.ShortForecast != "" {
+			c := ForecastChange{Kind: ChangeCondition, Day: day.Name, Date: day.Date, FromCondition: old.ShortForecast, ToCondition: day.ShortForecast}
+			c.describe()
+			changes = append(changes, c)
+		}
+	}
+
+	// Updates replace earlier versions of an alert, so they are neither new
+	// nor a cancellation of the version they replace.
+	seen := make(map[string]bool)
+	for _, a := range prev.Alerts {
+		seen[a.ID] = true
+	}
+	replaced := make(map[string]bool)
+	for _, a := range cur.Alerts {
+		for _, ref := range a.References {
+			replaced[ref] = true
+		}
+	}
+	for _, a := range cur.Alerts {
+		if seen[a.ID] || a.MessageType == "Cancel" {
+			continue
+		}
+		isUpdate := false
+		for _, ref := range a.References {
+			isUpdate = isUpdate || seen[ref]
+		}
+		if !isUpdate {
+			c := ForecastChange{Kind: ChangeAlertNew, Event: a.Event}
+			c.describe()
+			changes = append(changes, c)
+		}
+	}
+	current := make(map[string]bool)
+	for _, a := range cur.Alerts {
+		if a.MessageType != "Cancel" {
+			current[a.ID] = true
+		}
+	}
+	for _, a := range prev.Alerts {
+		if a.MessageType == "Cancel" || current[a.ID] || (replaced[a.ID] && !cancelled(cur.Alerts, a.ID)) {
+			continue
+		}
+		if !a.Expires.IsZero() && !a.Expires.After(now) {
+			continue
+		}
+		c := ForecastChange{Kind: ChangeAlertCancelled, Event: a.Event}
+		c.describe()
+		changes = append(changes, c)
+	}
+
+	return changes
+}
+
+// cancelled reports whether alerts has a Cancel message for id.
+func cancelled(alerts []Alert, id string) bool {
+	for _, a := range alerts {
+		if a.MessageType != "Cancel" {
+			continue
+		}
+		for _, ref := range a.References {
+			if ref == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findDay returns the day in days for the same date as day, or the same
+// name when dates aren't known.
+func findDay(days []DailyForecast, day DailyForecast) (DailyForecast, bool) {
+	for _, d := range days {
+		if day.Date != "" && d.Date != "" {
+			if d.Date == day.Date {
+				return d, true
+			}
+		} else if d.Name == day.Name {
+			return d, true
+		}
+	}
+	return DailyForecast{}, false
+}
+
+func dayChange(kind string, day DailyForecast, from, to int, unit string) ForecastChange {
+	c := ForecastChange{Kind: kind, Day: day.Name, Date: day.Date, From: from, To: to, Unit: unit}
+	c.describe()
+	return c
+}
+
+// describe sets Summary from the other fields.
+func (c *ForecastChange) describe() {
+	direction := "up"
+	if c.To < c.From {
+		direction = "down"
+	}
+	unit := c.Unit
+	if unit != "%" {
+		unit = "°" + unit
+	}
+
+	switch c.Kind {
+	case ChangePrecip:
+		c.Summary = fmt.Sprintf("%s: precipitation chance %s from %d%% to %d%%", c.Day, direction, c.From, c.To)
+	case ChangeHigh:
+		c.Summary = fmt.Sprintf("%s: high %s from %d%s to %d%s", c.Day, direction, c.From, unit, c.To, unit)
+	case ChangeLow:
+		c.Summary = fmt.Sprintf("%s: low %s from %d%s to %d%s", c.Day, direction, c.From, unit, c.To, unit)
+	case ChangeCondition:
+		c.Summary = fmt.Sprintf("%s: now %s (was %s)", c.Day, c.ToCondition, c.FromCondition)
+	case ChangeAlertNew:
+		c.Summary = "New: " + c.Event
+	case ChangeAlertCancelled:
+		c.Summary = "Cancelled: " + c.Event
+	}
+}
+
+// withUnits converts temperature changes to set.
+func (c ForecastChange) withUnits(set unitSet) ForecastChange {
+	if c.Kind != ChangeHigh && c.Kind != ChangeLow {
+		return c
+	}
+	c.From = convertTemp(c.From, c.Unit, set.Temperature)
+	c.To = convertTemp(c.To, c.Unit, set.Temperature)
+	c.Unit = temperatureUnit(c.Unit, set)
+	c.describe()
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
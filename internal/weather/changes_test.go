@@ -0,0 +1,113 @@
+package weather
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffForecasts(t *testing.T) {
+	now := time.Date(2025, 5, 6, 12, 0, 0, 0, time.UTC)
+	prev := &WeatherData{
+		Forecast: []DailyForecast{
+			{Name: "Today", Date: "2025-05-06", HighTemp: 75, LowTemp: 58, TemperatureUnit: "F", ShortForecast: "Sunny", PrecipChance: 20},
+			{Name: "Wednesday", Date: "2025-05-07", HighTemp: 80, LowTemp: 60, TemperatureUnit: "F", ShortForecast: "Mostly Sunny", PrecipChance: 10},
+		},
+		Alerts: []Alert{
+			{ID: "wind", Event: "Wind Advisory", Expires: now.Add(time.Hour)},
+			{ID: "heat", Event: "Heat Advisory", Expires: now.Add(time.Hour)},
+			{ID: "fog", Event: "Dense Fog Advisory", Expires: now.Add(-time.Hour)},
+		},
+	}
+	cur := &WeatherData{
+		Forecast: []DailyForecast{
+			// Both forecasts name the current day differently once the
+			// afternoon forecast drops "Today"; dates still match.
+			{Name: "This Afternoon", Date: "2025-05-06", HighTemp: 73, LowTemp: 58, TemperatureUnit: "F", ShortForecast: "sunny", PrecipChance: 70},
+			{Name: "Wednesday", Date: "2025-05-07", HighTemp: 72, LowTemp: 55, TemperatureUnit: "F", ShortForecast: "Showers And Thunderstorms", PrecipChance: 25},
+			{Name: "Thursday", Date: "2025-05-08", HighTemp: 70, LowTemp: 50, TemperatureUnit: "F", ShortForecast: "Rain", PrecipChance: 90},
+		},
+		Alerts: []Alert{
+			{ID: "heat2", Event: "Heat Advisory", MessageType: "Update", References: []string{"heat"}},
+			{ID: "tor", Event: "Tornado Warning", MessageType: "Alert"},
+		},
+	}
+
+	got := DiffForecasts(prev, cur, now)
+	want := []string{
+		"This Afternoon: precipitation chance up from 20% to 70%",
+		"Wednesday: high down from 80°F to 72°F",
+		"Wednesday: low down from 60°F to 55°F",
+		"Wednesday: now Showers And Thunderstorms (was Mostly Sunny)",
+		"New: Tornado Warning",
+		"Cancelled: Wind Advisory",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), got)
+	}
+	for i, c := range got {
+		if c.Summary != want[i] {
+			t.Errorf("change %d: expected %q, got %q", i, want[i], c.Summary)
+		}
+	}
+	if got[0].Kind != ChangePrecip || got[0].From != 20 || got[0].To != 70 || got[0].Date != "2025-05-06" {
+		t.Errorf("unexpected precip change %+v", got[0])
+	}
+}
+
+func TestDiffForecasts_CancelMessage(t *testing.T) {
+	now := time.Date(2025, 5, 6, 12, 0, 0, 0, time.UTC)
+	prev := &WeatherData{Alerts: []Alert{{ID: "tor", Event: "Tornado Warning", Expires: now.Add(time.Hour)}}}
+	cur := &WeatherData{Alerts: []Alert{{ID: "tor-c", Event: "Tornado Warning", MessageType: "Cancel", References: []string{"tor"}}}}
+
+	got := DiffForecasts(prev, cur, now)
+	if len(got) != 1 || got[0].Kind != ChangeAlertCancelled {
+		t.Errorf("expected one cancellation, got %+v", got)
+	}
+
+	if got := DiffForecasts(cur, cur, now); len(got) != 0 {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
+
+func TestWithUnits_Changes(t *testing.T) {
+	wd := &WeatherData{Changes: []ForecastChange{
+		{Kind: ChangeHigh, Day: "Wednesday", From: 80, To: 72, Unit: "F"},
+		{Kind: ChangePrecip, Day: "Wednesday", From: 10, To: 60, Unit: "%"},
+	}}
+	wd.Changes[0].describe()
+	wd.Changes[1].describe()
+
+	got := wd.WithUnits(UnitsMetric).Changes
+	if got[0].Summary != "Wednesday: high down from 27°C to 22°C" {
+		t.Errorf("unexpected summary %q", got[0].Summary)
+	}
+	if got[1].From != 10 || got[1].To != 60 {
+		t.Errorf("expected precipitation chance unchanged, got %+v", got[1])
+	}
+	if wd.Changes[0].Unit != "F" {
+		t.Errorf("expected the original to be unchanged")
+	}
+}
+
+func TestChangesSinceLabel(t *testing.T) {
+	wd := &WeatherData{ChangesSince: time.Date(2025, 5, 6, 9, 4, 0, 0, time.UTC)}
+	if got := wd.ChangesSinceLabel(); got != "9:04 AM UTC" {
+		t.Errorf("expected UTC without a time zone, got %q", got)
+	}
+	wd.TimeZone = "America/Chicago"
+	if got := wd.ChangesSinceLabel(); got != "4:04 AM CDT" {
+		t.Errorf("expected local time, got %q", got)
+	}
+}
+
+func TestChangesSinceJSON(t *testing.T) {
+	data, err := json.Marshal(&WeatherData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "changes_since") {
+		t.Errorf("expected changes_since left out without a previous forecast, got %s", data)
+	}
+}
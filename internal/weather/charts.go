@@ -0,0 +1,243 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// seriesHours is how far ahead HourlySeries runs.
+const seriesHours = 48
+
+// Chart drawing area, in SVG user units. The plot sits inside the margins,
+// which hold the axis labels.
+const (
+	chartWidth  = 600
+	chartHeight = 180
+	chartLeft   = 40
+	chartRight  = 10
+	chartTop    = 10
+	chartBottom = 24
+)
+
+// Chart is an hourly chart laid out for the weather_fragment template,
+// which draws it as SVG. Summary is its text alternative.
+type Chart struct {
+	ID      string // "temperature" or "precip"
+	Title   string
+	Summary string
+	Width   int
+	Height  int
+	Series  []ChartSeries
+	Bars    []ChartBar
+	XTicks  []ChartTick
+	YTicks  []ChartTick
+	// PlotLeft, PlotRight, PlotTop and PlotBottom bound the plot area.
+	PlotLeft, PlotRight, PlotTop, PlotBottom int
+}
+
+// ChartSeries is one line of a line chart, as SVG polyline points.
+type ChartSeries struct {
+	Name   string
+	Class  string
+	Points string
+}
+
+// ChartBar is one bar of a bar chart. Label is its tooltip.
+type ChartBar struct {
+	X, Y, Width, Height float64
+	Label               string
+}
+
+// ChartTick is an axis label at Pos, an x or y coordinate.
+type ChartTick struct {
+	Pos   float64
+	Label string
+}
+
+// hourlySeries builds HourlySeries from the NWS hourly forecast periods.
+func hourlySeries(periods []ForecastPeriod) []HourlyPoint {
+	var series []HourlyPoint
+	for _, p := range periods {
+		if len(series) == seriesHours {
+			break
+		}
+		t, err := time.Parse(time.RFC3339, p.StartTime)
+		if err != nil {
+			continue
+		}
+		wind := parseWind(p.WindSpeed, string(p.WindGust), p.WindDirection)
+		mph, ok := convertValue(wind.Max, wind.Unit, UnitMPH)
+		if !ok {
+			mph = 0
+		}
+		f := convertTemperature(float64(p.Temperature), p.TemperatureUnit, UnitFahrenheit)
+		feels := convertTemperature(feelsLike(f, p.RelativeHumidity.Value, mph), UnitFahrenheit, p.TemperatureUnit)
+		series = append(series, HourlyPoint{
+			Time:            t,
+			Temperature:     p.Temperature,
+			FeelsLike:       int(math.Round(feels)),
+			TemperatureUnit: p.TemperatureUnit,
+			Icon:            mapIcon(p.Icon, p.IsDaytime),
+			PrecipChance:    p.ProbabilityOfPrecipitation.Value,
+		})
+	}
+	return series
+}
+
+// feelsLike returns the apparent temperature in °F: the NWS wind chill at
+// 50°F and below in wind of 3 mph or more, the NWS heat index when that
+// comes to 80°F or more, and t otherwise.
+func feelsLike(t, humidity, windMPH float64) float64 {
+	if t <= 50 && windMPH >= 3 {
+		v := math.Pow(windMPH, 0.16)
+		return 35.74 + 0.6215*t - 35.75*v + 0.4275*t*v
+	}
+
+	rh := humidity
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 < 80 {
+		return t
+	}
+	hi = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh -
+		0.00683783*t*t - 0.05481717*rh*rh + 0.00122874*t*t*rh +
+		0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += (rh - 85) / 10 * (87 - t) / 5
+	}
+	return hi
+}
+
+// HourlyCharts lays out the temperature (with feels-like) and
+// precipitation chance charts for HourlySeries, or returns nil when there
+// aren't enough hours to chart.
+func (wd *WeatherData) HourlyCharts() []Chart {
+	s := wd.HourlySeries
+	if len(s) < 2 {
+		return nil
+	}
+	return []Chart{temperatureChart(s), precipChart(s)}
+}
+
+func newChart(id, title string) Chart {
+	return Chart{
+		ID: id, Title: title, Width: chartWidth, Height: chartHeight,
+		PlotLeft: chartLeft, PlotRight: chartWidth - chartRight,
+		PlotTop: chartTop, PlotBottom: chartHeight - chartBottom,
+	}
+}
+
+// x returns the x coordinate of hour i of n.
+func (c *Chart) x(i, n int) float64 {
+	return roundTo(float64(c.PlotLeft)+float64(i)*float64(c.PlotRight-c.PlotLeft)/float64(n-1), 1)
+}
+
+// y returns the y coordinate of v on a scale from lo to hi.
+func (c *Chart) y(v, lo, hi float64) float64 {
+	return roundTo(float64(c.PlotBottom)-(v-lo)/(hi-lo)*float64(c.PlotBottom-c.PlotTop), 1)
+}
+
+// timeTicks labels every sixth hour, with the day's name at midnight.
+func (c *Chart) timeTicks(s []HourlyPoint, x func(i int) float64) {
+	for i, p := range s {
+		if p.Time.Hour()%6 != 0 {
+			continue
+		}
+		label := p.Time.Format("3 PM")
+		if p.Time.Hour() == 0 {
+			label = p.Time.Format("Mon")
+		}
+		c.XTicks = append(c.XTicks, ChartTick{Pos: x(i), Label: label})
+	}
+}
+
+func temperatureChart(s []HourlyPoint) Chart {
+	c := newChart("temperature", fmt.Sprintf("Temperature and feels like, next %d hours", len(s)))
+	unit := "°" + s[0].TemperatureUnit
+
+	lo, hi := s[0].Temperature, s[0].Temperature
+	loAt, hiAt := 0, 0
+	feelsLo, feelsHi := s[0].FeelsLike, s[0].FeelsLike
+	for i, p := range s {
+		if p.Temperature < lo {
+			lo, loAt = p.Temperature, i
+		}
+		if p.Temperature > hi {
+			hi, hiAt = p.Temperature, i
+		}
+		feelsLo, feelsHi = min(feelsLo, p.FeelsLike), max(feelsHi, p.FeelsLike)
+	}
+	// Round the scale out to multiples of 10 degrees.
+	scaleLo := math.Floor(float64(min(lo, feelsLo))/10) * 10
+	scaleHi := math.Ceil(float64(max(hi, feelsHi))/10) * 10
+	if scaleHi == scaleLo {
+		scaleHi += 10
+	}
+
+	temps := make([]string, len(s))
+	feels := make([]string, len(s))
+	for i, p := range s {
+		x := strconv.FormatFloat(c.x(i, len(s)), 'f', -1, 64)
+		temps[i] = x + "," + strconv.FormatFloat(c.y(float64(p.Temperature), scaleLo, scaleHi), 'f', -1, 64)
+		feels[i] = x + "," + strconv.FormatFloat(c.y(float64(p.FeelsLike), scaleLo, scaleHi), 'f', -1, 64)
+	}
+	c.Series = []ChartSeries{
+		{Name: "Temperature", Class: "chart-temperature", Points: strings.Join(temps, " ")},
+		{Name: "Feels like", Class: "chart-feels-like", Points: strings.Join(feels, " ")},
+	}
+	mid := (scaleLo + scaleHi) / 2
+	for _, v := range []float64{scaleLo, mid, scaleHi} {
+		c.YTicks = append(c.YTicks, ChartTick{Pos: c.y(v, scaleLo, scaleHi), Label: strconv.FormatFloat(roundTo(v, 1), 'f', -1, 64) + "°"})
+	}
+	c.timeTicks(s, func(i int) float64 { return c.x(i, len(s)) })
+
+	c.Summary = fmt.Sprintf("Temperature over the next %d hours ranges from %d%s (%s) to %d%s (%s).",
+		len(s), lo, unit, hourLabel(s[loAt].Time), hi, unit, hourLabel(s[hiAt].Time))
+	if feelsLo != lo || feelsHi != hi {
+		c.Summary += fmt.Sprintf(" It feels like %d%s to %d%s.", feelsLo, unit, feelsHi, unit)
+	}
+	return c
+}
+
+func precipChart(s []HourlyPoint) Chart {
+	c := newChart("precip", fmt.Sprintf("Chance of precipitation, next %d hours", len(s)))
+	slot := float64(c.PlotRight-c.PlotLeft) / float64(len(s))
+	x := func(i int) float64 { return roundTo(float64(c.PlotLeft)+(float64(i)+0.5)*slot, 1) }
+
+	peak, peakAt := 0, 0
+	for i, p := range s {
+		if p.PrecipChance > peak {
+			peak, peakAt = p.PrecipChance, i
+		}
+		y := c.y(float64(p.PrecipChance), 0, 100)
+		c.Bars = append(c.Bars, ChartBar{
+			X:      roundTo(float64(c.PlotLeft)+float64(i)*slot+1, 1),
+			Y:      y,
+			Width:  roundTo(max(slot-2, 1), 1),
+			Height: roundTo(float64(c.PlotBottom)-y, 1),
+			Label:  fmt.Sprintf("%s: %d%%", hourLabel(p.Time), p.PrecipChance),
+		})
+	}
+	for _, v := range []float64{0, 50, 100} {
+		c.YTicks = append(c.YTicks, ChartTick{Pos: c.y(v, 0, 100), Label: fmt.Sprintf("%g%%", v)})
+	}
+	c.timeTicks(s, x)
+
+	if peak == 0 {
+		c.Summary = fmt.Sprintf("No precipitation is expected in the next %d hours.", len(s))
+	} else {
+		c.Summary = fmt.Sprintf("Chance of precipitation over the next %d hours peaks at %d%% (%s).",
+			len(s), peak, hourLabel(s[peakAt].Time))
+	}
+	return c
+}
+
+// hourLabel formats an hour for chart text, e.g. "Tue 3 PM".
+func hourLabel(t time.Time) string {
+	return t.Format("Mon 3 PM")
+}
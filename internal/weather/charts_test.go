@@ -0,0 +1,101 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeelsLike(t *testing.T) {
+	tests := []struct {
+		name                string
+		temp, humidity, mph float64
+		want                float64
+	}{
+		{"mild", 68, 50, 10, 68},
+		{"wind chill", 20, 50, 15, 6.2},
+		{"calm cold", 20, 50, 2, 20},
+		{"heat index", 96, 65, 5, 121.1},
+		{"dry heat", 100, 10, 5, 94.1},
+		{"warm but not humid enough", 80, 10, 5, 80},
+	}
+	for _, tt := range tests {
+		got := feelsLike(tt.temp, tt.humidity, tt.mph)
+		if math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s: feelsLike(%g, %g, %g) = %.1f, want %g", tt.name, tt.temp, tt.humidity, tt.mph, got, tt.want)
+		}
+	}
+}
+
+func hourlyPeriods(n int) []ForecastPeriod {
+	start := time.Date(2025, 7, 14, 12, 0, 0, 0, time.FixedZone("CDT", -5*3600))
+	periods := make([]ForecastPeriod, n)
+	for i := range periods {
+		p := &periods[i]
+		p.StartTime = start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)
+		p.Temperature = 80 + i%16
+		p.TemperatureUnit = "F"
+		p.WindSpeed = "5 mph"
+		p.RelativeHumidity.Value = 60
+		p.ProbabilityOfPrecipitation.Value = (i * 10) % 70
+	}
+	return periods
+}
+
+func TestHourlySeries(t *testing.T) {
+	series := hourlySeries(hourlyPeriods(60))
+	if len(series) != 48 {
+		t.Fatalf("expected 48 hours, got %d", len(series))
+	}
+	p := series[15]
+	if p.Temperature != 95 || p.FeelsLike != 113 || p.PrecipChance != 10 || p.Time.Hour() != 3 {
+		t.Errorf("unexpected point %+v", p)
+	}
+
+	wd := &WeatherData{HourlySeries: series}
+	if c := wd.WithUnits(UnitsMetric).HourlySeries[15]; c.Temperature != 35 || c.FeelsLike != 45 || c.TemperatureUnit != "C" {
+		t.Errorf("expected metric units, got %+v", c)
+	}
+	if series[0].TemperatureUnit != "F" {
+		t.Errorf("WithUnits modified the original")
+	}
+}
+
+func TestHourlyCharts(t *testing.T) {
+	if (&WeatherData{}).HourlyCharts() != nil {
+		t.Errorf("expected no charts without an hourly series")
+	}
+
+	wd := &WeatherData{HourlySeries: hourlySeries(hourlyPeriods(48))}
+	charts := wd.HourlyCharts()
+	if len(charts) != 2 {
+		t.Fatalf("expected two charts, got %d", len(charts))
+	}
+
+	temp := charts[0]
+	if len(temp.Series) != 2 || strings.Count(temp.Series[0].Points, ",") != 48 {
+		t.Errorf("expected two 48 point lines, got %+v", temp.Series)
+	}
+	// The first point is 80°F on a scale from 80 up.
+	if !strings.HasPrefix(temp.Series[0].Points, fmt.Sprintf("%d,%d ", chartLeft, chartHeight-chartBottom)) {
+		t.Errorf("unexpected first point %q", temp.Series[0].Points[:20])
+	}
+	if temp.YTicks[0].Label != "80°" || temp.YTicks[2].Label != "120°" ||
+		temp.XTicks[0].Label != "12 PM" || temp.XTicks[2].Label != "Tue" {
+		t.Errorf("unexpected ticks %+v %+v", temp.YTicks, temp.XTicks)
+	}
+	want := "Temperature over the next 48 hours ranges from 80°F (Mon 12 PM) to 95°F (Tue 3 AM). It feels like 82°F to 113°F."
+	if temp.Summary != want {
+		t.Errorf("unexpected summary %q", temp.Summary)
+	}
+
+	precip := charts[1]
+	if len(precip.Bars) != 48 || precip.Bars[0].Height != 0 || precip.Bars[6].Label != "Mon 6 PM: 60%" {
+		t.Errorf("unexpected bars %+v", precip.Bars[:7])
+	}
+	if precip.Summary != "Chance of precipitation over the next 48 hours peaks at 60% (Mon 6 PM)." {
+		t.Errorf("unexpected summary %q", precip.Summary)
+	}
+}
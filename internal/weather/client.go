@@ -1,14 +1,21 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/logging"
+	"github.com/swelljoe/wthr.lol/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client handles NWS API interactions
@@ -17,6 +24,15 @@ type Client struct {
 	HTTPClient *http.Client
 }
 
+// hostOf returns the host name of a URL, or "" if it doesn't parse.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
 // NewClient creates a new NWS API client
 func NewClient() *Client {
 	userAgent := os.Getenv("NWS_USER_AGENT")
@@ -32,8 +48,24 @@ func NewClient() *Client {
 	}
 }
 
-func (c *Client) get(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// get fetches url. endpoint names the kind of request (e.g. "points") for
+// the upstream metrics, logs and span. The log and span record only the
+// host, as the rest of the URL can hold a user's coordinates or search text.
+func (c *Client) get(ctx context.Context, endpoint, url string) (data []byte, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "GET "+endpoint, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String("GET"), semconv.ServerAddress(hostOf(url))))
+	defer func() {
+		tracing.End(span, err)
+		observeUpstream(endpoint, start, err)
+		attrs := []any{"endpoint", endpoint, "host", hostOf(url), "latency_ms", logging.Millis(time.Since(start))}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		}
+		slog.DebugContext(ctx, "Upstream request", attrs...)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +78,7 @@ func (c *Client) get(url string) ([]byte, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("NWS API error: %d %s", resp.StatusCode, resp.Status)
@@ -69,9 +102,9 @@ type PointResponse struct {
 }
 
 // GetPointMetadata fetches metadata for a lat/lon
-func (c *Client) GetPointMetadata(lat, lon float64) (*PointResponse, error) {
+func (c *Client) GetPointMetadata(ctx context.Context, lat, lon float64) (*PointResponse, error) {
 	url := fmt.Sprintf("https://api.weather.gov/points/%.4f,%.4f", lat, lon)
-	data, err := c.get(url)
+	data, err := c.get(ctx, "points", url)
 	if err != nil {
 		return nil, err
 	}
@@ -83,30 +116,43 @@ func (c *Client) GetPointMetadata(lat, lon float64) (*PointResponse, error) {
 	return &pt, nil
 }
 
+// ForecastPeriod is a single period of an NWS forecast (a day/night half for
+// the daily forecast or one hour for the hourly forecast).
+type ForecastPeriod struct {
+	Name                       string `json:"name"`
+	StartTime                  string `json:"startTime"`
+	IsDaytime                  bool   `json:"isDaytime"`
+	Temperature                int    `json:"temperature"`
+	TemperatureUnit            string `json:"temperatureUnit"`
+	ProbabilityOfPrecipitation struct {
+		Value int `json:"value"`
+	} `json:"probabilityOfPrecipitation"`
+	RelativeHumidity struct {
+		Value float64 `json:"value"`
+	} `json:"relativeHumidity"` // Percent; only in hourly forecasts
+	WindSpeed        string      `json:"windSpeed"`
+	WindGust         SpeedString `json:"windGust"` // Only present on some gridpoints, e.g. "25 mph"
+	WindDirection    string      `json:"windDirection"`
+	Icon             string      `json:"icon"`
+	ShortForecast    string      `json:"shortForecast"`
+	DetailedForecast string      `json:"detailedForecast"`
+}
+
 // ForecastResponse represents the NWS /gridpoints/.../forecast response
 type ForecastResponse struct {
 	Properties struct {
-		Periods []struct {
-			Name                       string `json:"name"`
-			StartTime                  string `json:"startTime"`
-			IsDaytime                  bool   `json:"isDaytime"`
-			Temperature                int    `json:"temperature"`
-			TemperatureUnit            string `json:"temperatureUnit"`
-			ProbabilityOfPrecipitation struct {
-				Value int `json:"value"`
-			} `json:"probabilityOfPrecipitation"`
-			WindSpeed        string `json:"windSpeed"`
-			WindDirection    string `json:"windDirection"`
-			Icon             string `json:"icon"`
-			ShortForecast    string `json:"shortForecast"`
-			DetailedForecast string `json:"detailedForecast"`
-		} `json:"periods"`
+		UpdateTime string           `json:"updateTime"` // When the forecast was issued, RFC 3339
+		Periods    []ForecastPeriod `json:"periods"`
 	} `json:"properties"`
 }
 
 // GetForecast fetches forecast data from a provided URL
-func (c *Client) GetForecast(url string) (*ForecastResponse, error) {
-	data, err := c.get(url)
+func (c *Client) GetForecast(ctx context.Context, url string) (*ForecastResponse, error) {
+	endpoint := "forecast"
+	if strings.HasSuffix(url, "/hourly") {
+		endpoint = "forecast_hourly"
+	}
+	data, err := c.get(ctx, endpoint, url)
 	if err != nil {
 		return nil, err
 	}
@@ -120,21 +166,163 @@ func (c *Client) GetForecast(url string) (*ForecastResponse, error) {
 
 // AlertsResponse represents the NWS /alerts/active response
 type AlertsResponse struct {
-	Features []struct {
-		Properties struct {
-			Event       string `json:"event"`
-			Headline    string `json:"headline"`
-			Description string `json:"description"`
-			Severity    string `json:"severity"`
-			AreaDesc    string `json:"areaDesc"`
-		} `json:"properties"`
-	} `json:"features"`
+	Features []AlertFeature `json:"features"`
+}
+
+// AlertFeature is one alert in an AlertsResponse. ID is the alert's URN,
+// which stays the same for the life of one alert message.
+type AlertFeature struct {
+	ID         string    `json:"id"`
+	Geometry   *Geometry `json:"geometry"` // Nil for alerts issued by zone
+	Properties struct {
+		ID          string    `json:"id"`
+		Event       string    `json:"event"`
+		Headline    string    `json:"headline"`
+		Description string    `json:"description"`
+		Instruction string    `json:"instruction"`
+		Severity    string    `json:"severity"`
+		AreaDesc    string    `json:"areaDesc"`
+		MessageType string    `json:"messageType"` // "Alert", "Update" or "Cancel"
+		Sent        time.Time `json:"sent"`
+		Effective   time.Time `json:"effective"`
+		Onset       time.Time `json:"onset"`
+		Expires     time.Time `json:"expires"`
+		Ends        time.Time `json:"ends"`
+		References  []struct {
+			Identifier string    `json:"identifier"`
+			Sent       time.Time `json:"sent"`
+		} `json:"references"` // Earlier versions this message updates or cancels
+		Parameters struct {
+			VTEC []string `json:"VTEC"` // e.g. "/O.CON.KOUN.TO.W.0012.250506T2000Z-250506T2100Z/"
+		} `json:"parameters"`
+		AffectedZones []string `json:"affectedZones"` // Zone URLs, e.g. https://api.weather.gov/zones/forecast/OKZ025
+		Geocode       struct {
+			UGC []string `json:"UGC"` // Zone and county codes, e.g. "OKZ025", "OKC109"
+		} `json:"geocode"`
+	} `json:"properties"`
+}
+
+// vtecEvent returns the event a set of P-VTEC strings track, as office,
+// phenomenon, significance and event tracking number ("KOUN.TO.W.0012"),
+// preferring one still in effect over one being cancelled or upgraded. It
+// returns "" when there is no usable VTEC.
+func vtecEvent(vtecs []string) string {
+	event := ""
+	for _, v := range vtecs {
+		// /k.aaa.cccc.pp.s.####.yymmddThhnnZ-yymmddThhnnZ/
+		parts := strings.Split(strings.Trim(v, "/"), ".")
+		if len(parts) != 7 {
+			continue
+		}
+		key := strings.Join(parts[2:6], ".")
+		switch parts[1] {
+		case "CAN", "EXP", "UPG":
+			if event == "" {
+				event = key
+			}
+		default:
+			return key
+		}
+	}
+	return event
+}
+
+// Alerts converts the response features to Alerts.
+func (al *AlertsResponse) Alerts() []Alert {
+	alerts := make([]Alert, 0, len(al.Features))
+	for _, f := range al.Features {
+		id := f.Properties.ID
+		if id == "" {
+			id = f.ID
+		}
+		// The first version is the oldest referenced message, or this one.
+		var refs []string
+		first, firstSent := id, f.Properties.Sent
+		for _, ref := range f.Properties.References {
+			refs = append(refs, ref.Identifier)
+			if !ref.Sent.IsZero() && ref.Sent.Before(firstSent) {
+				first, firstSent = ref.Identifier, ref.Sent
+			}
+		}
+		series := first
+		if event := vtecEvent(f.Properties.Parameters.VTEC); event != "" {
+			series = fmt.Sprintf("%s.%d", event, firstSent.Year())
+		}
+		alerts = append(alerts, Alert{
+			ID:          id,
+			Event:       f.Properties.Event,
+			Headline:    f.Properties.Headline,
+			Description: f.Properties.Description,
+			Instruction: f.Properties.Instruction,
+			Severity:    f.Properties.Severity,
+			AreaDesc:    f.Properties.AreaDesc,
+			MessageType: f.Properties.MessageType,
+			Sent:        f.Properties.Sent,
+			Effective:   f.Properties.Effective,
+			Onset:       f.Properties.Onset,
+			Expires:     f.Properties.Expires,
+			Ends:        f.Properties.Ends,
+			References:  refs,
+			Series:      series,
+		})
+	}
+	return alerts
 }
 
 // GetAlerts fetches active alerts for a lat/lon
-func (c *Client) GetAlerts(lat, lon float64) (*AlertsResponse, error) {
+func (c *Client) GetAlerts(ctx context.Context, lat, lon float64) (*AlertsResponse, error) {
 	url := fmt.Sprintf("https://api.weather.gov/alerts/active?point=%.4f,%.4f", lat, lon)
-	data, err := c.get(url)
+	data, err := c.get(ctx, "alerts", url)
+	if err != nil {
+		return nil, err
+	}
+
+	var al AlertsResponse
+	if err := json.Unmarshal(data, &al); err != nil {
+		return nil, err
+	}
+	return &al, nil
+}
+
+// GetAllAlerts fetches every active alert nationwide
+func (c *Client) GetAllAlerts(ctx context.Context) (*AlertsResponse, error) {
+	data, err := c.get(ctx, "alerts_all", "https://api.weather.gov/alerts/active?status=actual")
+	if err != nil {
+		return nil, err
+	}
+
+	var al AlertsResponse
+	if err := json.Unmarshal(data, &al); err != nil {
+		return nil, err
+	}
+	return &al, nil
+}
+
+// ZoneResponse represents a /zones/{type}/{id} response
+type ZoneResponse struct {
+	ID       string    `json:"id"`
+	Geometry *Geometry `json:"geometry"`
+}
+
+// GetZone fetches a zone by its URL, as listed in an alert's affectedZones
+func (c *Client) GetZone(ctx context.Context, zoneURL string) (*ZoneResponse, error) {
+	data, err := c.get(ctx, "zone", zoneURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var z ZoneResponse
+	if err := json.Unmarshal(data, &z); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+// GetAlertsByArea fetches active alerts for a state or marine area code
+// (e.g. "OK")
+func (c *Client) GetAlertsByArea(ctx context.Context, area string) (*AlertsResponse, error) {
+	url := fmt.Sprintf("https://api.weather.gov/alerts/active?area=%s", url.QueryEscape(area))
+	data, err := c.get(ctx, "alerts_area", url)
 	if err != nil {
 		return nil, err
 	}
@@ -153,20 +341,28 @@ type ObservationStationsResponse struct {
 	} `json:"features"`
 }
 
+// QuantitativeValue is a measured value with its WMO unit code (e.g. "wmoUnit:degC").
+// Value is nil when the station did not report the measurement.
+type QuantitativeValue struct {
+	Value    *float64 `json:"value"`
+	UnitCode string   `json:"unitCode"`
+}
+
 // ObservationResponse represents the /stations/.../observations/latest response
 type ObservationResponse struct {
 	Properties struct {
-		Temperature struct {
-			Value    *float64 `json:"value"`
-			UnitCode string   `json:"unitCode"`
-		} `json:"temperature"`
-		TextDescription string `json:"textDescription"`
+		Temperature           QuantitativeValue `json:"temperature"`
+		TextDescription       string            `json:"textDescription"`
+		BarometricPressure    QuantitativeValue `json:"barometricPressure"`
+		Visibility            QuantitativeValue `json:"visibility"`
+		PrecipitationLastHour QuantitativeValue `json:"precipitationLastHour"`
+		Timestamp             string            `json:"timestamp"` // RFC 3339
 	} `json:"properties"`
 }
 
 // GetObservationStations fetches observation station URLs for a point
-func (c *Client) GetObservationStations(stationsURL string) ([]string, error) {
-	data, err := c.get(stationsURL)
+func (c *Client) GetObservationStations(ctx context.Context, stationsURL string) ([]string, error) {
+	data, err := c.get(ctx, "stations", stationsURL)
 	if err != nil {
 		return nil, err
 	}
@@ -187,9 +383,9 @@ func (c *Client) GetObservationStations(stationsURL string) ([]string, error) {
 }
 
 // GetLatestObservation fetches the latest observation for a station URL
-func (c *Client) GetLatestObservation(stationURL string) (*ObservationResponse, error) {
+func (c *Client) GetLatestObservation(ctx context.Context, stationURL string) (*ObservationResponse, error) {
 	obsURL := strings.TrimRight(stationURL, "/") + "/observations/latest"
-	data, err := c.get(obsURL)
+	data, err := c.get(ctx, "observation", obsURL)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +404,7 @@ type GeocodeResponse []struct {
 }
 
 // Geocode fetches coordinates for a location string using OpenStreetMap
-func (c *Client) Geocode(query string) (float64, float64, error) {
+func (c *Client) Geocode(ctx context.Context, query string) (float64, float64, error) {
 	baseURL := "https://nominatim.openstreetmap.org/search"
 	params := url.Values{}
 	params.Set("q", query)
@@ -216,7 +412,7 @@ func (c *Client) Geocode(query string) (float64, float64, error) {
 	params.Set("limit", "1")
 	requestURL := baseURL + "?" + params.Encode()
 
-	data, err := c.get(requestURL)
+	data, err := c.get(ctx, "nominatim_search", requestURL)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -250,7 +446,7 @@ type ReverseResponse struct {
 }
 
 // ReverseGeocode fetches a human-friendly location name for given coords using OpenStreetMap
-func (c *Client) ReverseGeocode(lat, lon float64) (string, error) {
+func (c *Client) ReverseGeocode(ctx context.Context, lat, lon float64) (string, error) {
 	baseURL := "https://nominatim.openstreetmap.org/reverse"
 	params := url.Values{}
 	params.Set("format", "json")
@@ -260,7 +456,7 @@ func (c *Client) ReverseGeocode(lat, lon float64) (string, error) {
 	params.Set("addressdetails", "1")
 	requestURL := baseURL + "?" + params.Encode()
 
-	data, err := c.get(requestURL)
+	data, err := c.get(ctx, "nominatim_reverse", requestURL)
 	if err != nil {
 		return "", err
 	}
@@ -1,7 +1,10 @@
 package weather
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -26,24 +29,13 @@ func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 // createMockObservation is a helper function to create ObservationResponse instances
 // for testing, reducing code duplication.
 func createMockObservation(tempValue *float64, unitCode, description string) ObservationResponse {
-	return ObservationResponse{
-		Properties: struct {
-			Temperature struct {
-				Value    *float64 `json:"value"`
-				UnitCode string   `json:"unitCode"`
-			} `json:"temperature"`
-			TextDescription string `json:"textDescription"`
-		}{
-			Temperature: struct {
-				Value    *float64 `json:"value"`
-				UnitCode string   `json:"unitCode"`
-			}{
-				Value:    tempValue,
-				UnitCode: unitCode,
-			},
-			TextDescription: description,
-		},
+	var obs ObservationResponse
+	obs.Properties.Temperature = QuantitativeValue{
+		Value:    tempValue,
+		UnitCode: unitCode,
 	}
+	obs.Properties.TextDescription = description
+	return obs
 }
 
 // TestReverseGeocode_CityWithState tests successful reverse geocoding with city and state
@@ -91,7 +83,7 @@ func TestReverseGeocode_CityWithState(t *testing.T) {
 		},
 	}
 
-	result, err := client.ReverseGeocode(37.7749, -122.4194)
+	result, err := client.ReverseGeocode(context.Background(), 37.7749, -122.4194)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -130,7 +122,7 @@ func TestReverseGeocode_TownWithState(t *testing.T) {
 		},
 	}
 
-	result, err := client.ReverseGeocode(30.0, -97.0)
+	result, err := client.ReverseGeocode(context.Background(), 30.0, -97.0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -168,7 +160,7 @@ func TestReverseGeocode_VillageOnly(t *testing.T) {
 		},
 	}
 
-	result, err := client.ReverseGeocode(45.0, 10.0)
+	result, err := client.ReverseGeocode(context.Background(), 45.0, 10.0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -207,7 +199,7 @@ func TestReverseGeocode_CountyFallback(t *testing.T) {
 		},
 	}
 
-	result, err := client.ReverseGeocode(38.0, -117.0)
+	result, err := client.ReverseGeocode(context.Background(), 38.0, -117.0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -243,7 +235,7 @@ func TestReverseGeocode_DisplayNameFallback(t *testing.T) {
 		},
 	}
 
-	result, err := client.ReverseGeocode(0.0, 0.0)
+	result, err := client.ReverseGeocode(context.Background(), 0.0, 0.0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -279,7 +271,7 @@ func TestReverseGeocode_LocationNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := client.ReverseGeocode(999.0, 999.0)
+	_, err := client.ReverseGeocode(context.Background(), 999.0, 999.0)
 	if err == nil {
 		t.Fatal("expected error for location not found, got nil")
 	}
@@ -303,7 +295,7 @@ func TestReverseGeocode_APIError(t *testing.T) {
 		},
 	}
 
-	_, err := client.ReverseGeocode(37.7749, -122.4194)
+	_, err := client.ReverseGeocode(context.Background(), 37.7749, -122.4194)
 	if err == nil {
 		t.Fatal("expected error for API error, got nil")
 	}
@@ -323,7 +315,7 @@ func TestReverseGeocode_InvalidJSON(t *testing.T) {
 		},
 	}
 
-	_, err := client.ReverseGeocode(37.7749, -122.4194)
+	_, err := client.ReverseGeocode(context.Background(), 37.7749, -122.4194)
 	if err == nil {
 		t.Fatal("expected error for invalid JSON, got nil")
 	}
@@ -358,7 +350,7 @@ func TestReverseGeocode_CityPriorityOverTown(t *testing.T) {
 		},
 	}
 
-	result, err := client.ReverseGeocode(37.7749, -122.4194)
+	result, err := client.ReverseGeocode(context.Background(), 37.7749, -122.4194)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -393,7 +385,7 @@ func TestGetObservationStations_Success(t *testing.T) {
 		},
 	}
 
-	stations, err := client.GetObservationStations("https://api.weather.gov/gridpoints/MTR/85,105/stations")
+	stations, err := client.GetObservationStations(context.Background(), "https://api.weather.gov/gridpoints/MTR/85,105/stations")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -428,7 +420,7 @@ func TestGetObservationStations_EmptyList(t *testing.T) {
 		},
 	}
 
-	stations, err := client.GetObservationStations("https://api.weather.gov/gridpoints/MTR/85,105/stations")
+	stations, err := client.GetObservationStations(context.Background(), "https://api.weather.gov/gridpoints/MTR/85,105/stations")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -451,7 +443,7 @@ func TestGetObservationStations_APIError(t *testing.T) {
 		},
 	}
 
-	_, err := client.GetObservationStations("https://api.weather.gov/gridpoints/MTR/85,105/stations")
+	_, err := client.GetObservationStations(context.Background(), "https://api.weather.gov/gridpoints/MTR/85,105/stations")
 	if err == nil {
 		t.Fatal("expected error for API error, got nil")
 	}
@@ -471,7 +463,7 @@ func TestGetObservationStations_InvalidJSON(t *testing.T) {
 		},
 	}
 
-	_, err := client.GetObservationStations("https://api.weather.gov/gridpoints/MTR/85,105/stations")
+	_, err := client.GetObservationStations(context.Background(), "https://api.weather.gov/gridpoints/MTR/85,105/stations")
 	if err == nil {
 		t.Fatal("expected error for invalid JSON, got nil")
 	}
@@ -499,7 +491,7 @@ func TestGetLatestObservation_Success(t *testing.T) {
 		},
 	}
 
-	obs, err := client.GetLatestObservation("https://api.weather.gov/stations/KSFO")
+	obs, err := client.GetLatestObservation(context.Background(), "https://api.weather.gov/stations/KSFO")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -537,7 +529,7 @@ func TestGetLatestObservation_NullTemperature(t *testing.T) {
 		},
 	}
 
-	obs, err := client.GetLatestObservation("https://api.weather.gov/stations/KSFO")
+	obs, err := client.GetLatestObservation(context.Background(), "https://api.weather.gov/stations/KSFO")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -574,7 +566,7 @@ func TestGetLatestObservation_URLTrimming(t *testing.T) {
 	}
 
 	// Test with trailing slash
-	obs, err := client.GetLatestObservation("https://api.weather.gov/stations/KSFO/")
+	obs, err := client.GetLatestObservation(context.Background(), "https://api.weather.gov/stations/KSFO/")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -601,7 +593,7 @@ func TestGetLatestObservation_APIError(t *testing.T) {
 		},
 	}
 
-	_, err := client.GetLatestObservation("https://api.weather.gov/stations/INVALID")
+	_, err := client.GetLatestObservation(context.Background(), "https://api.weather.gov/stations/INVALID")
 	if err == nil {
 		t.Fatal("expected error for API error, got nil")
 	}
@@ -621,8 +613,135 @@ func TestGetLatestObservation_InvalidJSON(t *testing.T) {
 		},
 	}
 
-	_, err := client.GetLatestObservation("https://api.weather.gov/stations/KSFO")
+	_, err := client.GetLatestObservation(context.Background(), "https://api.weather.gov/stations/KSFO")
 	if err == nil {
 		t.Fatal("expected error for invalid JSON, got nil")
 	}
 }
+
+// TestGetAlerts_IDs tests that alerts keep their NWS identifiers
+func TestGetAlerts_IDs(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("point"); got != "39.7817,-89.6501" {
+			t.Errorf("expected point query, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write([]byte(`{"features": [
+			{"id": "https://api.weather.gov/alerts/urn:oid:1", "properties": {"id": "urn:oid:1", "event": "Tornado Warning", "severity": "Extreme"}},
+			{"id": "https://api.weather.gov/alerts/urn:oid:2", "properties": {"event": "Wind Advisory", "severity": "Minor"}}
+		]}`))
+	})
+
+	client := &Client{
+		UserAgent: "test-agent",
+		HTTPClient: &http.Client{
+			Transport: &mockRoundTripper{handler: handler},
+		},
+	}
+
+	al, err := client.GetAlerts(context.Background(), 39.7817, -89.6501)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alerts := al.Alerts()
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(alerts))
+	}
+	if alerts[0].ID != "urn:oid:1" || alerts[0].Event != "Tornado Warning" {
+		t.Errorf("unexpected first alert: %+v", alerts[0])
+	}
+	// Falls back to the feature id when properties.id is missing
+	if alerts[1].ID != "https://api.weather.gov/alerts/urn:oid:2" {
+		t.Errorf("expected feature id fallback, got %q", alerts[1].ID)
+	}
+}
+
+// TestAlerts_UpdateFields tests decoding of alert versioning fields
+func TestAlerts_UpdateFields(t *testing.T) {
+	var al AlertsResponse
+	err := json.Unmarshal([]byte(`{"features": [{"properties": {
+		"id": "urn:oid:2",
+		"event": "Flood Warning",
+		"messageType": "Update",
+		"sent": "2025-06-01T10:15:00-05:00",
+		"expires": "2025-06-01T18:00:00-05:00",
+		"references": [{"identifier": "urn:oid:1", "sent": "2025-06-01T08:00:00-05:00"}]
+	}}]}`), &al)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := al.Alerts()[0]
+	if !a.IsUpdate() || a.MessageType != "Update" {
+		t.Errorf("expected an update, got %+v", a)
+	}
+	if len(a.References) != 1 || a.References[0] != "urn:oid:1" {
+		t.Errorf("expected reference to urn:oid:1, got %v", a.References)
+	}
+	if a.Sent.UTC().Hour() != 15 || a.Expires.UTC().Hour() != 23 {
+		t.Errorf("unexpected times sent=%v expires=%v", a.Sent, a.Expires)
+	}
+	if a.Series != "urn:oid:1" {
+		t.Errorf("expected the first version's ID as the series without VTEC, got %q", a.Series)
+	}
+}
+
+// TestAlerts_Series tests that every version of a VTEC event shares a
+// series, however its references are ordered.
+func TestAlerts_Series(t *testing.T) {
+	var al AlertsResponse
+	err := json.Unmarshal([]byte(`{"features": [
+		{"properties": {"id": "urn:oid:1", "sent": "2025-12-31T22:00:00Z",
+			"parameters": {"VTEC": ["/O.NEW.KOUN.WS.W.0003.251231T2200Z-260101T1800Z/"]}}},
+		{"properties": {"id": "urn:oid:3", "messageType": "Update", "sent": "2026-01-01T06:00:00Z",
+			"references": [{"identifier": "urn:oid:2", "sent": "2026-01-01T02:00:00Z"}, {"identifier": "urn:oid:1", "sent": "2025-12-31T22:00:00Z"}],
+			"parameters": {"VTEC": ["/O.CON.KOUN.WS.W.0003.000000T0000Z-260101T1800Z/"]}}},
+		{"properties": {"id": "urn:oid:4", "messageType": "Update", "sent": "2026-01-01T06:00:00Z",
+			"references": [{"identifier": "urn:oid:1", "sent": "2025-12-31T22:00:00Z"}],
+			"parameters": {"VTEC": ["/O.UPG.KOUN.WS.A.0002.000000T0000Z-260101T1800Z/", "/O.EXA.KOUN.BZ.W.0001.000000T0000Z-260101T1800Z/"]}}}
+	]}`), &al)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alerts := al.Alerts()
+	for i, want := range []string{"KOUN.WS.W.0003.2025", "KOUN.WS.W.0003.2025", "KOUN.BZ.W.0001.2025"} {
+		if alerts[i].Series != want {
+			t.Errorf("alert %d: expected series %q, got %q", i, want, alerts[i].Series)
+		}
+	}
+}
+
+func TestSeverityRank(t *testing.T) {
+	if !(SeverityRank("Extreme") > SeverityRank("Severe") &&
+		SeverityRank("Severe") > SeverityRank("moderate") &&
+		SeverityRank("Moderate") > SeverityRank("Minor") &&
+		SeverityRank("Minor") > SeverityRank("Unknown")) {
+		t.Error("severities out of order")
+	}
+	if SeverityRank("bogus") != -1 {
+		t.Error("expected -1 for unrecognized severity")
+	}
+}
+
+func TestClientDebugLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	c := &Client{HTTPClient: &http.Client{Transport: &mockRoundTripper{handler: handler}}}
+	c.Geocode(context.Background(), "1600 Pennsylvania Ave")
+
+	log := buf.String()
+	if !strings.Contains(log, "host=nominatim.openstreetmap.org") {
+		t.Errorf("expected the host logged, got %q", log)
+	}
+	// Search text mustn't reach the logs.
+	if strings.Contains(log, "Pennsylvania") {
+		t.Errorf("expected the geocoding query left out of the log, got %q", log)
+	}
+}
@@ -0,0 +1,81 @@
+package weather
+
+import "time"
+
+// FeatureCollection is a GeoJSON FeatureCollection of active alerts.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is one alert and the area it covers. Alerts issued by zone have a
+// GeometryCollection of the zone polygons.
+type Feature struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id,omitempty"`
+	Geometry   *Geometry         `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// FeatureProperties are the alert fields carried on each Feature.
+type FeatureProperties struct {
+	ID       string    `json:"id,omitempty"`
+	Event    string    `json:"event"`
+	Severity string    `json:"severity"`
+	Headline string    `json:"headline"`
+	AreaDesc string    `json:"area_desc"`
+	Onset    time.Time `json:"onset"`
+	Expires  time.Time `json:"expires"`
+}
+
+// AlertFilter narrows the alerts returned by AlertIndex.Features.
+type AlertFilter struct {
+	// BBox is minLon, minLat, maxLon, maxLat; nil matches everywhere.
+	BBox *[4]float64
+	// MinSeverity is the lowest SeverityRank included.
+	MinSeverity int
+}
+
+// Features returns the indexed alerts that have a known area and pass the
+// filter as a GeoJSON FeatureCollection.
+func (idx *AlertIndex) Features(filter AlertFilter) *FeatureCollection {
+	fc := &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for _, ia := range idx.alerts {
+		if len(ia.areas) == 0 || SeverityRank(ia.alert.Severity) < filter.MinSeverity {
+			continue
+		}
+		if b := filter.BBox; b != nil && !ia.intersects(b[0], b[1], b[2], b[3]) {
+			continue
+		}
+
+		geometry := ia.areas[0]
+		if len(ia.areas) > 1 {
+			geometry = &Geometry{Type: "GeometryCollection", Geometries: ia.areas}
+		}
+		a := ia.alert
+		fc.Features = append(fc.Features, Feature{
+			Type:     "Feature",
+			ID:       a.ID,
+			Geometry: geometry,
+			Properties: FeatureProperties{
+				ID:       a.ID,
+				Event:    a.Event,
+				Severity: a.Severity,
+				Headline: a.Headline,
+				AreaDesc: a.AreaDesc,
+				Onset:    a.Onset,
+				Expires:  a.Expires,
+			},
+		})
+	}
+	return fc
+}
+
+func (ia indexedAlert) intersects(minLon, minLat, maxLon, maxLat float64) bool {
+	for _, g := range ia.areas {
+		if g.Intersects(minLon, minLat, maxLon, maxLat) {
+			return true
+		}
+	}
+	return false
+}
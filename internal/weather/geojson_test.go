@@ -0,0 +1,59 @@
+package weather
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAlertIndexFeatures(t *testing.T) {
+	var al AlertsResponse
+	if err := json.Unmarshal([]byte(activeAlertsJSON), &al); err != nil {
+		t.Fatalf("failed to decode alerts: %v", err)
+	}
+	zones := map[string]*Geometry{
+		"https://api.weather.gov/zones/forecast/TXZ001": mustGeometry(t, `{"type":"Polygon","coordinates":[[[-103,36],[-102,36],[-102,37],[-103,37],[-103,36]]]}`),
+		"https://api.weather.gov/zones/forecast/TXZ404": mustGeometry(t, `{"type":"Polygon","coordinates":[[[-100,30],[-99,30],[-99,31],[-100,31],[-100,30]]]}`),
+	}
+	idx := NewAlertIndex(&al, zones)
+
+	tests := []struct {
+		name   string
+		filter AlertFilter
+		want   []string
+	}{
+		{"everything", AlertFilter{}, []string{"urn:oid:polygon", "urn:oid:zone"}},
+		{"severity", AlertFilter{MinSeverity: SeverityRank("Severe")}, []string{"urn:oid:polygon"}},
+		{"bbox around zone", AlertFilter{BBox: &[4]float64{-101, 29, -98, 32}}, []string{"urn:oid:zone"}},
+		{"empty bbox", AlertFilter{BBox: &[4]float64{-80, 40, -79, 41}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := idx.Features(tt.filter)
+			if len(fc.Features) != len(tt.want) {
+				t.Fatalf("expected %v, got %+v", tt.want, fc.Features)
+			}
+			for i, f := range fc.Features {
+				if f.ID != tt.want[i] {
+					t.Errorf("feature %d: expected %s, got %s", i, tt.want[i], f.ID)
+				}
+			}
+		})
+	}
+
+	// Zone alerts cover all their zones; the output round trips as GeoJSON.
+	data, err := json.Marshal(idx.Features(AlertFilter{}))
+	if err != nil {
+		t.Fatalf("failed to encode features: %v", err)
+	}
+	var decoded FeatureCollection
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode features: %v", err)
+	}
+	zone := decoded.Features[1]
+	if zone.Geometry.Type != "GeometryCollection" || !zone.Geometry.Contains(30.5, -99.5) {
+		t.Errorf("unexpected zone geometry %s", data)
+	}
+	if zone.Properties.Event != "Heat Advisory" || zone.Properties.Severity != "Moderate" {
+		t.Errorf("unexpected properties %+v", zone.Properties)
+	}
+}
@@ -0,0 +1,119 @@
+package weather
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Geometry is a GeoJSON geometry as returned by the NWS API for alerts and
+// zones. Polygon, MultiPolygon and GeometryCollection are understood for
+// containment tests; other types decode but contain nothing.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []*Geometry     `json:"geometries,omitempty"`
+
+	polygons []polygon
+	// bbox is minLon, minLat, maxLon, maxLat over all polygons.
+	bbox [4]float64
+}
+
+// polygon is a list of linear rings of [lon, lat] positions; the first ring
+// is the outer boundary and any others are holes.
+type polygon [][][2]float64
+
+// UnmarshalJSON decodes the geometry and prepares it for Contains.
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	type plain Geometry
+	if err := json.Unmarshal(data, (*plain)(g)); err != nil {
+		return err
+	}
+	return g.parse()
+}
+
+func (g *Geometry) parse() error {
+	g.polygons = nil
+	switch g.Type {
+	case "Polygon":
+		var p polygon
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return fmt.Errorf("invalid Polygon: %w", err)
+		}
+		g.polygons = []polygon{p}
+	case "MultiPolygon":
+		var ps []polygon
+		if err := json.Unmarshal(g.Coordinates, &ps); err != nil {
+			return fmt.Errorf("invalid MultiPolygon: %w", err)
+		}
+		g.polygons = ps
+	case "GeometryCollection":
+		for _, child := range g.Geometries {
+			if child != nil {
+				g.polygons = append(g.polygons, child.polygons...)
+			}
+		}
+	}
+
+	g.bbox = [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range g.polygons {
+		if len(p) == 0 {
+			continue
+		}
+		for _, pos := range p[0] {
+			g.bbox[0] = math.Min(g.bbox[0], pos[0])
+			g.bbox[1] = math.Min(g.bbox[1], pos[1])
+			g.bbox[2] = math.Max(g.bbox[2], pos[0])
+			g.bbox[3] = math.Max(g.bbox[3], pos[1])
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the point lies inside the geometry. Points on an
+// edge may go either way.
+func (g *Geometry) Contains(lat, lon float64) bool {
+	if g == nil || lon < g.bbox[0] || lat < g.bbox[1] || lon > g.bbox[2] || lat > g.bbox[3] {
+		return false
+	}
+	for _, p := range g.polygons {
+		if p.contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersects reports whether the geometry's bounding box overlaps the box
+// minLon, minLat, maxLon, maxLat.
+func (g *Geometry) Intersects(minLon, minLat, maxLon, maxLat float64) bool {
+	if g == nil || len(g.polygons) == 0 {
+		return false
+	}
+	return g.bbox[0] <= maxLon && g.bbox[2] >= minLon && g.bbox[1] <= maxLat && g.bbox[3] >= minLat
+}
+
+func (p polygon) contains(lat, lon float64) bool {
+	if len(p) == 0 || !ringContains(p[0], lat, lon) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains is the even-odd ray casting test.
+func ringContains(ring [][2]float64, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
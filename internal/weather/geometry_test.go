@@ -0,0 +1,65 @@
+package weather
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustGeometry(t *testing.T, s string) *Geometry {
+	t.Helper()
+	var g Geometry
+	if err := json.Unmarshal([]byte(s), &g); err != nil {
+		t.Fatalf("failed to decode geometry: %v", err)
+	}
+	return &g
+}
+
+func TestGeometryContains(t *testing.T) {
+	// A 2x2 degree square with a 1x1 hole in the middle
+	square := `[[-99,34],[-97,34],[-97,36],[-99,36],[-99,34]]`
+	hole := `[[-98.5,34.5],[-97.5,34.5],[-97.5,35.5],[-98.5,35.5],[-98.5,34.5]]`
+	far := `[[-75,40],[-74,40],[-74,41],[-75,41],[-75,40]]`
+
+	tests := []struct {
+		name     string
+		geometry string
+		lat, lon float64
+		want     bool
+	}{
+		{"inside polygon", `{"type":"Polygon","coordinates":[` + square + `]}`, 35, -98, true},
+		{"outside polygon", `{"type":"Polygon","coordinates":[` + square + `]}`, 37, -98, false},
+		{"in hole", `{"type":"Polygon","coordinates":[` + square + `,` + hole + `]}`, 35, -98, false},
+		{"beside hole", `{"type":"Polygon","coordinates":[` + square + `,` + hole + `]}`, 34.2, -98, true},
+		{"second of multipolygon", `{"type":"MultiPolygon","coordinates":[[` + square + `],[` + far + `]]}`, 40.5, -74.5, true},
+		{"between multipolygon parts", `{"type":"MultiPolygon","coordinates":[[` + square + `],[` + far + `]]}`, 38, -85, false},
+		{"collection", `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[0,0]},{"type":"Polygon","coordinates":[` + far + `]}]}`, 40.5, -74.5, true},
+		{"point never contains", `{"type":"Point","coordinates":[-98,35]}`, 35, -98, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustGeometry(t, tt.geometry).Contains(tt.lat, tt.lon); got != tt.want {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+
+	var nilGeometry *Geometry
+	if nilGeometry.Contains(35, -98) {
+		t.Error("nil geometry should contain nothing")
+	}
+}
+
+func TestGeometry_RoundTrip(t *testing.T) {
+	g := mustGeometry(t, `{"type":"Polygon","coordinates":[[[-99,34],[-97,34],[-97,36],[-99,34]]]}`)
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("failed to encode geometry: %v", err)
+	}
+	if !mustGeometry(t, string(data)).Contains(34.5, -97.5) {
+		t.Errorf("expected re-decoded geometry to keep its shape, got %s", data)
+	}
+	if !g.Intersects(-98, 35, -90, 40) || g.Intersects(-90, 35, -80, 40) {
+		t.Error("unexpected bounding box intersection result")
+	}
+}
@@ -0,0 +1,150 @@
+package weather
+
+import "time"
+
+// haConditions maps mapIcon names to Home Assistant weather conditions.
+// mapIcon doesn't tell light rain from heavy, or rain from a rain and snow
+// mix, so "pouring", "snowy-rainy", "hail" and "windy-variant" are never
+// used; "thermostat", the fallback for NWS icons with no symbol of their
+// own (hot, cold, smoke, dust and so on), becomes "exceptional".
+var haConditions = map[string]string{
+	"sunny":               "sunny",
+	"clear_night":         "clear-night",
+	"partly_cloudy_day":   "partlycloudy",
+	"partly_cloudy_night": "partlycloudy",
+	"cloud":               "cloudy",
+	"rainy":               "rainy",
+	"thunderstorm":        "lightning-rainy",
+	"weather_snowy":       "snowy",
+	"foggy":               "fog",
+	"air":                 "windy",
+	"thermostat":          "exceptional",
+}
+
+// HACondition returns the Home Assistant weather condition for a mapIcon
+// name.
+func HACondition(icon string) string {
+	if c, ok := haConditions[icon]; ok {
+		return c
+	}
+	return "exceptional"
+}
+
+// HAWeather is the weather shaped for a Home Assistant REST sensor feeding
+// a template weather entity. Values are in the units they were converted
+// to, named the way Home Assistant names them, so it can do any further
+// conversion itself.
+type HAWeather struct {
+	Condition                string  `json:"condition"`
+	Temperature              int     `json:"temperature"`
+	ApparentTemperature      *int    `json:"apparent_temperature,omitempty"`
+	TemperatureUnit          string  `json:"temperature_unit"`
+	PrecipitationProbability int     `json:"precipitation_probability"`
+	Humidity                 int     `json:"humidity,omitempty"`
+	WindSpeed                float64 `json:"wind_speed"`
+	WindGustSpeed            float64 `json:"wind_gust_speed,omitempty"`
+	WindBearing              *int    `json:"wind_bearing,omitempty"`
+	WindSpeedUnit            string  `json:"wind_speed_unit,omitempty"`
+	// Pressure and Visibility are observed at the nearest station and
+	// left out when it didn't report them.
+	Pressure       float64      `json:"pressure,omitempty"`
+	PressureUnit   string       `json:"pressure_unit,omitempty"`
+	Visibility     float64      `json:"visibility,omitempty"`
+	VisibilityUnit string       `json:"visibility_unit,omitempty"`
+	Alerts         []string     `json:"alerts"` // Events of the active alerts
+	Location       string       `json:"location,omitempty"`
+	Attribution    string       `json:"attribution"`
+	Updated        time.Time    `json:"updated"`
+	Forecast       []HAForecast `json:"forecast"`
+	ForecastHourly []HAForecast `json:"forecast_hourly"`
+}
+
+// HAForecast is one entry of a Home Assistant forecast list. Datetime is
+// local midnight for daily entries and the start of the hour for hourly
+// ones.
+type HAForecast struct {
+	Datetime                 string `json:"datetime"`
+	Condition                string `json:"condition"`
+	Temperature              int    `json:"temperature"`
+	TempLow                  *int   `json:"templow,omitempty"`
+	ApparentTemperature      *int   `json:"apparent_temperature,omitempty"`
+	PrecipitationProbability int    `json:"precipitation_probability"`
+}
+
+// HomeAssistant returns wd in the Home Assistant shape. Daily forecasts
+// without a date are left out.
+func (wd *WeatherData) HomeAssistant() *HAWeather {
+	c := wd.Current
+	ha := &HAWeather{
+		Condition:                HACondition(c.Icon),
+		Temperature:              c.Temperature,
+		TemperatureUnit:          haTemperatureUnit(c.TemperatureUnit),
+		PrecipitationProbability: c.Precipitation,
+		Humidity:                 c.Humidity,
+		WindSpeed:                c.Wind.Max,
+		WindGustSpeed:            c.Wind.Gust,
+		WindSpeedUnit:            c.Wind.Unit,
+		Pressure:                 c.Pressure,
+		PressureUnit:             c.PressureUnit,
+		Visibility:               c.Visibility,
+		VisibilityUnit:           c.VisibilityUnit,
+		Alerts:                   []string{},
+		Location:                 wd.Location,
+		Attribution:              "Data from the National Weather Service",
+		Updated:                  wd.CachedAt,
+		Forecast:                 []HAForecast{},
+		ForecastHourly:           []HAForecast{},
+	}
+	if c.Wind.Direction != "" {
+		deg := int(c.Wind.Degrees)
+		ha.WindBearing = &deg
+	}
+	if len(wd.HourlySeries) > 0 {
+		feels := wd.HourlySeries[0].FeelsLike
+		ha.ApparentTemperature = &feels
+	}
+	for _, a := range wd.Alerts {
+		if a.MessageType != "Cancel" {
+			ha.Alerts = append(ha.Alerts, a.Event)
+		}
+	}
+
+	loc := time.UTC
+	if l, err := time.LoadLocation(wd.TimeZone); err == nil && wd.TimeZone != "" {
+		loc = l
+	}
+	for _, d := range wd.Forecast {
+		day, err := time.ParseInLocation("2006-01-02", d.Date, loc)
+		if err != nil {
+			continue
+		}
+		low := d.LowTemp
+		ha.Forecast = append(ha.Forecast, HAForecast{
+			Datetime:                 day.Format(time.RFC3339),
+			Condition:                HACondition(d.Icon),
+			Temperature:              d.HighTemp,
+			TempLow:                  &low,
+			PrecipitationProbability: d.PrecipChance,
+		})
+	}
+	for _, p := range wd.HourlySeries {
+		feels := p.FeelsLike
+		ha.ForecastHourly = append(ha.ForecastHourly, HAForecast{
+			Datetime:                 p.Time.Format(time.RFC3339),
+			Condition:                HACondition(p.Icon),
+			Temperature:              p.Temperature,
+			ApparentTemperature:      &feels,
+			PrecipitationProbability: p.PrecipChance,
+		})
+	}
+	return ha
+}
+
+// haTemperatureUnit returns Home Assistant's name for a temperature unit.
+func haTemperatureUnit(unit string) string {
+	switch unit {
+	case UnitFahrenheit, UnitCelsius:
+		return "°" + unit
+	}
+	return unit
+}
@@ -0,0 +1,70 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHACondition(t *testing.T) {
+	tests := map[string]string{
+		"sunny":               "sunny",
+		"clear_night":         "clear-night",
+		"partly_cloudy_night": "partlycloudy",
+		"thunderstorm":        "lightning-rainy",
+		"thermostat":          "exceptional",
+		"":                    "exceptional",
+	}
+	for icon, want := range tests {
+		if got := HACondition(icon); got != want {
+			t.Errorf("HACondition(%q) = %q, want %q", icon, got, want)
+		}
+	}
+}
+
+func TestHomeAssistant(t *testing.T) {
+	cdt := time.FixedZone("CDT", -5*3600)
+	wd := &WeatherData{
+		Current: CurrentCondition{
+			Temperature: 72, TemperatureUnit: "F", Icon: "partly_cloudy_day", Precipitation: 20, Humidity: 45,
+			Wind:     Wind{Min: 5, Max: 10, Gust: 25, Unit: UnitMPH, Direction: "NW", Degrees: 315},
+			Pressure: 30.01, PressureUnit: UnitInHg,
+		},
+		Forecast: []DailyForecast{
+			{Name: "Today", Date: "2025-05-06", HighTemp: 75, LowTemp: 58, TemperatureUnit: "F", Icon: "rainy", PrecipChance: 60},
+			{Name: "Wednesday"},
+		},
+		HourlySeries: []HourlyPoint{
+			{Time: time.Date(2025, 5, 6, 14, 0, 0, 0, cdt), Temperature: 72, FeelsLike: 70, TemperatureUnit: "F", Icon: "sunny", PrecipChance: 10},
+		},
+		Alerts: []Alert{
+			{Event: "Tornado Watch", MessageType: "Alert"},
+			{Event: "Flood Warning", MessageType: "Cancel"},
+		},
+		TimeZone: "America/Chicago",
+		Units:    UnitsUS,
+	}
+
+	ha := wd.HomeAssistant()
+	if ha.Condition != "partlycloudy" || ha.Temperature != 72 || ha.TemperatureUnit != "°F" || *ha.ApparentTemperature != 70 || ha.Humidity != 45 {
+		t.Errorf("unexpected current conditions %+v", ha)
+	}
+	if ha.WindSpeed != 10 || ha.WindGustSpeed != 25 || ha.WindSpeedUnit != "mph" || *ha.WindBearing != 315 || ha.PressureUnit != "inHg" {
+		t.Errorf("unexpected wind or pressure %+v", ha)
+	}
+	if len(ha.Alerts) != 1 || ha.Alerts[0] != "Tornado Watch" {
+		t.Errorf("expected only the active alert, got %v", ha.Alerts)
+	}
+	if len(ha.Forecast) != 1 {
+		t.Fatalf("expected the undated day to be left out, got %+v", ha.Forecast)
+	}
+	if f := ha.Forecast[0]; f.Datetime != "2025-05-06T00:00:00-05:00" || f.Condition != "rainy" || f.Temperature != 75 || *f.TempLow != 58 || f.PrecipitationProbability != 60 {
+		t.Errorf("unexpected daily forecast %+v", f)
+	}
+	if len(ha.ForecastHourly) != 1 || ha.ForecastHourly[0].Datetime != "2025-05-06T14:00:00-05:00" || ha.ForecastHourly[0].Condition != "sunny" {
+		t.Errorf("unexpected hourly forecast %+v", ha.ForecastHourly)
+	}
+
+	if metric := wd.WithUnits(UnitsMetric).HomeAssistant(); metric.TemperatureUnit != "°C" || metric.WindSpeedUnit != "km/h" || metric.PressureUnit != "hPa" {
+		t.Errorf("expected metric units, got %+v", metric)
+	}
+}
@@ -0,0 +1,104 @@
+package weather
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/logging"
+	"github.com/swelljoe/wthr.lol/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	upstreamRequests = metrics.NewCounter("wthr_upstream_requests_total",
+		"Requests to NWS and Nominatim, by endpoint.", "endpoint")
+	upstreamErrors = metrics.NewCounter("wthr_upstream_errors_total",
+		"Failed requests to NWS and Nominatim, by endpoint.", "endpoint")
+	upstreamDuration = metrics.NewHistogram("wthr_upstream_request_duration_seconds",
+		"Time taken by requests to NWS and Nominatim, by endpoint.", nil, "endpoint")
+	cacheLookups = metrics.NewCounter("wthr_weather_cache_lookups_total",
+		"Weather cache lookups by result: hit, miss (nothing cached) or stale (cached but expired).", "result")
+
+	temperatureGauge = metrics.NewGauge("wthr_temperature_celsius",
+		"Current temperature at each exported point.", "location")
+	humidityGauge = metrics.NewGauge("wthr_relative_humidity_percent",
+		"Current relative humidity at each exported point.", "location")
+)
+
+// observeUpstream records a request to endpoint that began at start.
+func observeUpstream(endpoint string, start time.Time, err error) {
+	upstreamRequests.Inc(endpoint)
+	upstreamDuration.Observe(time.Since(start).Seconds(), endpoint)
+	if err != nil {
+		upstreamErrors.Inc(endpoint)
+	}
+}
+
+// noteCache counts a weather cache lookup, notes it for ctx's request log
+// and records it on ctx's span.
+func noteCache(ctx context.Context, result string) {
+	cacheLookups.Inc(result)
+	logging.NoteCache(ctx, result)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("wthr.cache.result", result))
+}
+
+// ConditionsSource fetches the weather for a point; *Service implements
+// it.
+type ConditionsSource interface {
+	GetWeather(lat, lon float64) (*WeatherData, error)
+}
+
+// ConditionsExporter periodically sets the temperature and humidity
+// gauges for each of Points, labelled with its db.PointKey. It reads
+// through the weather cache, so NWS is only asked when that expires.
+type ConditionsExporter struct {
+	weather  ConditionsSource
+	Points   []db.Point
+	Interval time.Duration
+}
+
+// NewConditionsExporter creates a ConditionsExporter that updates every
+// five minutes.
+func NewConditionsExporter(source ConditionsSource) *ConditionsExporter {
+	return &ConditionsExporter{weather: source, Interval: 5 * time.Minute}
+}
+
+// Run updates the gauges until ctx is cancelled.
+func (e *ConditionsExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll updates the gauges for each point once.
+func (e *ConditionsExporter) Poll() {
+	for _, pt := range e.Points {
+		key := db.PointKey(pt.Latitude, pt.Longitude)
+		wd, err := e.weather.GetWeather(pt.Latitude, pt.Longitude)
+		if err != nil {
+			slog.Error("Metrics: failed to get weather", "location", key, "error", err)
+			continue
+		}
+		c := wd.Current
+		// Export the station's reading, not the whole degrees shown.
+		temp, unit := float64(c.Temperature), c.TemperatureUnit
+		if c.ObservedTemperature != nil {
+			temp, unit = *c.ObservedTemperature, c.ObservedTemperatureUnit
+		}
+		temperatureGauge.Set(roundTo(convertTemperature(temp, unit, UnitCelsius), 1), key)
+		if c.Humidity > 0 {
+			humidityGauge.Set(float64(c.Humidity), key)
+		}
+	}
+}
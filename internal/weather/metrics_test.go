@@ -0,0 +1,85 @@
+package weather
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/metrics"
+)
+
+type fakeConditions map[float64]*WeatherData
+
+func (f fakeConditions) GetWeather(lat, lon float64) (*WeatherData, error) {
+	if wd, ok := f[lat]; ok {
+		return wd, nil
+	}
+	return nil, errors.New("upstream error")
+}
+
+func writeMetrics(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := metrics.Default.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestClientMetrics(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/zones/") {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Write([]byte(`{"properties": {"periods": []}}`))
+	})
+	c := &Client{HTTPClient: &http.Client{Transport: &mockRoundTripper{handler: handler}}}
+
+	c.GetForecast(context.Background(), "https://api.weather.gov/gridpoints/OUN/97,94/forecast/hourly")
+	c.GetZone(context.Background(), "https://api.weather.gov/zones/forecast/OKZ025")
+
+	body := writeMetrics(t)
+	for _, want := range []string{
+		`wthr_upstream_requests_total{endpoint="forecast_hourly"}`,
+		`wthr_upstream_request_duration_seconds_count{endpoint="forecast_hourly"}`,
+		`wthr_upstream_errors_total{endpoint="zone"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics", want)
+		}
+	}
+	if strings.Contains(body, `wthr_upstream_errors_total{endpoint="forecast_hourly"}`) {
+		t.Errorf("expected no errors for the successful request")
+	}
+}
+
+func TestConditionsExporter(t *testing.T) {
+	observed := 20.3
+	e := NewConditionsExporter(fakeConditions{
+		35.47: {Current: CurrentCondition{Temperature: 68, TemperatureUnit: "F", Humidity: 40}},
+		// Observed 20.3°C is cached as 69°F, which would be 20.6°C.
+		39.1: {Current: CurrentCondition{Temperature: 69, TemperatureUnit: "F", ObservedTemperature: &observed, ObservedTemperatureUnit: "C"}},
+	})
+	e.Points = []db.Point{{Latitude: 35.47, Longitude: -97.52}, {Latitude: 39.1, Longitude: -94.58}, {Latitude: 1, Longitude: 1}}
+
+	e.Poll()
+
+	body := writeMetrics(t)
+	for _, want := range []string{
+		`wthr_temperature_celsius{location="35.47,-97.52"} 20`,
+		`wthr_relative_humidity_percent{location="35.47,-97.52"} 40`,
+		`wthr_temperature_celsius{location="39.10,-94.58"} 20.3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics", want)
+		}
+	}
+	if strings.Contains(body, `location="1.00,1.00"`) {
+		t.Errorf("expected no gauges for the point that failed")
+	}
+}
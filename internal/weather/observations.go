@@ -0,0 +1,143 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// StationResponse represents the NWS /stations/{id} response
+type StationResponse struct {
+	Properties struct {
+		StationIdentifier string `json:"stationIdentifier"`
+		Name              string `json:"name"`
+		TimeZone          string `json:"timeZone"`
+	} `json:"properties"`
+}
+
+// GetStation fetches metadata for a station URL
+func (c *Client) GetStation(ctx context.Context, stationURL string) (*StationResponse, error) {
+	data, err := c.get(ctx, "station", stationURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var st StationResponse
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Station is an observation station.
+type Station struct {
+	ID       string // e.g. "KOKC"
+	Name     string
+	TimeZone string // IANA time zone name
+}
+
+// Observation is one station observation in metric units. Measurements are
+// nil when the station did not report them.
+type Observation struct {
+	Station        string
+	Time           time.Time
+	Temperature    *float64 // °C
+	PrecipLastHour *float64 // mm
+	Description    string
+}
+
+// StationURL returns the NWS API URL for a station ID.
+func StationURL(id string) string {
+	return "https://api.weather.gov/stations/" + strings.ToUpper(id)
+}
+
+// GetStation returns a station's name and time zone.
+func (s *Service) GetStation(id string) (*Station, error) {
+	st, err := s.client.GetStation(context.Background(), StationURL(id))
+	if err != nil {
+		return nil, err
+	}
+	return &Station{
+		ID:       st.Properties.StationIdentifier,
+		Name:     st.Properties.Name,
+		TimeZone: st.Properties.TimeZone,
+	}, nil
+}
+
+// GetObservation returns a station's latest observation.
+func (s *Service) GetObservation(id string) (*Observation, error) {
+	obs, err := s.client.GetLatestObservation(context.Background(), StationURL(id))
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339, obs.Properties.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid observation time %q: %w", obs.Properties.Timestamp, err)
+	}
+
+	o := &Observation{
+		Station:     strings.ToUpper(id),
+		Time:        t,
+		Description: obs.Properties.TextDescription,
+	}
+	if v := obs.Properties.Temperature.Value; v != nil && !math.IsNaN(*v) {
+		if from := wmoUnit(obs.Properties.Temperature.UnitCode); from == UnitCelsius || from == UnitFahrenheit {
+			c := roundTo(convertTemperature(*v, from, UnitCelsius), 1)
+			o.Temperature = &c
+		}
+	}
+	if v := obs.Properties.PrecipitationLastHour.Value; v != nil && !math.IsNaN(*v) {
+		if mm, ok := convertValue(*v, wmoUnit(obs.Properties.PrecipitationLastHour.UnitCode), UnitMM); ok {
+			o.PrecipLastHour = &mm
+		}
+	}
+	return o, nil
+}
+
+// ForecastIssue is the forecast currently issued for a point, along with
+// the station whose observations verify it.
+type ForecastIssue struct {
+	Station  string // Nearest observation station ID, e.g. "KOKC"
+	TimeZone string
+	Daily    *ForecastResponse
+	Hourly   *ForecastResponse
+}
+
+// GetForecastIssue fetches the daily and hourly forecasts for a point,
+// bypassing the weather cache.
+func (s *Service) GetForecastIssue(lat, lon float64) (*ForecastIssue, error) {
+	ctx := context.Background()
+	pt, err := s.client.GetPointMetadata(ctx, lat, lon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point metadata: %w", err)
+	}
+	stations, err := s.client.GetObservationStations(ctx, pt.Properties.ObservationStations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observation stations: %w", err)
+	}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("no observation stations near %.4f,%.4f", lat, lon)
+	}
+	daily, err := s.client.GetForecast(ctx, pt.Properties.Forecast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+	hourly, err := s.client.GetForecast(ctx, pt.Properties.ForecastHourly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly forecast: %w", err)
+	}
+
+	station := stations[0]
+	if i := strings.LastIndex(station, "/"); i != -1 {
+		station = station[i+1:]
+	}
+	return &ForecastIssue{
+		Station:  station,
+		TimeZone: pt.Properties.TimeZone,
+		Daily:    daily,
+		Hourly:   hourly,
+	}, nil
+}
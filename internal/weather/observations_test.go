@@ -0,0 +1,90 @@
+package weather
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceGetObservation(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stations/KOKC/observations/latest" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"properties": {
+			"timestamp": "2025-05-06T13:53:00+00:00",
+			"textDescription": "Light Rain",
+			"temperature": {"value": 64.4, "unitCode": "wmoUnit:degF"},
+			"precipitationLastHour": {"value": 0.0051, "unitCode": "wmoUnit:m"}
+		}}`))
+	})
+	s := &Service{client: &Client{HTTPClient: &http.Client{Transport: &mockRoundTripper{handler: handler}}}}
+
+	obs, err := s.GetObservation("kokc")
+	if err != nil {
+		t.Fatalf("GetObservation failed: %v", err)
+	}
+	if obs.Station != "KOKC" || !obs.Time.Equal(time.Date(2025, 5, 6, 13, 53, 0, 0, time.UTC)) || obs.Description != "Light Rain" {
+		t.Errorf("unexpected observation %+v", obs)
+	}
+	if obs.Temperature == nil || *obs.Temperature != 18 {
+		t.Errorf("expected 18°C, got %v", obs.Temperature)
+	}
+	if obs.PrecipLastHour == nil || *obs.PrecipLastHour != 5.1 {
+		t.Errorf("expected 5.1 mm, got %v", obs.PrecipLastHour)
+	}
+}
+
+func TestServiceGetStation(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/stations/KOKC") {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"properties": {"stationIdentifier": "KOKC", "name": "Oklahoma City, Will Rogers World Airport", "timeZone": "America/Chicago"}}`))
+	})
+	s := &Service{client: &Client{HTTPClient: &http.Client{Transport: &mockRoundTripper{handler: handler}}}}
+
+	st, err := s.GetStation("KOKC")
+	if err != nil {
+		t.Fatalf("GetStation failed: %v", err)
+	}
+	if st.ID != "KOKC" || st.TimeZone != "America/Chicago" {
+		t.Errorf("unexpected station %+v", st)
+	}
+}
+
+func TestServiceGetForecastIssue(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/points/35.4700,-97.5200":
+			w.Write([]byte(`{"properties": {
+				"forecast": "https://api.weather.gov/gridpoints/OUN/97,94/forecast",
+				"forecastHourly": "https://api.weather.gov/gridpoints/OUN/97,94/forecast/hourly",
+				"observationStations": "https://api.weather.gov/gridpoints/OUN/97,94/stations",
+				"timeZone": "America/Chicago"
+			}}`))
+		case "/gridpoints/OUN/97,94/stations":
+			w.Write([]byte(`{"features": [{"id": "https://api.weather.gov/stations/KOKC"}, {"id": "https://api.weather.gov/stations/KPWA"}]}`))
+		case "/gridpoints/OUN/97,94/forecast":
+			w.Write([]byte(`{"properties": {"updateTime": "2025-05-05T14:00:00-05:00", "periods": [{"name": "This Afternoon", "isDaytime": true, "temperature": 86}]}}`))
+		case "/gridpoints/OUN/97,94/forecast/hourly":
+			w.Write([]byte(`{"properties": {"periods": [{"temperature": 85}, {"temperature": 84}]}}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+	s := &Service{client: &Client{HTTPClient: &http.Client{Transport: &mockRoundTripper{handler: handler}}}}
+
+	issue, err := s.GetForecastIssue(35.47, -97.52)
+	if err != nil {
+		t.Fatalf("GetForecastIssue failed: %v", err)
+	}
+	if issue.Station != "KOKC" || issue.TimeZone != "America/Chicago" {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+	if issue.Daily.Properties.UpdateTime != "2025-05-05T14:00:00-05:00" || len(issue.Hourly.Properties.Periods) != 2 {
+		t.Errorf("unexpected forecasts %+v %+v", issue.Daily, issue.Hourly)
+	}
+}
@@ -1,14 +1,16 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"strings"
 	"time"
 
 	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/tracing"
 )
 
 // Service handles weather business logic and caching
@@ -27,51 +29,91 @@ func NewService(db *db.DB) *Service {
 
 // GetWeather returns weather data for a given location, utilizing caching
 func (s *Service) GetWeather(lat, lon float64) (*WeatherData, error) {
+	return s.GetWeatherContext(context.Background(), lat, lon)
+}
+
+// GetWeatherContext is GetWeather for a request: its logs and upstream
+// calls carry ctx's request ID, and the cache outcome is noted for the
+// request log.
+func (s *Service) GetWeatherContext(ctx context.Context, lat, lon float64) (*WeatherData, error) {
 	// 1. Round coordinates to 2 decimal places (approx 1.1km precision)
 	// This reduces the number of unique cache entries and API hits
 	const precision = 100.0
 	rLat := math.Round(lat*precision) / precision
 	rLon := math.Round(lon*precision) / precision
 
-	// 2. Check cache
-	cached, err := s.db.GetCachedWeather(rLat, rLon)
+	// 2. Check cache. The lookup span ends once the outcome is known.
+	lookupCtx, lookup := tracing.Start(ctx, "weather.cache_lookup")
+	cached, err := s.db.GetCachedWeather(lookupCtx, rLat, rLon)
 	if err != nil {
-		log.Printf("Cache error: %v", err)
+		slog.ErrorContext(ctx, "Cache error", "error", err)
 		// Proceed to fetch fresh data on cache error
 	}
 
 	if cached != nil {
 		var wd WeatherData
 		if err := json.Unmarshal([]byte(cached.Data), &wd); err == nil {
-			wd.CachedAt = cached.CreatedAt
+			// The fetch time stored with the data is more precise than
+			// created_at, and Version depends on it.
+			if wd.CachedAt.IsZero() {
+				wd.CachedAt = cached.CreatedAt
+			}
 			// Ideally we want to know when it expires.
 			wd.ExpiresAt = cached.ExpiresAt
+			wd.Latitude, wd.Longitude = rLat, rLon
+			noteCache(lookupCtx, "hit")
+			lookup.End()
 			return &wd, nil
 		} else {
-			log.Printf("Cache unmarshal error: %v", err)
+			slog.ErrorContext(ctx, "Cache unmarshal error", "error", err)
 		}
 	}
 
-	// 3. Fetch fresh data
-	wd, err := s.fetchFreshWeather(rLat, rLon)
+	// 3. Find the previous forecast, which is still in the cache after it
+	// expires, to compare the fresh one with.
+	prev, err := s.db.GetLastCachedWeather(lookupCtx, rLat, rLon)
+	if err != nil {
+		slog.ErrorContext(ctx, "Cache error", "error", err)
+	}
+	if prev != nil {
+		noteCache(lookupCtx, "stale")
+	} else {
+		noteCache(lookupCtx, "miss")
+	}
+	lookup.End()
+
+	// 4. Fetch fresh data
+	wd, err := s.fetchFreshWeather(ctx, rLat, rLon)
 	if err != nil {
 		return nil, err
 	}
+	wd.Latitude, wd.Longitude = rLat, rLon
+
+	if prev != nil {
+		var old WeatherData
+		if err := json.Unmarshal([]byte(prev.Data), &old); err == nil {
+			wd.Changes = DiffForecasts(&old, wd, time.Now())
+			wd.ChangesSince = old.CachedAt
+		}
+	}
 
-	// 4. Update cache
+	// 5. Update cache
 	data, err := json.Marshal(wd)
 	if err == nil {
-		if err := s.db.SetCachedWeather(rLat, rLon, string(data), 1*time.Hour); err != nil {
-			log.Printf("Failed to update cache: %v", err)
+		if err := s.db.SetCachedWeather(ctx, rLat, rLon, string(data), 1*time.Hour); err != nil {
+			slog.ErrorContext(ctx, "Failed to update cache", "error", err)
 		}
 	}
 
 	return wd, nil
 }
 
-func (s *Service) fetchFreshWeather(lat, lon float64) (*WeatherData, error) {
+func (s *Service) fetchFreshWeather(ctx context.Context, lat, lon float64) (_ *WeatherData, err error) {
+	ctx, span := tracing.Start(ctx, "weather.fetch")
+	defer func() { tracing.End(span, err) }()
+
 	// A. Get Point Metadata to find Forecast URL
-	pt, err := s.client.GetPointMetadata(lat, lon)
+	pt, err := s.client.GetPointMetadata(ctx, lat, lon)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get point metadata: %w", err)
 	}
@@ -79,8 +121,8 @@ func (s *Service) fetchFreshWeather(lat, lon float64) (*WeatherData, error) {
 	// A.1 Get hourly forecast (best effort).
 	var hc *ForecastResponse
 	if pt.Properties.ForecastHourly != "" {
-		if hourly, err := s.client.GetForecast(pt.Properties.ForecastHourly); err != nil {
-			log.Printf("Failed to get hourly forecast: %v", err)
+		if hourly, err := s.client.GetForecast(ctx, pt.Properties.ForecastHourly); err != nil {
+			slog.WarnContext(ctx, "Failed to get hourly forecast", "error", err)
 		} else {
 			hc = hourly
 		}
@@ -89,11 +131,11 @@ func (s *Service) fetchFreshWeather(lat, lon float64) (*WeatherData, error) {
 	// A.2 Get latest observation for current temperature (best effort).
 	var obs *ObservationResponse
 	if pt.Properties.ObservationStations != "" {
-		if stations, err := s.client.GetObservationStations(pt.Properties.ObservationStations); err != nil {
-			log.Printf("Failed to get observation stations: %v", err)
+		if stations, err := s.client.GetObservationStations(ctx, pt.Properties.ObservationStations); err != nil {
+			slog.WarnContext(ctx, "Failed to get observation stations", "error", err)
 		} else if len(stations) > 0 {
-			if latest, err := s.client.GetLatestObservation(stations[0]); err != nil {
-				log.Printf("Failed to get latest observation: %v", err)
+			if latest, err := s.client.GetLatestObservation(ctx, stations[0]); err != nil {
+				slog.WarnContext(ctx, "Failed to get latest observation", "error", err)
 			} else {
 				obs = latest
 			}
@@ -101,18 +143,18 @@ func (s *Service) fetchFreshWeather(lat, lon float64) (*WeatherData, error) {
 	}
 
 	// B. Get Forecast
-	fc, err := s.client.GetForecast(pt.Properties.Forecast)
+	fc, err := s.client.GetForecast(ctx, pt.Properties.Forecast)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get forecast: %w", err)
 	}
 
 	// C. Get Alerts
-	al, err := s.client.GetAlerts(lat, lon)
+	al, err := s.client.GetAlerts(ctx, lat, lon)
 	if err != nil {
 		// Log error but don't fail entire request?
 		// User wants "Display severe weather alerts... if any".
 		// If fails, we assume no alerts or partial failure.
-		log.Printf("Failed to get alerts: %v", err)
+		slog.WarnContext(ctx, "Failed to get alerts", "error", err)
 		al = &AlertsResponse{} // Empty alerts
 	}
 
@@ -123,11 +165,11 @@ func (s *Service) fetchFreshWeather(lat, lon float64) (*WeatherData, error) {
 	}
 
 	// Attempt to reverse geocode to get a friendly location name.
-	if loc, err := s.client.ReverseGeocode(lat, lon); err == nil {
+	if loc, err := s.client.ReverseGeocode(ctx, lat, lon); err == nil {
 		wd.Location = loc
 	} else {
 		// Non-fatal: log and continue without location
-		log.Printf("Reverse geocode error: %v", err)
+		slog.WarnContext(ctx, "Reverse geocode error", "error", err)
 	}
 
 	return wd, nil
@@ -141,6 +183,7 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 		Hourly:    make([]HourlyForecast, 0),
 		Alerts:    make([]Alert, 0),
 		TimeZone:  tz,
+		Units:     UnitsUS,
 	}
 
 	if hc != nil {
@@ -155,10 +198,15 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 				ShortForecast:   p.ShortForecast,
 				Icon:            mapIcon(p.Icon, p.IsDaytime),
 				PrecipChance:    p.ProbabilityOfPrecipitation.Value,
+				Wind:            parseWind(p.WindSpeed, string(p.WindGust), p.WindDirection),
 			})
 		}
 	}
 
+	if hc != nil {
+		wd.HourlySeries = hourlySeries(hc.Properties.Periods)
+	}
+
 	if hc != nil && len(hc.Properties.Periods) > 0 {
 		curr := hc.Properties.Periods[0]
 		wd.Current = CurrentCondition{
@@ -166,8 +214,8 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 			TemperatureUnit: curr.TemperatureUnit,
 			ShortForecast:   curr.ShortForecast,
 			Precipitation:   curr.ProbabilityOfPrecipitation.Value,
-			WindSpeed:       curr.WindSpeed,
-			WindDirection:   curr.WindDirection,
+			Humidity:        int(math.Round(curr.RelativeHumidity.Value)),
+			Wind:            parseWind(curr.WindSpeed, string(curr.WindGust), curr.WindDirection),
 			Icon:            mapIcon(curr.Icon, curr.IsDaytime),
 		}
 	} else if fc != nil && len(fc.Properties.Periods) > 0 {
@@ -177,8 +225,7 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 			TemperatureUnit: curr.TemperatureUnit,
 			ShortForecast:   curr.ShortForecast,
 			Precipitation:   curr.ProbabilityOfPrecipitation.Value,
-			WindSpeed:       curr.WindSpeed,
-			WindDirection:   curr.WindDirection,
+			Wind:            parseWind(curr.WindSpeed, string(curr.WindGust), curr.WindDirection),
 			Icon:            mapIcon(curr.Icon, curr.IsDaytime),
 		}
 	}
@@ -210,6 +257,7 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 				// Create a new day entry
 				day := DailyForecast{
 					Name:            p.Name,
+					Date:            periodDate(p.StartTime),
 					TemperatureUnit: p.TemperatureUnit,
 					Icon:            mapIcon(p.Icon, p.IsDaytime),
 					ShortForecast:   p.ShortForecast,
@@ -253,6 +301,9 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 	if temp, unit, ok := observationTemperature(obs); ok {
 		wd.Current.Temperature = temp
 		wd.Current.TemperatureUnit = unit
+		if v, u, ok := observationReading(obs); ok && (u == UnitCelsius || u == UnitFahrenheit) {
+			wd.Current.ObservedTemperature, wd.Current.ObservedTemperatureUnit = &v, u
+		}
 
 		// If no hourly or daily forecast data is available, use the observation
 		// temperature as a fallback for high and low to avoid misleading 0° values.
@@ -264,20 +315,24 @@ func transform(fc *ForecastResponse, hc *ForecastResponse, al *AlertsResponse, o
 		}
 	}
 
+	observationMeasurements(obs, &wd.Current)
+
 	// Alerts
-	for _, feature := range al.Features {
-		wd.Alerts = append(wd.Alerts, Alert{
-			Event:       feature.Properties.Event,
-			Headline:    feature.Properties.Headline,
-			Description: feature.Properties.Description,
-			Severity:    feature.Properties.Severity,
-			AreaDesc:    feature.Properties.AreaDesc,
-		})
-	}
+	wd.Alerts = append(wd.Alerts, al.Alerts()...)
 
 	return wd, nil
 }
 
+// periodDate returns the local calendar date of a period's start time. NWS
+// times carry the location's UTC offset, so no time zone lookup is needed.
+func periodDate(startTime string) string {
+	t, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
 func formatHourlyLabel(startTime, fallback, tz string) string {
 	if startTime == "" {
 		return fallback
@@ -299,7 +354,22 @@ func formatHourlyLabel(startTime, fallback, tz string) string {
 	return t.In(time.UTC).Format("3 PM MST")
 }
 
+// observationTemperature returns the observed temperature rounded to a whole
+// degree Fahrenheit, like the forecast temperatures it's shown with.
 func observationTemperature(obs *ObservationResponse) (int, string, bool) {
+	temp, unit, ok := observationReading(obs)
+	if !ok {
+		return 0, "", false
+	}
+	if unit == UnitCelsius {
+		return int(math.Round(convertTemperature(temp, UnitCelsius, UnitFahrenheit))), UnitFahrenheit, true
+	}
+	return int(math.Round(temp)), unit, true
+}
+
+// observationReading returns the observed temperature as the station
+// reported it, with its unit symbol.
+func observationReading(obs *ObservationResponse) (float64, string, bool) {
 	if obs == nil {
 		return 0, "", false
 	}
@@ -311,22 +381,40 @@ func observationTemperature(obs *ObservationResponse) (int, string, bool) {
 	unitCode := obs.Properties.Temperature.UnitCode
 	switch {
 	case strings.HasSuffix(unitCode, "degC"):
-		f := (*temp * 9.0 / 5.0) + 32.0
-		return int(math.Round(f)), "F", true
+		return *temp, UnitCelsius, true
 	case strings.HasSuffix(unitCode, "degF"):
-		return int(math.Round(*temp)), "F", true
+		return *temp, UnitFahrenheit, true
 	default:
 		// Fallback: extract suffix from compound unit codes like "wmoUnit:degC"
 		displayUnit := unitCode
 		if idx := strings.LastIndex(unitCode, ":"); idx != -1 && idx+1 < len(unitCode) {
 			displayUnit = unitCode[idx+1:]
 		} else {
-			log.Printf("weather: unrecognized temperature unitCode format: %q", unitCode)
+			slog.Warn("Unrecognized temperature unitCode format", "unit_code", unitCode)
 		}
-		return int(math.Round(*temp)), displayUnit, true
+		return *temp, displayUnit, true
 	}
 }
 
+// observationMeasurements copies pressure, visibility and precipitation from
+// the observation into c as reported, leaving WithUnits to convert them
+// once for display.
+func observationMeasurements(obs *ObservationResponse, c *CurrentCondition) {
+	if obs == nil {
+		return
+	}
+	measure := func(q QuantitativeValue) (float64, string) {
+		unit := wmoUnit(q.UnitCode)
+		if _, known := toBase[unit]; q.Value == nil || math.IsNaN(*q.Value) || !known {
+			return 0, ""
+		}
+		return *q.Value, unit
+	}
+	c.Pressure, c.PressureUnit = measure(obs.Properties.BarometricPressure)
+	c.Visibility, c.VisibilityUnit = measure(obs.Properties.Visibility)
+	c.PrecipLastHour, c.PrecipLastHourUnit = measure(obs.Properties.PrecipitationLastHour)
+}
+
 // mapIcon maps NWS icon URL or forecast description to Material Symbol name
 func mapIcon(iconURL string, isDaytime bool) string {
 	// Basic mapping based on keywords
@@ -366,5 +454,40 @@ func mapIcon(iconURL string, isDaytime bool) string {
 
 // Geocode resolves a location string to coordinates
 func (s *Service) Geocode(query string) (float64, float64, error) {
-	return s.client.Geocode(query)
+	return s.GeocodeContext(context.Background(), query)
+}
+
+// GeocodeContext is Geocode for a request.
+func (s *Service) GeocodeContext(ctx context.Context, query string) (float64, float64, error) {
+	return s.client.Geocode(ctx, query)
+}
+
+// GetAlerts fetches the currently active alerts for a point, bypassing the
+// weather cache so background pollers see new alerts promptly.
+func (s *Service) GetAlerts(lat, lon float64) ([]Alert, error) {
+	return s.GetAlertsContext(context.Background(), lat, lon)
+}
+
+// GetAlertsContext is GetAlerts for a request.
+func (s *Service) GetAlertsContext(ctx context.Context, lat, lon float64) ([]Alert, error) {
+	al, err := s.client.GetAlerts(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	return al.Alerts(), nil
+}
+
+// GetAlertsByArea fetches the currently active alerts for a state or marine
+// area code.
+func (s *Service) GetAlertsByArea(area string) ([]Alert, error) {
+	return s.GetAlertsByAreaContext(context.Background(), area)
+}
+
+// GetAlertsByAreaContext is GetAlertsByArea for a request.
+func (s *Service) GetAlertsByAreaContext(ctx context.Context, area string) ([]Alert, error) {
+	al, err := s.client.GetAlertsByArea(ctx, area)
+	if err != nil {
+		return nil, err
+	}
+	return al.Alerts(), nil
 }
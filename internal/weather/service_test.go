@@ -424,21 +424,7 @@ func createMockForecastResponse(periods []struct {
 }) *ForecastResponse {
 	fc := &ForecastResponse{}
 	for _, p := range periods {
-		fc.Properties.Periods = append(fc.Properties.Periods, struct {
-			Name                       string `json:"name"`
-			StartTime                  string `json:"startTime"`
-			IsDaytime                  bool   `json:"isDaytime"`
-			Temperature                int    `json:"temperature"`
-			TemperatureUnit            string `json:"temperatureUnit"`
-			ProbabilityOfPrecipitation struct {
-				Value int `json:"value"`
-			} `json:"probabilityOfPrecipitation"`
-			WindSpeed        string `json:"windSpeed"`
-			WindDirection    string `json:"windDirection"`
-			Icon             string `json:"icon"`
-			ShortForecast    string `json:"shortForecast"`
-			DetailedForecast string `json:"detailedForecast"`
-		}{
+		fc.Properties.Periods = append(fc.Properties.Periods, ForecastPeriod{
 			Name:            p.Name,
 			StartTime:       p.StartTime,
 			IsDaytime:       p.IsDaytime,
@@ -448,26 +434,14 @@ func createMockForecastResponse(periods []struct {
 			WindDirection:   p.WindDir,
 			Icon:            p.Icon,
 			ShortForecast:   p.ShortFcst,
-			ProbabilityOfPrecipitation: struct {
-				Value int `json:"value"`
-			}{Value: p.PrecipValue},
 		})
+		fc.Properties.Periods[len(fc.Properties.Periods)-1].ProbabilityOfPrecipitation.Value = p.PrecipValue
 	}
 	return fc
 }
 
 func createMockAlertsResponse() *AlertsResponse {
-	return &AlertsResponse{
-		Features: []struct {
-			Properties struct {
-				Event       string `json:"event"`
-				Headline    string `json:"headline"`
-				Description string `json:"description"`
-				Severity    string `json:"severity"`
-				AreaDesc    string `json:"areaDesc"`
-			} `json:"properties"`
-		}{},
-	}
+	return &AlertsResponse{Features: []AlertFeature{}}
 }
 
 // TestTransform_HourlyNil tests transform when hourly forecast (hc) is nil
@@ -627,8 +601,8 @@ func TestTransform_BothHourlyAndObservationPresent(t *testing.T) {
 	if wd.Current.ShortForecast != "Cloudy" {
 		t.Errorf("Expected Current.ShortForecast to be 'Cloudy' from hc, got %s", wd.Current.ShortForecast)
 	}
-	if wd.Current.WindSpeed != "5 mph" {
-		t.Errorf("Expected Current.WindSpeed to be '5 mph' from hc, got %s", wd.Current.WindSpeed)
+	if wd.Current.Wind.Display != "5 mph" {
+		t.Errorf("Expected Current.Wind.Display to be '5 mph' from hc, got %s", wd.Current.Wind.Display)
 	}
 }
 
@@ -665,11 +639,17 @@ func TestTransform_CurrentFromHourly(t *testing.T) {
 	if wd.Current.ShortForecast != "Partly Cloudy" {
 		t.Errorf("Expected Current.ShortForecast to be 'Partly Cloudy', got %s", wd.Current.ShortForecast)
 	}
-	if wd.Current.WindSpeed != "8 mph" {
-		t.Errorf("Expected Current.WindSpeed to be '8 mph', got %s", wd.Current.WindSpeed)
+	if wd.Current.Wind.Display != "8 mph" {
+		t.Errorf("Expected Current.Wind.Display to be '8 mph', got %s", wd.Current.Wind.Display)
+	}
+	if wd.Current.Wind.Min != 8 || wd.Current.Wind.Max != 8 || wd.Current.Wind.Unit != "mph" {
+		t.Errorf("Expected parsed wind speed 8-8 mph, got %v-%v %s", wd.Current.Wind.Min, wd.Current.Wind.Max, wd.Current.Wind.Unit)
+	}
+	if wd.Current.Wind.Degrees != 225 {
+		t.Errorf("Expected Current.Wind.Degrees to be 225 for SW, got %v", wd.Current.Wind.Degrees)
 	}
-	if wd.Current.WindDirection != "SW" {
-		t.Errorf("Expected Current.WindDirection to be 'SW', got %s", wd.Current.WindDirection)
+	if wd.Current.Wind.Direction != "SW" {
+		t.Errorf("Expected Current.Wind.Direction to be 'SW', got %s", wd.Current.Wind.Direction)
 	}
 	if wd.Current.Precipitation != 15 {
 		t.Errorf("Expected Current.Precipitation to be 15, got %d", wd.Current.Precipitation)
@@ -709,8 +689,8 @@ func TestTransform_CurrentFallbackToForecast(t *testing.T) {
 	if wd.Current.ShortForecast != "Sunny" {
 		t.Errorf("Expected Current.ShortForecast to be 'Sunny', got %s", wd.Current.ShortForecast)
 	}
-	if wd.Current.WindSpeed != "12 mph" {
-		t.Errorf("Expected Current.WindSpeed to be '12 mph', got %s", wd.Current.WindSpeed)
+	if wd.Current.Wind.Display != "12 mph" {
+		t.Errorf("Expected Current.Wind.Display to be '12 mph', got %s", wd.Current.Wind.Display)
 	}
 }
 
@@ -805,8 +785,8 @@ func TestTransform_ObservationOverridesCurrentTemperature(t *testing.T) {
 	if wd.Current.ShortForecast != "Cloudy" {
 		t.Errorf("Expected Current.ShortForecast to remain 'Cloudy' from hc, got %s", wd.Current.ShortForecast)
 	}
-	if wd.Current.WindSpeed != "10 mph" {
-		t.Errorf("Expected Current.WindSpeed to remain '10 mph' from hc, got %s", wd.Current.WindSpeed)
+	if wd.Current.Wind.Display != "10 mph" {
+		t.Errorf("Expected Current.Wind.Display to remain '10 mph' from hc, got %s", wd.Current.Wind.Display)
 	}
 }
 
@@ -866,3 +846,35 @@ func TestTransform_TimezonePropagation(t *testing.T) {
 		t.Errorf("Expected Hourly[0].Name to be '7 AM PST', got %s", wd.Hourly[0].Name)
 	}
 }
+
+// TestTransform_ForecastDates checks days are dated in the location's own
+// offset rather than UTC.
+func TestTransform_ForecastDates(t *testing.T) {
+	fc := createMockForecastResponse([]struct {
+		Name        string
+		StartTime   string
+		IsDaytime   bool
+		Temperature int
+		Unit        string
+		WindSpeed   string
+		WindDir     string
+		Icon        string
+		ShortFcst   string
+		PrecipValue int
+	}{
+		{Name: "Tonight", StartTime: "2024-01-15T20:00:00-06:00", IsDaytime: false, Temperature: 40, Unit: "F", Icon: "https://api.weather.gov/icons/land/night/few", ShortFcst: "Clear"},
+		{Name: "Tuesday", StartTime: "2024-01-16T06:00:00-06:00", IsDaytime: true, Temperature: 60, Unit: "F", Icon: "https://api.weather.gov/icons/land/day/sct", ShortFcst: "Sunny"},
+		{Name: "Tuesday Night", StartTime: "2024-01-16T18:00:00-06:00", IsDaytime: false, Temperature: 42, Unit: "F", Icon: "https://api.weather.gov/icons/land/night/sct", ShortFcst: "Clear"},
+	})
+
+	wd, err := transform(fc, nil, createMockAlertsResponse(), nil, "America/Chicago")
+	if err != nil {
+		t.Fatalf("transform failed: %v", err)
+	}
+	if len(wd.Forecast) != 2 {
+		t.Fatalf("Expected 2 days, got %d", len(wd.Forecast))
+	}
+	if wd.Forecast[0].Date != "2024-01-15" || wd.Forecast[1].Date != "2024-01-16" {
+		t.Errorf("Unexpected dates %q, %q", wd.Forecast[0].Date, wd.Forecast[1].Date)
+	}
+}
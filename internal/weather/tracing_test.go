@@ -0,0 +1,72 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestClientSpans(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/zones/") {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Write([]byte(`{"properties": {"periods": []}}`))
+	})
+	c := &Client{HTTPClient: &http.Client{Transport: &mockRoundTripper{handler: handler}}}
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	c.GetForecast(ctx, "https://api.weather.gov/gridpoints/OUN/97,94/forecast/hourly")
+	c.GetZone(ctx, "https://api.weather.gov/zones/forecast/OKZ025")
+	c.Geocode(ctx, "1600 Pennsylvania Ave")
+	parent.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+	forecast, zone, geocode := spans[0], spans[1], spans[2]
+	if forecast.Name != "GET forecast_hourly" || zone.Name != "GET zone" {
+		t.Errorf("unexpected span names %q, %q", forecast.Name, zone.Name)
+	}
+	for _, s := range []tracetest.SpanStub{forecast, zone} {
+		if s.Parent.SpanID() != spans[3].SpanContext.SpanID() {
+			t.Errorf("expected %s to be a child of the caller's span", s.Name)
+		}
+	}
+	if !hasAttr(forecast.Attributes, attribute.Int("http.response.status_code", http.StatusOK)) ||
+		!hasAttr(forecast.Attributes, attribute.String("server.address", "api.weather.gov")) {
+		t.Errorf("unexpected forecast attributes %v", forecast.Attributes)
+	}
+	// Search text mustn't reach the trace collector.
+	for _, a := range geocode.Attributes {
+		if strings.Contains(a.Value.Emit(), "Pennsylvania") {
+			t.Errorf("expected the geocoding query left out of the span, got %v", a)
+		}
+	}
+	if forecast.Status.Code != codes.Unset || zone.Status.Code != codes.Error {
+		t.Errorf("expected only the zone request to fail, got %v and %v", forecast.Status, zone.Status)
+	}
+}
+
+func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
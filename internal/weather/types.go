@@ -1,33 +1,76 @@
 package weather
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
 
 // WeatherData aggregates all weather info
 type WeatherData struct {
-	Current   CurrentCondition `json:"current"`
-	Forecast  []DailyForecast  `json:"forecast"`
-	Hourly    []HourlyForecast `json:"hourly"`
-	Alerts    []Alert          `json:"alerts"`
-	CachedAt  time.Time        `json:"cached_at"`
-	ExpiresAt time.Time        `json:"expires_at"`
-	Location  string           `json:"location,omitempty"`
-	TimeZone  string           `json:"time_zone,omitempty"`
+	Current  CurrentCondition `json:"current"`
+	Forecast []DailyForecast  `json:"forecast"`
+	Hourly   []HourlyForecast `json:"hourly"`
+	// HourlySeries is the next 48 hours, for the hourly charts.
+	HourlySeries []HourlyPoint `json:"hourly_series,omitempty"`
+	Alerts       []Alert       `json:"alerts"`
+	CachedAt     time.Time     `json:"cached_at"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	Location     string        `json:"location,omitempty"`
+	Latitude     float64       `json:"latitude"`  // Rounded cache coordinates
+	Longitude    float64       `json:"longitude"` // Rounded cache coordinates
+	TimeZone     string        `json:"time_zone,omitempty"`
+	Units        Units         `json:"units"`
+	// Changes lists what changed since the previous forecast for this
+	// location, fetched at ChangesSince.
+	Changes      []ForecastChange `json:"changes,omitempty"`
+	ChangesSince time.Time        `json:"changes_since,omitzero"`
+}
+
+// Version identifies a snapshot of the weather for a location. It changes
+// when the cache is refreshed or the set of active alerts changes, and not
+// with the unit system it's displayed in.
+func (wd *WeatherData) Version() string {
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatInt(wd.CachedAt.UnixNano(), 10)))
+	for _, a := range wd.Alerts {
+		h.Write([]byte{0})
+		h.Write([]byte(a.ID))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
 }
 
 type CurrentCondition struct {
 	Temperature     int    `json:"temperature"`
 	TemperatureUnit string `json:"temperature_unit"`
-	ShortForecast   string `json:"short_forecast"`
-	Precipitation   int    `json:"precipitation_chance"`
-	WindSpeed       string `json:"wind_speed"`
-	WindDirection   string `json:"wind_direction"`
-	Icon            string `json:"icon"`
-	HighTemp        int    `json:"high_temp"`
-	LowTemp         int    `json:"low_temp"`
+	// ObservedTemperature is the station's reading at the precision it was
+	// reported, so WithUnits converts it only once; nil when Temperature
+	// comes from the forecast.
+	ObservedTemperature     *float64 `json:"observed_temperature,omitempty"`
+	ObservedTemperatureUnit string   `json:"observed_temperature_unit,omitempty"`
+	ShortForecast           string   `json:"short_forecast"`
+	Precipitation           int      `json:"precipitation_chance"`
+	Humidity                int      `json:"humidity,omitempty"` // Relative humidity, %, from the hourly forecast
+	Wind                    Wind     `json:"wind"`
+	Icon                    string   `json:"icon"`
+	HighTemp                int      `json:"high_temp"`
+	LowTemp                 int      `json:"low_temp"`
+
+	// Observed values from the nearest station, cached in the units NWS
+	// reported them in (Pa, m and mm). Each unit field is empty when the
+	// station did not report that value.
+	Pressure           float64 `json:"pressure,omitempty"`
+	PressureUnit       string  `json:"pressure_unit,omitempty"`
+	Visibility         float64 `json:"visibility,omitempty"`
+	VisibilityUnit     string  `json:"visibility_unit,omitempty"`
+	PrecipLastHour     float64 `json:"precip_last_hour,omitempty"`
+	PrecipLastHourUnit string  `json:"precip_last_hour_unit,omitempty"`
 }
 
 type DailyForecast struct {
-	Name            string `json:"name"` // e.g., "Monday"
+	Name            string `json:"name"`           // e.g., "Monday"
+	Date            string `json:"date,omitempty"` // Local date, "2006-01-02"
 	HighTemp        int    `json:"high_temp"`
 	LowTemp         int    `json:"low_temp"`
 	TemperatureUnit string `json:"temperature_unit"`
@@ -44,12 +87,50 @@ type HourlyForecast struct {
 	ShortForecast   string `json:"short_forecast"`
 	Icon            string `json:"icon"`
 	PrecipChance    int    `json:"precip_chance"`
+	Wind            Wind   `json:"wind"`
+}
+
+// HourlyPoint is one hour of WeatherData.HourlySeries. Time carries the
+// location's UTC offset.
+type HourlyPoint struct {
+	Time            time.Time `json:"time"`
+	Temperature     int       `json:"temperature"`
+	FeelsLike       int       `json:"feels_like"`
+	TemperatureUnit string    `json:"temperature_unit"`
+	Icon            string    `json:"icon"`
+	PrecipChance    int       `json:"precip_chance"`
+}
+
+// Wind is a wind forecast parsed from the NWS windSpeed, windGust and
+// windDirection strings (e.g. "5 to 10 mph", "25 mph", "NW").
+type Wind struct {
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Gust      float64 `json:"gust,omitempty"`
+	Unit      string  `json:"unit,omitempty"` // Empty when the speed could not be parsed
+	Direction string  `json:"direction,omitempty"`
+	Degrees   float64 `json:"degrees"` // Only meaningful when Direction is set
+	Display   string  `json:"display"` // Formatted speed, e.g. "5 to 10 mph, gusts 25 mph"
 }
 
 type Alert struct {
+	ID          string `json:"id,omitempty"` // NWS alert identifier (a URN)
 	Event       string `json:"event"`
 	Headline    string `json:"headline"`
 	Description string `json:"description"`
+	Instruction string `json:"instruction,omitempty"`
 	Severity    string `json:"severity"`
 	AreaDesc    string `json:"area_desc"`
+	// MessageType is "Alert" for a new alert and "Update" or "Cancel" for a
+	// later version; References lists the IDs of the versions it replaces.
+	MessageType string    `json:"message_type,omitempty"`
+	Sent        time.Time `json:"sent"`
+	Effective   time.Time `json:"effective"`
+	Onset       time.Time `json:"onset"` // When the hazard begins; zero if not given
+	Expires     time.Time `json:"expires"`
+	Ends        time.Time `json:"ends"` // When the hazard ends; zero if not given
+	References  []string  `json:"references,omitempty"`
+	// Series is the same for every version of an alert: its VTEC event and
+	// the year it was first issued, or the ID of its first version.
+	Series string `json:"series,omitempty"`
 }
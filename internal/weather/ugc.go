@@ -0,0 +1,105 @@
+package weather
+
+import "strings"
+
+// ugcAreaBounds holds rough bounds of each state, territory and marine area
+// by the two letter prefix of its UGC codes, as minLon, minLat, maxLon,
+// maxLat. A minLon greater than maxLon crosses the antimeridian.
+var ugcAreaBounds = map[string][4]float64{
+	"AL": {-88.5, 30.1, -84.9, 35.0},
+	"AK": {172.0, 51.2, -129.9, 71.5},
+	"AZ": {-114.8, 31.3, -109.0, 37.0},
+	"AR": {-94.6, 33.0, -89.6, 36.5},
+	"CA": {-124.5, 32.5, -114.1, 42.0},
+	"CO": {-109.1, 37.0, -102.0, 41.0},
+	"CT": {-73.7, 40.9, -71.8, 42.1},
+	"DE": {-75.8, 38.4, -75.0, 39.8},
+	"DC": {-77.1, 38.8, -76.9, 39.0},
+	"FL": {-87.6, 24.4, -80.0, 31.0},
+	"GA": {-85.6, 30.4, -80.8, 35.0},
+	"HI": {-160.3, 18.9, -154.8, 22.3},
+	"ID": {-117.3, 42.0, -111.0, 49.0},
+	"IL": {-91.6, 36.9, -87.0, 42.5},
+	"IN": {-88.1, 37.7, -84.8, 41.8},
+	"IA": {-96.7, 40.3, -90.1, 43.5},
+	"KS": {-102.1, 37.0, -94.6, 40.0},
+	"KY": {-89.6, 36.5, -81.9, 39.2},
+	"LA": {-94.1, 28.9, -88.8, 33.0},
+	"ME": {-71.1, 43.0, -66.9, 47.5},
+	"MD": {-79.5, 37.9, -75.0, 39.8},
+	"MA": {-73.5, 41.2, -69.9, 42.9},
+	"MI": {-90.4, 41.7, -82.1, 48.3},
+	"MN": {-97.3, 43.5, -89.5, 49.4},
+	"MS": {-91.7, 30.1, -88.1, 35.0},
+	"MO": {-95.8, 36.0, -89.1, 40.6},
+	"MT": {-116.1, 44.4, -104.0, 49.0},
+	"NE": {-104.1, 40.0, -95.3, 43.0},
+	"NV": {-120.0, 35.0, -114.0, 42.0},
+	"NH": {-72.6, 42.7, -70.6, 45.3},
+	"NJ": {-75.6, 38.9, -73.9, 41.4},
+	"NM": {-109.1, 31.3, -103.0, 37.0},
+	"NY": {-79.8, 40.5, -71.8, 45.0},
+	"NC": {-84.3, 33.8, -75.4, 36.6},
+	"ND": {-104.1, 45.9, -96.6, 49.0},
+	"OH": {-84.8, 38.4, -80.5, 42.3},
+	"OK": {-103.0, 33.6, -94.4, 37.0},
+	"OR": {-124.6, 41.9, -116.5, 46.3},
+	"PA": {-80.5, 39.7, -74.7, 42.3},
+	"RI": {-71.9, 41.1, -71.1, 42.0},
+	"SC": {-83.4, 32.0, -78.5, 35.2},
+	"SD": {-104.1, 42.5, -96.4, 45.9},
+	"TN": {-90.3, 35.0, -81.6, 36.7},
+	"TX": {-106.6, 25.8, -93.5, 36.5},
+	"UT": {-114.1, 37.0, -109.0, 42.0},
+	"VT": {-73.4, 42.7, -71.5, 45.0},
+	"VA": {-83.7, 36.5, -75.2, 39.5},
+	"WA": {-124.8, 45.5, -116.9, 49.0},
+	"WV": {-82.6, 37.2, -77.7, 40.6},
+	"WI": {-92.9, 42.5, -86.2, 47.1},
+	"WY": {-111.1, 41.0, -104.1, 45.0},
+	"PR": {-67.3, 17.9, -65.2, 18.5},
+	"VI": {-65.1, 17.6, -64.5, 18.4},
+	"GU": {144.6, 13.2, 145.0, 13.7},
+	"MP": {144.9, 14.1, 146.1, 20.6},
+	"AS": {-171.1, -14.6, -168.1, -11.0},
+
+	"AM": {-83.0, 17.0, -64.0, 37.0},
+	"AN": {-77.0, 36.0, -66.0, 46.0},
+	"GM": {-98.0, 23.0, -80.0, 31.0},
+	"PZ": {-131.0, 30.0, -117.0, 49.0},
+	"PK": {170.0, 50.0, -129.0, 72.0},
+	"PH": {-165.0, 15.0, -150.0, 26.0},
+	"PM": {144.0, 12.0, 147.0, 21.0},
+	"PS": {-172.0, -15.0, -168.0, -10.0},
+	"LC": {-83.0, 42.2, -82.3, 42.8},
+	"LE": {-83.5, 41.3, -78.8, 43.0},
+	"LH": {-84.8, 43.0, -82.0, 46.3},
+	"LM": {-88.1, 41.6, -84.7, 46.1},
+	"LO": {-80.0, 43.1, -76.0, 44.3},
+	"LS": {-92.2, 46.4, -84.3, 49.0},
+	"SL": {-76.4, 44.1, -74.6, 45.1},
+}
+
+// ugcBoundsMargin widens ugcAreaBounds, in degrees, so that points near a
+// border count as possibly on either side.
+const ugcBoundsMargin = 0.5
+
+// ugcMayContain reports whether a point could lie in the zone or county with
+// the UGC code (e.g. "TXZ404"), judging by the bounds of its state or marine
+// area. A code from an unknown area may contain any point.
+func ugcMayContain(code string, lat, lon float64) bool {
+	if len(code) < 2 {
+		return true
+	}
+	b, ok := ugcAreaBounds[strings.ToUpper(code[:2])]
+	if !ok {
+		return true
+	}
+	if lat < b[1]-ugcBoundsMargin || lat > b[3]+ugcBoundsMargin {
+		return false
+	}
+	if b[0] > b[2] {
+		return lon >= b[0]-ugcBoundsMargin || lon <= b[2]+ugcBoundsMargin
+	}
+	return lon >= b[0]-ugcBoundsMargin && lon <= b[2]+ugcBoundsMargin
+}
@@ -0,0 +1,261 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Units selects the measurement system used when presenting WeatherData.
+// Forecasts are cached in UnitsUS (what NWS returns) and station
+// observations as reported, and both are converted on the way out with
+// WithUnits.
+type Units string
+
+const (
+	// UnitsUS is °F, mph, inHg, inches and miles.
+	UnitsUS Units = "us"
+	// UnitsMetric is °C, km/h, hPa, millimetres and kilometres.
+	UnitsMetric Units = "metric"
+	// UnitsSI is UnitsMetric with wind speed in m/s.
+	UnitsSI Units = "si"
+)
+
+// Unit symbols used in the *Unit fields of WeatherData.
+const (
+	UnitFahrenheit = "F"
+	UnitCelsius    = "C"
+	UnitMPH        = "mph"
+	UnitKPH        = "km/h"
+	UnitMPS        = "m/s"
+	UnitInHg       = "inHg"
+	UnitHPa        = "hPa"
+	UnitPa         = "Pa"
+	UnitInch       = "in"
+	UnitMM         = "mm"
+	UnitMile       = "mi"
+	UnitKM         = "km"
+	UnitMeter      = "m"
+)
+
+// unitSet holds the unit symbol used for each kind of measurement in a system.
+type unitSet struct {
+	Temperature   string
+	Speed         string
+	Pressure      string
+	Precipitation string
+	Distance      string
+}
+
+var unitSets = map[Units]unitSet{
+	UnitsUS:     {UnitFahrenheit, UnitMPH, UnitInHg, UnitInch, UnitMile},
+	UnitsMetric: {UnitCelsius, UnitKPH, UnitHPa, UnitMM, UnitKM},
+	UnitsSI:     {UnitCelsius, UnitMPS, UnitHPa, UnitMM, UnitKM},
+}
+
+// toBase holds the factor converting each non-temperature unit to its SI
+// base unit (m/s, Pa or m).
+var toBase = map[string]float64{
+	UnitMPH:   0.44704,
+	UnitKPH:   1 / 3.6,
+	UnitMPS:   1,
+	UnitInHg:  3386.389,
+	UnitHPa:   100,
+	UnitPa:    1,
+	UnitInch:  0.0254,
+	UnitMM:    0.001,
+	UnitMile:  1609.344,
+	UnitKM:    1000,
+	UnitMeter: 1,
+}
+
+// decimals is the number of decimal places kept after converting into a unit.
+var decimals = map[string]int{
+	UnitInHg: 2,
+	UnitInch: 2,
+	UnitMM:   1,
+	UnitMile: 1,
+	UnitKM:   1,
+}
+
+// ParseUnits parses a units query value. An empty string selects UnitsUS.
+func ParseUnits(s string) (Units, error) {
+	u := Units(strings.ToLower(strings.TrimSpace(s)))
+	if u == "" {
+		return UnitsUS, nil
+	}
+	if _, ok := unitSets[u]; !ok {
+		return "", fmt.Errorf("unknown units %q (want us, metric or si)", s)
+	}
+	return u, nil
+}
+
+// convertTemperature converts a temperature between F and C, returning the
+// value unchanged if either unit is unrecognized.
+func convertTemperature(v float64, from, to string) float64 {
+	switch {
+	case from == to:
+		return v
+	case from == UnitFahrenheit && to == UnitCelsius:
+		return (v - 32) * 5 / 9
+	case from == UnitCelsius && to == UnitFahrenheit:
+		return v*9/5 + 32
+	}
+	return v
+}
+
+// convertTemp converts an integer temperature, rounding the result.
+func convertTemp(v int, from, to string) int {
+	return int(math.Round(convertTemperature(float64(v), from, to)))
+}
+
+// convertValue converts a speed, pressure or distance between units. ok is
+// false when either unit is unknown.
+func convertValue(v float64, from, to string) (float64, bool) {
+	f, okFrom := toBase[from]
+	t, okTo := toBase[to]
+	if !okFrom || !okTo {
+		return v, false
+	}
+	return roundTo(v*f/t, decimals[to]), true
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// wmoUnit maps an NWS/WMO unit code such as "wmoUnit:km_h-1" to one of the
+// unit symbols above.
+func wmoUnit(unitCode string) string {
+	code := unitCode
+	if idx := strings.LastIndex(code, ":"); idx != -1 {
+		code = code[idx+1:]
+	}
+	switch code {
+	case "degC":
+		return UnitCelsius
+	case "degF":
+		return UnitFahrenheit
+	case "km_h-1":
+		return UnitKPH
+	case "m_s-1":
+		return UnitMPS
+	case "Pa":
+		return UnitPa
+	case "m":
+		return UnitMeter
+	case "mm":
+		return UnitMM
+	}
+	return ""
+}
+
+// WithUnits returns a copy of wd with every measurement converted into the
+// requested unit system. wd itself is not modified.
+func (wd *WeatherData) WithUnits(u Units) *WeatherData {
+	set, ok := unitSets[u]
+	if !ok {
+		set, u = unitSets[UnitsUS], UnitsUS
+	}
+
+	out := *wd
+	out.Units = u
+	out.Current = wd.Current.withUnits(set)
+
+	out.Forecast = make([]DailyForecast, len(wd.Forecast))
+	for i, d := range wd.Forecast {
+		d.HighTemp = convertTemp(d.HighTemp, d.TemperatureUnit, set.Temperature)
+		d.LowTemp = convertTemp(d.LowTemp, d.TemperatureUnit, set.Temperature)
+		d.TemperatureUnit = temperatureUnit(d.TemperatureUnit, set)
+		out.Forecast[i] = d
+	}
+
+	out.Hourly = make([]HourlyForecast, len(wd.Hourly))
+	for i, h := range wd.Hourly {
+		h.Temperature = convertTemp(h.Temperature, h.TemperatureUnit, set.Temperature)
+		h.TemperatureUnit = temperatureUnit(h.TemperatureUnit, set)
+		h.Wind = h.Wind.withUnits(set.Speed)
+		out.Hourly[i] = h
+	}
+
+	if wd.HourlySeries != nil {
+		out.HourlySeries = make([]HourlyPoint, len(wd.HourlySeries))
+		for i, p := range wd.HourlySeries {
+			p.Temperature = convertTemp(p.Temperature, p.TemperatureUnit, set.Temperature)
+			p.FeelsLike = convertTemp(p.FeelsLike, p.TemperatureUnit, set.Temperature)
+			p.TemperatureUnit = temperatureUnit(p.TemperatureUnit, set)
+			out.HourlySeries[i] = p
+		}
+	}
+
+	out.Alerts = append([]Alert(nil), wd.Alerts...)
+
+	if wd.Changes != nil {
+		out.Changes = make([]ForecastChange, len(wd.Changes))
+		for i, c := range wd.Changes {
+			out.Changes[i] = c.withUnits(set)
+		}
+	}
+	return &out
+}
+
+func (c CurrentCondition) withUnits(set unitSet) CurrentCondition {
+	if c.ObservedTemperature != nil {
+		// Convert the reading, not the Fahrenheit it was rounded to.
+		v := convertTemperature(*c.ObservedTemperature, c.ObservedTemperatureUnit, set.Temperature)
+		c.Temperature = int(math.Round(v))
+		v = roundTo(v, 1)
+		c.ObservedTemperature, c.ObservedTemperatureUnit = &v, set.Temperature
+	} else {
+		c.Temperature = convertTemp(c.Temperature, c.TemperatureUnit, set.Temperature)
+	}
+	c.HighTemp = convertTemp(c.HighTemp, c.TemperatureUnit, set.Temperature)
+	c.LowTemp = convertTemp(c.LowTemp, c.TemperatureUnit, set.Temperature)
+	c.TemperatureUnit = temperatureUnit(c.TemperatureUnit, set)
+
+	c.Wind = c.Wind.withUnits(set.Speed)
+
+	if v, ok := convertValue(c.Pressure, c.PressureUnit, set.Pressure); ok {
+		c.Pressure, c.PressureUnit = v, set.Pressure
+	}
+	if v, ok := convertValue(c.Visibility, c.VisibilityUnit, set.Distance); ok {
+		c.Visibility, c.VisibilityUnit = v, set.Distance
+	}
+	if v, ok := convertValue(c.PrecipLastHour, c.PrecipLastHourUnit, set.Precipitation); ok {
+		c.PrecipLastHour, c.PrecipLastHourUnit = v, set.Precipitation
+	}
+	return c
+}
+
+// temperatureUnit returns the target temperature unit, leaving unknown
+// source units (which convertTemp passes through) untouched.
+func temperatureUnit(from string, set unitSet) string {
+	if from == UnitFahrenheit || from == UnitCelsius {
+		return set.Temperature
+	}
+	return from
+}
+
+// Temperature converts a temperature in from (UnitCelsius or
+// UnitFahrenheit) into u, to one decimal place, and returns its unit.
+func (u Units) Temperature(v float64, from string) (float64, string) {
+	set, ok := unitSets[u]
+	if !ok {
+		set = unitSets[UnitsUS]
+	}
+	return roundTo(convertTemperature(v, from, set.Temperature), 1), set.Temperature
+}
+
+// Precipitation converts a precipitation amount in from into u and returns
+// its unit.
+func (u Units) Precipitation(v float64, from string) (float64, string) {
+	set, ok := unitSets[u]
+	if !ok {
+		set = unitSets[UnitsUS]
+	}
+	if c, ok := convertValue(v, from, set.Precipitation); ok {
+		return c, set.Precipitation
+	}
+	return v, from
+}
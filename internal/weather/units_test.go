@@ -0,0 +1,210 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseUnits tests accepted and rejected units values
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  Units
+		expectErr bool
+	}{
+		{"", UnitsUS, false},
+		{"us", UnitsUS, false},
+		{"metric", UnitsMetric, false},
+		{" SI ", UnitsSI, false},
+		{"imperial", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			u, err := ParseUnits(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ParseUnits(%q) error = %v, expectErr %v", tt.input, err, tt.expectErr)
+			}
+			if u != tt.expected {
+				t.Errorf("ParseUnits(%q) = %q, want %q", tt.input, u, tt.expected)
+			}
+		})
+	}
+}
+
+// TestWithUnits_Metric tests converting cached US data to metric
+func TestWithUnits_Metric(t *testing.T) {
+	wd := &WeatherData{
+		Current: CurrentCondition{
+			Temperature:        68,
+			TemperatureUnit:    "F",
+			HighTemp:           77,
+			LowTemp:            50,
+			Wind:               Wind{Min: 5, Max: 10, Unit: "mph", Display: "5 to 10 mph"},
+			Pressure:           29.92,
+			PressureUnit:       "inHg",
+			Visibility:         10,
+			VisibilityUnit:     "mi",
+			PrecipLastHour:     0.1,
+			PrecipLastHourUnit: "in",
+		},
+		Forecast: []DailyForecast{{Name: "Today", HighTemp: 86, LowTemp: 32, TemperatureUnit: "F"}},
+		Hourly:   []HourlyForecast{{Name: "3 PM", Temperature: 14, TemperatureUnit: "F"}},
+		Units:    UnitsUS,
+	}
+
+	got := wd.WithUnits(UnitsMetric)
+
+	if got.Units != UnitsMetric {
+		t.Errorf("Expected Units to be metric, got %q", got.Units)
+	}
+	if got.Current.Temperature != 20 || got.Current.TemperatureUnit != "C" {
+		t.Errorf("Expected current temperature 20 C, got %d %s", got.Current.Temperature, got.Current.TemperatureUnit)
+	}
+	if got.Current.HighTemp != 25 || got.Current.LowTemp != 10 {
+		t.Errorf("Expected high/low 25/10, got %d/%d", got.Current.HighTemp, got.Current.LowTemp)
+	}
+	if got.Current.Wind.Display != "8 to 16 km/h" {
+		t.Errorf("Expected wind speed '8 to 16 km/h', got %q", got.Current.Wind.Display)
+	}
+	if got.Current.Pressure != 1013 || got.Current.PressureUnit != "hPa" {
+		t.Errorf("Expected pressure 1013 hPa, got %v %s", got.Current.Pressure, got.Current.PressureUnit)
+	}
+	if got.Current.Visibility != 16.1 || got.Current.VisibilityUnit != "km" {
+		t.Errorf("Expected visibility 16.1 km, got %v %s", got.Current.Visibility, got.Current.VisibilityUnit)
+	}
+	if got.Current.PrecipLastHour != 2.5 || got.Current.PrecipLastHourUnit != "mm" {
+		t.Errorf("Expected precipitation 2.5 mm, got %v %s", got.Current.PrecipLastHour, got.Current.PrecipLastHourUnit)
+	}
+	if got.Forecast[0].HighTemp != 30 || got.Forecast[0].LowTemp != 0 || got.Forecast[0].TemperatureUnit != "C" {
+		t.Errorf("Expected forecast 30/0 C, got %d/%d %s", got.Forecast[0].HighTemp, got.Forecast[0].LowTemp, got.Forecast[0].TemperatureUnit)
+	}
+	if got.Hourly[0].Temperature != -10 {
+		t.Errorf("Expected hourly -10, got %d", got.Hourly[0].Temperature)
+	}
+
+	// The original must be left untouched so cached data stays in US units.
+	if wd.Current.Temperature != 68 || wd.Forecast[0].HighTemp != 86 || wd.Hourly[0].Temperature != 14 {
+		t.Errorf("WithUnits modified the source WeatherData")
+	}
+}
+
+// TestWithUnits_SIWindSpeed tests that SI uses m/s for wind
+func TestWithUnits_SIWindSpeed(t *testing.T) {
+	wd := &WeatherData{
+		Current: CurrentCondition{
+			Wind: Wind{Min: 20, Max: 20, Gust: 30, Unit: "mph", Display: "20 mph, gusts 30 mph"},
+		},
+	}
+
+	got := wd.WithUnits(UnitsSI)
+	if got.Current.Wind.Display != "9 m/s, gusts 13 m/s" {
+		t.Errorf("Expected wind speed '9 m/s, gusts 13 m/s', got %q", got.Current.Wind.Display)
+	}
+}
+
+// TestWithUnits_UnparsedWindSpeedKept tests that an unparsed wind string is passed through
+func TestWithUnits_UnparsedWindSpeedKept(t *testing.T) {
+	wd := &WeatherData{Current: CurrentCondition{Wind: Wind{Display: "Calm"}}}
+
+	got := wd.WithUnits(UnitsMetric)
+	if got.Current.Wind.Display != "Calm" {
+		t.Errorf("Expected wind speed 'Calm', got %q", got.Current.Wind.Display)
+	}
+}
+
+// TestObservationMeasurements tests that station values are stored as
+// reported and converted once for display
+func TestObservationMeasurements(t *testing.T) {
+	pressure := 101325.0
+	visibility := 16093.44
+	precip := 25.4
+	obs := createMockObservation(nil, "wmoUnit:degC", "Rain")
+	obs.Properties.BarometricPressure = QuantitativeValue{Value: &pressure, UnitCode: "wmoUnit:Pa"}
+	obs.Properties.Visibility = QuantitativeValue{Value: &visibility, UnitCode: "wmoUnit:m"}
+	obs.Properties.PrecipitationLastHour = QuantitativeValue{Value: &precip, UnitCode: "wmoUnit:mm"}
+
+	var c CurrentCondition
+	observationMeasurements(&obs, &c)
+
+	if c.Pressure != 101325 || c.PressureUnit != "Pa" || c.Visibility != 16093.44 || c.VisibilityUnit != "m" {
+		t.Errorf("Expected the reported values, got %v %s and %v %s", c.Pressure, c.PressureUnit, c.Visibility, c.VisibilityUnit)
+	}
+
+	us := c.withUnits(unitSets[UnitsUS])
+	if us.Pressure != 29.92 || us.PressureUnit != "inHg" {
+		t.Errorf("Expected pressure 29.92 inHg, got %v %s", us.Pressure, us.PressureUnit)
+	}
+	if us.Visibility != 10 || us.VisibilityUnit != "mi" {
+		t.Errorf("Expected visibility 10 mi, got %v %s", us.Visibility, us.VisibilityUnit)
+	}
+	if us.PrecipLastHour != 1 || us.PrecipLastHourUnit != "in" {
+		t.Errorf("Expected precipitation 1 in, got %v %s", us.PrecipLastHour, us.PrecipLastHourUnit)
+	}
+
+	metric := c.withUnits(unitSets[UnitsMetric])
+	if metric.Pressure != 1013 || metric.Visibility != 16.1 || metric.PrecipLastHour != 25.4 {
+		t.Errorf("Expected 1013 hPa, 16.1 km and 25.4 mm, got %v, %v and %v", metric.Pressure, metric.Visibility, metric.PrecipLastHour)
+	}
+}
+
+// TestWithUnits_ObservedTemperature tests that an observed temperature is
+// converted from the reading rather than the rounded Fahrenheit
+func TestWithUnits_ObservedTemperature(t *testing.T) {
+	celsius := 20.3
+	obs := createMockObservation(&celsius, "wmoUnit:degC", "Clear")
+	wd, err := transform(&ForecastResponse{}, nil, &AlertsResponse{}, &obs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.Current.Temperature != 69 || wd.Current.TemperatureUnit != "F" {
+		t.Errorf("Expected 69°F cached, got %d°%s", wd.Current.Temperature, wd.Current.TemperatureUnit)
+	}
+
+	metric := wd.WithUnits(UnitsMetric).Current
+	if metric.Temperature != 20 || metric.TemperatureUnit != "C" {
+		t.Errorf("Expected 20°C, got %d°%s", metric.Temperature, metric.TemperatureUnit)
+	}
+	if metric.ObservedTemperature == nil || *metric.ObservedTemperature != 20.3 || metric.ObservedTemperatureUnit != "C" {
+		t.Errorf("Expected an observed 20.3°C, got %v %s", metric.ObservedTemperature, metric.ObservedTemperatureUnit)
+	}
+	if us := wd.WithUnits(UnitsUS).Current; us.Temperature != 69 || *us.ObservedTemperature != 68.5 {
+		t.Errorf("Expected 69°F observed as 68.5, got %d and %v", us.Temperature, *us.ObservedTemperature)
+	}
+}
+
+// TestObservationMeasurements_Missing tests that unreported values leave units empty
+func TestObservationMeasurements_Missing(t *testing.T) {
+	obs := createMockObservation(nil, "wmoUnit:degC", "Clear")
+
+	var c CurrentCondition
+	observationMeasurements(&obs, &c)
+
+	if c.PressureUnit != "" || c.VisibilityUnit != "" || c.PrecipLastHourUnit != "" {
+		t.Errorf("Expected empty units for missing values, got %q %q %q", c.PressureUnit, c.VisibilityUnit, c.PrecipLastHourUnit)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	wd := &WeatherData{
+		CachedAt: time.Date(2025, 5, 6, 13, 30, 0, 123, time.UTC),
+		Alerts:   []Alert{{ID: "urn:oid:1"}},
+		Units:    UnitsUS,
+	}
+	v := wd.Version()
+	if got := wd.WithUnits(UnitsMetric).Version(); got != v {
+		t.Errorf("expected units not to change the version, got %q and %q", v, got)
+	}
+
+	refreshed := *wd
+	refreshed.CachedAt = refreshed.CachedAt.Add(time.Hour)
+	if refreshed.Version() == v {
+		t.Errorf("expected a cache refresh to change the version")
+	}
+
+	alerted := *wd
+	alerted.Alerts = append(alerted.Alerts, Alert{ID: "urn:oid:2"})
+	if alerted.Version() == v {
+		t.Errorf("expected a new alert to change the version")
+	}
+}
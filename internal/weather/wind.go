@@ -0,0 +1,139 @@
+package weather
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SpeedString holds an NWS speed such as "25 mph". NWS documents fields like
+// windGust as either a string, null or a QuantitativeValue object, so all
+// three are accepted and normalized to the string form.
+type SpeedString string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *SpeedString) UnmarshalJSON(data []byte) error {
+	var str *string
+	if err := json.Unmarshal(data, &str); err == nil {
+		if str != nil {
+			*s = SpeedString(*str)
+		}
+		return nil
+	}
+
+	var q QuantitativeValue
+	if err := json.Unmarshal(data, &q); err != nil {
+		return err
+	}
+	if q.Value != nil {
+		if unit := wmoUnit(q.UnitCode); unit != "" {
+			*s = SpeedString(fmt.Sprintf("%g %s", math.Round(*q.Value), unit))
+		}
+	}
+	return nil
+}
+
+// compassPoints lists the 16-point compass directions NWS uses, clockwise
+// from north in 22.5° steps.
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// compassDegrees returns the bearing in degrees for a compass point.
+func compassDegrees(dir string) (float64, bool) {
+	dir = strings.ToUpper(strings.TrimSpace(dir))
+	for i, p := range compassPoints {
+		if p == dir {
+			return float64(i) * 22.5, true
+		}
+	}
+	return 0, false
+}
+
+// parseWind builds a Wind from the NWS windSpeed, windGust and windDirection
+// strings. Speeds that can't be parsed are kept verbatim in Display.
+func parseWind(speed, gust, direction string) Wind {
+	w := Wind{Display: speed}
+
+	if deg, ok := compassDegrees(direction); ok {
+		w.Direction = strings.ToUpper(strings.TrimSpace(direction))
+		w.Degrees = deg
+	}
+
+	nums, unit := speedNumbers(speed)
+	if unit == "" || len(nums) == 0 {
+		return w
+	}
+
+	// Some periods fold gusts into the speed, e.g. "10 to 15 mph with gusts to 30 mph".
+	if strings.Contains(strings.ToLower(speed), "gust") && len(nums) > 1 {
+		w.Gust = nums[len(nums)-1]
+		nums = nums[:len(nums)-1]
+	}
+
+	w.Unit = unit
+	w.Min, w.Max = nums[0], nums[0]
+	if len(nums) > 1 {
+		w.Max = nums[1]
+	}
+
+	if g, gunit := speedNumbers(gust); len(g) > 0 {
+		if v, ok := convertValue(g[len(g)-1], gunit, unit); ok {
+			w.Gust = math.Round(v)
+		}
+	}
+
+	w.Display = w.format()
+	return w
+}
+
+// speedNumbers returns the numbers in an NWS speed string and the last unit
+// that appears in it, or an empty unit if there is none.
+func speedNumbers(s string) ([]float64, string) {
+	var nums []float64
+	unit := ""
+	for _, f := range strings.Fields(s) {
+		f = strings.TrimRight(f, ",.")
+		if _, ok := toBase[f]; ok {
+			unit = f
+			continue
+		}
+		var v float64
+		if _, err := fmt.Sscanf(f, "%g", &v); err == nil {
+			nums = append(nums, v)
+		}
+	}
+	return nums, unit
+}
+
+// format renders the speed portion of w, e.g. "5 to 10 mph, gusts 25 mph".
+func (w Wind) format() string {
+	s := fmt.Sprintf("%g %s", w.Min, w.Unit)
+	if w.Min != w.Max {
+		s = fmt.Sprintf("%g to %g %s", w.Min, w.Max, w.Unit)
+	}
+	if w.Gust > 0 {
+		s += fmt.Sprintf(", gusts %g %s", w.Gust, w.Unit)
+	}
+	return s
+}
+
+// withUnits converts w into the given speed unit. Unparsed winds are
+// returned unchanged.
+func (w Wind) withUnits(unit string) Wind {
+	if w.Unit == "" || w.Unit == unit {
+		return w
+	}
+	lo, okLo := convertValue(w.Min, w.Unit, unit)
+	hi, okHi := convertValue(w.Max, w.Unit, unit)
+	gust, okGust := convertValue(w.Gust, w.Unit, unit)
+	if !okLo || !okHi || !okGust {
+		return w
+	}
+	w.Min, w.Max, w.Gust = math.Round(lo), math.Round(hi), math.Round(gust)
+	w.Unit = unit
+	w.Display = w.format()
+	return w
+}
@@ -0,0 +1,130 @@
+package weather
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestParseWind tests parsing of NWS wind speed, gust and direction strings
+func TestParseWind(t *testing.T) {
+	tests := []struct {
+		name      string
+		speed     string
+		gust      string
+		direction string
+		expected  Wind
+	}{
+		{
+			name:      "single speed",
+			speed:     "10 mph",
+			direction: "N",
+			expected:  Wind{Min: 10, Max: 10, Unit: "mph", Direction: "N", Degrees: 0, Display: "10 mph"},
+		},
+		{
+			name:      "range",
+			speed:     "5 to 10 mph",
+			direction: "NW",
+			expected:  Wind{Min: 5, Max: 10, Unit: "mph", Direction: "NW", Degrees: 315, Display: "5 to 10 mph"},
+		},
+		{
+			name:      "separate gust field",
+			speed:     "15 mph",
+			gust:      "30 mph",
+			direction: "SSE",
+			expected:  Wind{Min: 15, Max: 15, Gust: 30, Unit: "mph", Direction: "SSE", Degrees: 157.5, Display: "15 mph, gusts 30 mph"},
+		},
+		{
+			name:      "gust in speed string",
+			speed:     "10 to 15 mph with gusts to 30 mph",
+			direction: "W",
+			expected:  Wind{Min: 10, Max: 15, Gust: 30, Unit: "mph", Direction: "W", Degrees: 270, Display: "10 to 15 mph, gusts 30 mph"},
+		},
+		{
+			name:      "gust in other unit",
+			speed:     "10 mph",
+			gust:      "48 km/h",
+			direction: "E",
+			expected:  Wind{Min: 10, Max: 10, Gust: 30, Unit: "mph", Direction: "E", Degrees: 90, Display: "10 mph, gusts 30 mph"},
+		},
+		{
+			name:      "metric speed",
+			speed:     "15 km/h",
+			direction: "ene",
+			expected:  Wind{Min: 15, Max: 15, Unit: "km/h", Direction: "ENE", Degrees: 67.5, Display: "15 km/h"},
+		},
+		{
+			name:     "unparseable speed kept for display",
+			speed:    "Calm",
+			expected: Wind{Display: "Calm"},
+		},
+		{
+			name:      "unknown direction",
+			speed:     "5 mph",
+			direction: "Variable",
+			expected:  Wind{Min: 5, Max: 5, Unit: "mph", Display: "5 mph"},
+		},
+		{
+			name:     "empty",
+			expected: Wind{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseWind(tt.speed, tt.gust, tt.direction)
+			if got != tt.expected {
+				t.Errorf("parseWind(%q, %q, %q) = %+v, want %+v", tt.speed, tt.gust, tt.direction, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSpeedString_UnmarshalJSON tests the string, null and object forms of windGust
+func TestSpeedString_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected SpeedString
+	}{
+		{"string", `"25 mph"`, "25 mph"},
+		{"null", `null`, ""},
+		{"quantitative value", `{"unitCode":"wmoUnit:km_h-1","value":40.3}`, "40 km/h"},
+		{"quantitative value without value", `{"unitCode":"wmoUnit:km_h-1","value":null}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p ForecastPeriod
+			if err := json.Unmarshal([]byte(`{"windGust":`+tt.input+`}`), &p); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.WindGust != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, p.WindGust)
+			}
+		})
+	}
+}
+
+// TestTransform_HourlyWind tests that hourly periods carry parsed wind
+func TestTransform_HourlyWind(t *testing.T) {
+	hc := &ForecastResponse{}
+	hc.Properties.Periods = []ForecastPeriod{
+		{Name: "Now", StartTime: "2024-01-15T15:00:00Z", Temperature: 60, TemperatureUnit: "F", WindSpeed: "10 mph", WindGust: "20 mph", WindDirection: "S"},
+	}
+
+	wd, err := transform(nil, hc, createMockAlertsResponse(), nil, "UTC")
+	if err != nil {
+		t.Fatalf("transform failed: %v", err)
+	}
+
+	if len(wd.Hourly) != 1 {
+		t.Fatalf("Expected 1 hourly item, got %d", len(wd.Hourly))
+	}
+	want := Wind{Min: 10, Max: 10, Gust: 20, Unit: "mph", Direction: "S", Degrees: 180, Display: "10 mph, gusts 20 mph"}
+	if wd.Hourly[0].Wind != want {
+		t.Errorf("Expected hourly wind %+v, got %+v", want, wd.Hourly[0].Wind)
+	}
+	if wd.Current.Wind != want {
+		t.Errorf("Expected current wind %+v, got %+v", want, wd.Current.Wind)
+	}
+}
@@ -0,0 +1,254 @@
+// Package webhook delivers NWS alerts for a location to subscriber URLs as
+// signed JSON POSTs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+const (
+	// EventCreated and EventUpdated are the payload types, also sent in the
+	// X-Wthr-Event header.
+	EventCreated = "alert.created"
+	EventUpdated = "alert.updated"
+
+	// maxAttempts is how many polls try a delivery before giving up on it.
+	// Each poll makes one attempt, so a dead endpoint can't hold up the
+	// other webhooks; at the default interval that's retrying for about
+	// half an hour.
+	maxAttempts = 6
+)
+
+// Store is the webhook storage the Dispatcher needs; *db.DB implements it.
+type Store interface {
+	ListWebhooks(ctx context.Context) ([]db.Webhook, error)
+	SetWebhookSeenAlerts(id int64, alertIDs []string) error
+	LogWebhookDelivery(d db.WebhookDelivery) error
+	CountWebhookAttempts(webhookID int64, alertID string) (int, error)
+}
+
+// AlertSource fetches active alerts for a point; *weather.ActiveAlerts and
+// *weather.Service implement it.
+type AlertSource interface {
+	GetAlerts(lat, lon float64) ([]weather.Alert, error)
+}
+
+// Payload is the JSON body POSTed to a webhook.
+type Payload struct {
+	Type      string        `json:"type"`
+	WebhookID int64         `json:"webhook_id"`
+	Location  Location      `json:"location"`
+	Alert     weather.Alert `json:"alert"`
+	SentAt    time.Time     `json:"sent_at"`
+}
+
+// Location is the point a webhook watches.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Dispatcher periodically checks alerts for every webhook's location and
+// delivers the ones it has not been sent yet.
+type Dispatcher struct {
+	store      Store
+	alerts     AlertSource
+	HTTPClient *http.Client
+	Interval   time.Duration
+}
+
+// NewDispatcher creates a Dispatcher that checks every five minutes.
+func NewDispatcher(store Store, alerts AlertSource) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		alerts: alerts,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		Interval: 5 * time.Minute,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(); err != nil {
+			log.Printf("Webhook poll error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll runs one pass over all webhooks, fetching alerts once per rounded
+// location.
+func (d *Dispatcher) Poll() error {
+	hooks, err := d.store.ListWebhooks(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	fetched := make(map[string][]weather.Alert)
+	for _, wh := range hooks {
+		key := fmt.Sprintf("%.2f,%.2f", wh.Latitude, wh.Longitude)
+		alerts, ok := fetched[key]
+		if !ok {
+			alerts, err = d.alerts.GetAlerts(wh.Latitude, wh.Longitude)
+			if err != nil {
+				// Leave seen alerts alone so nothing is lost; retry next poll.
+				log.Printf("Webhook poll: failed to get alerts for %s: %v", key, err)
+				continue
+			}
+			fetched[key] = alerts
+		}
+		d.dispatch(wh, alerts)
+	}
+	return nil
+}
+
+// dispatch delivers the alerts wh has not seen and records the current set.
+func (d *Dispatcher) dispatch(wh db.Webhook, alerts []weather.Alert) {
+	seen := make(map[string]bool, len(wh.SeenAlerts))
+	for _, id := range wh.SeenAlerts {
+		seen[id] = true
+	}
+
+	current := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		if a.ID == "" {
+			continue
+		}
+		current = append(current, a.ID)
+		if seen[a.ID] || !matches(wh, a) {
+			continue
+		}
+		if !d.deliver(wh, a) {
+			// Not marked seen, so the next poll tries again.
+			current = current[:len(current)-1]
+		}
+	}
+
+	if err := d.store.SetWebhookSeenAlerts(wh.ID, current); err != nil {
+		log.Printf("Failed to record seen alerts: %v", err)
+	}
+}
+
+// deliver POSTs one alert once. It reports whether the alert is finished
+// with: delivered, or failed too many times to keep trying. Otherwise the
+// next poll tries again, counting on from the logged attempts.
+func (d *Dispatcher) deliver(wh db.Webhook, a weather.Alert) bool {
+	event := EventCreated
+	if a.IsUpdate() {
+		event = EventUpdated
+	}
+
+	previous, err := d.store.CountWebhookAttempts(wh.ID, a.ID)
+	if err != nil {
+		log.Printf("Failed to count webhook attempts: %v", err)
+	}
+
+	body, err := json.Marshal(Payload{
+		Type:      event,
+		WebhookID: wh.ID,
+		Location:  Location{Latitude: wh.Latitude, Longitude: wh.Longitude},
+		Alert:     a,
+		SentAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Webhook payload error: %v", err)
+		return true
+	}
+
+	attempt := previous + 1
+	status, err := d.post(wh, event, body)
+
+	entry := db.WebhookDelivery{WebhookID: wh.ID, AlertID: a.ID, Event: event, Attempt: attempt, StatusCode: status}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := d.store.LogWebhookDelivery(entry); logErr != nil {
+		log.Printf("Failed to log webhook delivery: %v", logErr)
+	}
+
+	if err == nil {
+		return true
+	}
+	log.Printf("Webhook %d delivery attempt %d failed: %v", wh.ID, attempt, err)
+	return attempt >= maxAttempts
+}
+
+// post sends a signed payload and returns the response status.
+func (d *Dispatcher) post(wh db.Webhook, event string, body []byte) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest("POST", wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wthr.lol-webhook/1.0")
+	req.Header.Set("X-Wthr-Event", event)
+	req.Header.Set("X-Wthr-Timestamp", timestamp)
+	req.Header.Set("X-Wthr-Signature", Sign(wh.Secret, timestamp, body))
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Wthr-Signature value for a payload: "sha256=" followed
+// by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook
+// secret. Receivers should recompute it and reject stale timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// matches reports whether an alert passes a webhook's severity and event
+// filters.
+func matches(wh db.Webhook, a weather.Alert) bool {
+	if wh.MinSeverity != "" && weather.SeverityRank(a.Severity) < weather.SeverityRank(wh.MinSeverity) {
+		return false
+	}
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, e := range wh.Events {
+		if strings.EqualFold(e, a.Event) {
+			return true
+		}
+	}
+	return false
+}
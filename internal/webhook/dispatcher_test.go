@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/swelljoe/wthr.lol/internal/db"
+	"github.com/swelljoe/wthr.lol/internal/weather"
+)
+
+type memoryStore struct {
+	hooks      []db.Webhook
+	deliveries []db.WebhookDelivery
+}
+
+func (m *memoryStore) ListWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	return append([]db.Webhook(nil), m.hooks...), nil
+}
+
+func (m *memoryStore) SetWebhookSeenAlerts(id int64, alertIDs []string) error {
+	for i := range m.hooks {
+		if m.hooks[i].ID == id {
+			m.hooks[i].SeenAlerts = alertIDs
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) LogWebhookDelivery(d db.WebhookDelivery) error {
+	m.deliveries = append(m.deliveries, d)
+	return nil
+}
+
+func (m *memoryStore) CountWebhookAttempts(webhookID int64, alertID string) (int, error) {
+	n := 0
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID && d.AlertID == alertID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type fakeAlerts struct {
+	alerts []weather.Alert
+	err    error
+	calls  int
+}
+
+func (f *fakeAlerts) GetAlerts(lat, lon float64) ([]weather.Alert, error) {
+	f.calls++
+	return f.alerts, f.err
+}
+
+// receiver is a webhook endpoint that checks signatures and records payloads.
+type receiver struct {
+	t      *testing.T
+	secret string
+	status []int // Status to return per request; 200 once exhausted
+
+	mu       sync.Mutex
+	payloads []Payload
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if got, want := r.Header.Get("X-Wthr-Signature"), Sign(rc.secret, r.Header.Get("X-Wthr-Timestamp"), body); got != want {
+		rc.t.Errorf("bad signature %q, want %q", got, want)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		rc.t.Errorf("bad payload: %v", err)
+	}
+	if r.Header.Get("X-Wthr-Event") != p.Type {
+		rc.t.Errorf("event header %q does not match payload type %q", r.Header.Get("X-Wthr-Event"), p.Type)
+	}
+
+	rc.mu.Lock()
+	rc.payloads = append(rc.payloads, p)
+	status := http.StatusOK
+	if len(rc.status) > 0 {
+		status, rc.status = rc.status[0], rc.status[1:]
+	}
+	rc.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() []Payload {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Payload(nil), rc.payloads...)
+}
+
+func newTestDispatcher(t *testing.T, hook db.Webhook) (*Dispatcher, *memoryStore, *fakeAlerts, *receiver) {
+	t.Helper()
+	rc := &receiver{t: t, secret: "s3cret"}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	hook.ID = 1
+	hook.URL = server.URL
+	hook.Secret = rc.secret
+	hook.Latitude, hook.Longitude = 35.47, -97.52
+	store := &memoryStore{hooks: []db.Webhook{hook}}
+	alerts := &fakeAlerts{}
+
+	d := NewDispatcher(store, alerts)
+	return d, store, alerts, rc
+}
+
+func TestPoll_DeliversNewAndUpdatedAlerts(t *testing.T) {
+	d, store, alerts, rc := newTestDispatcher(t, db.Webhook{})
+	alerts.alerts = []weather.Alert{{ID: "urn:oid:1", Event: "Flood Warning", Severity: "Severe", MessageType: "Alert"}}
+
+	if err := d.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	got := rc.received()
+	if len(got) != 1 || got[0].Type != EventCreated || got[0].Alert.ID != "urn:oid:1" || got[0].Location.Latitude != 35.47 {
+		t.Fatalf("expected one alert.created payload, got %+v", got)
+	}
+
+	// Same alert again: nothing new
+	if err := d.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(rc.received()) != 1 {
+		t.Errorf("expected no redelivery, got %d payloads", len(rc.received()))
+	}
+
+	// NWS issues an update as a new message referencing the old one
+	alerts.alerts = []weather.Alert{{ID: "urn:oid:2", Event: "Flood Warning", Severity: "Severe", MessageType: "Update", References: []string{"urn:oid:1"}}}
+	if err := d.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	got = rc.received()
+	if len(got) != 2 || got[1].Type != EventUpdated {
+		t.Errorf("expected alert.updated payload, got %+v", got)
+	}
+	if len(store.deliveries) != 2 || store.deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("expected successful deliveries to be logged, got %+v", store.deliveries)
+	}
+}
+
+func TestPoll_Filters(t *testing.T) {
+	d, store, alerts, rc := newTestDispatcher(t, db.Webhook{MinSeverity: "Severe", Events: []string{"tornado warning", "Flood Warning"}})
+	alerts.alerts = []weather.Alert{
+		{ID: "urn:oid:1", Event: "Tornado Warning", Severity: "Extreme"},
+		{ID: "urn:oid:2", Event: "Flood Warning", Severity: "Moderate"},
+		{ID: "urn:oid:3", Event: "Heat Advisory", Severity: "Severe"},
+	}
+
+	if err := d.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	got := rc.received()
+	if len(got) != 1 || got[0].Alert.ID != "urn:oid:1" {
+		t.Errorf("expected only the tornado warning, got %+v", got)
+	}
+	if len(store.hooks[0].SeenAlerts) != 3 {
+		t.Errorf("expected filtered alerts to be marked seen too, got %v", store.hooks[0].SeenAlerts)
+	}
+}
+
+func TestPoll_Retries(t *testing.T) {
+	d, store, alerts, rc := newTestDispatcher(t, db.Webhook{})
+	rc.status = []int{http.StatusInternalServerError, http.StatusBadGateway}
+	alerts.alerts = []weather.Alert{{ID: "urn:oid:1", Event: "Tornado Warning", Severity: "Extreme"}}
+
+	// One attempt per poll
+	for poll := 1; poll <= 3; poll++ {
+		if err := d.Poll(); err != nil {
+			t.Fatalf("Poll failed: %v", err)
+		}
+		if len(rc.received()) != poll {
+			t.Errorf("poll %d: expected %d attempts, got %d", poll, poll, len(rc.received()))
+		}
+		if seen := len(store.hooks[0].SeenAlerts) == 1; seen != (poll == 3) {
+			t.Errorf("poll %d: expected seen=%v", poll, poll == 3)
+		}
+	}
+	if len(store.deliveries) != 3 || store.deliveries[0].Error == "" || store.deliveries[2].Attempt != 3 || store.deliveries[2].Error != "" {
+		t.Errorf("unexpected delivery log %+v", store.deliveries)
+	}
+}
+
+func TestPoll_GivesUpAfterMaxAttempts(t *testing.T) {
+	d, store, alerts, rc := newTestDispatcher(t, db.Webhook{})
+	for range maxAttempts + 3 {
+		rc.status = append(rc.status, http.StatusServiceUnavailable)
+	}
+	alerts.alerts = []weather.Alert{{ID: "urn:oid:1", Event: "Tornado Warning", Severity: "Extreme"}}
+
+	for poll := 1; poll <= maxAttempts+2; poll++ {
+		if err := d.Poll(); err != nil {
+			t.Fatalf("Poll failed: %v", err)
+		}
+		wantSeen := poll >= maxAttempts
+		if seen := len(store.hooks[0].SeenAlerts) == 1; seen != wantSeen {
+			t.Errorf("poll %d: expected seen=%v", poll, wantSeen)
+		}
+	}
+	if len(rc.received()) != maxAttempts {
+		t.Errorf("expected %d attempts in total, got %d", maxAttempts, len(rc.received()))
+	}
+}
+
+func TestPoll_UpstreamError(t *testing.T) {
+	d, store, alerts, rc := newTestDispatcher(t, db.Webhook{})
+	store.hooks[0].SeenAlerts = []string{"urn:oid:1"}
+	alerts.err = errors.New("NWS API error")
+
+	if err := d.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(rc.received()) != 0 || len(store.hooks[0].SeenAlerts) != 1 {
+		t.Errorf("expected nothing sent and seen alerts untouched")
+	}
+}
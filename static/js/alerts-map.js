@@ -0,0 +1,96 @@
+document.addEventListener("DOMContentLoaded", () => {
+    const svg = document.getElementById("alerts-map");
+    const status = document.getElementById("alerts-map-status");
+    const list = document.getElementById("alerts-map-list");
+    const severitySelect = document.getElementById("map-severity");
+    const SVG_NS = "http://www.w3.org/2000/svg";
+
+    // The map is a plain equirectangular projection of the lower 48 (plus
+    // whatever alerts fall outside it, which are listed but not drawn).
+    const VIEW = { minLon: -125, minLat: 24, maxLon: -66, maxLat: 50 };
+    const WIDTH = 1000;
+    const HEIGHT = 500;
+
+    function project([lon, lat]) {
+        const x = ((lon - VIEW.minLon) / (VIEW.maxLon - VIEW.minLon)) * WIDTH;
+        const y = ((VIEW.maxLat - lat) / (VIEW.maxLat - VIEW.minLat)) * HEIGHT;
+        return `${x.toFixed(1)},${y.toFixed(1)}`;
+    }
+
+    // polygons flattens a GeoJSON geometry to a list of polygons.
+    function polygons(geometry) {
+        if (!geometry) return [];
+        switch (geometry.type) {
+            case "Polygon":
+                return [geometry.coordinates];
+            case "MultiPolygon":
+                return geometry.coordinates;
+            case "GeometryCollection":
+                return geometry.geometries.flatMap(polygons);
+            default:
+                return [];
+        }
+    }
+
+    function pathData(geometry) {
+        return polygons(geometry)
+            .flatMap((rings) => rings.map((ring) => "M" + ring.map(project).join("L") + "Z"))
+            .join("");
+    }
+
+    function formatExpiry(expires) {
+        const d = new Date(expires);
+        if (isNaN(d) || d.getFullYear() < 2000) return "";
+        return ` until ${d.toLocaleString([], { weekday: "short", hour: "numeric", minute: "2-digit" })}`;
+    }
+
+    async function load() {
+        const params = new URLSearchParams();
+        if (severitySelect.value) params.set("severity", severitySelect.value);
+        status.textContent = "Loading alerts...";
+
+        try {
+            const response = await fetch(`/api/v1/alerts.geojson?${params}`);
+            if (!response.ok) throw new Error(`HTTP ${response.status}`);
+            const data = await response.json();
+            render(data.features);
+        } catch (err) {
+            console.error("Failed to load alerts:", err);
+            status.textContent = "Couldn't load alerts. Try again later.";
+        }
+    }
+
+    function render(features) {
+        svg.replaceChildren();
+        list.replaceChildren();
+
+        // Draw the least severe first so severe alerts stay on top.
+        const order = ["unknown", "minor", "moderate", "severe", "extreme"];
+        const rank = (f) => order.indexOf((f.properties.severity || "").toLowerCase());
+        const sorted = [...features].sort((a, b) => rank(a) - rank(b));
+
+        for (const f of sorted) {
+            const severity = (f.properties.severity || "unknown").toLowerCase();
+            const path = document.createElementNS(SVG_NS, "path");
+            path.setAttribute("d", pathData(f.geometry));
+            path.setAttribute("class", `severity-${severity}`);
+            const title = document.createElementNS(SVG_NS, "title");
+            title.textContent = `${f.properties.event}: ${f.properties.area_desc}`;
+            path.appendChild(title);
+            svg.appendChild(path);
+        }
+
+        for (const f of sorted.reverse()) {
+            const li = document.createElement("li");
+            const event = document.createElement("strong");
+            event.textContent = f.properties.event;
+            li.append(event, ` (${f.properties.severity}) ${f.properties.area_desc}${formatExpiry(f.properties.expires)}`);
+            list.appendChild(li);
+        }
+
+        status.textContent = features.length === 1 ? "1 active alert" : `${features.length} active alerts`;
+    }
+
+    severitySelect.addEventListener("change", load);
+    load();
+});
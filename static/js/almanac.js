@@ -0,0 +1,80 @@
+document.addEventListener("DOMContentLoaded", () => {
+    const svg = document.getElementById("almanac-chart");
+    if (!svg) return;
+    const SVG_NS = "http://www.w3.org/2000/svg";
+    const WIDTH = 700;
+    const HEIGHT = 260;
+    const PAD = { top: 10, right: 10, bottom: 30, left: 40 };
+    // The bottom quarter of the plot holds the precipitation bars.
+    const PRECIP_HEIGHT = 50;
+
+    function el(name, attrs, text) {
+        const node = document.createElementNS(SVG_NS, name);
+        for (const [k, v] of Object.entries(attrs)) node.setAttribute(k, v);
+        if (text !== undefined) node.textContent = text;
+        svg.appendChild(node);
+        return node;
+    }
+
+    function render(data) {
+        const days = data.days;
+        if (days.length === 0) return;
+
+        const temps = days.flatMap((d) => [d.high, d.low]).filter((v) => v !== null);
+        if (temps.length === 0) return;
+        const min = Math.floor(Math.min(...temps)) - 1;
+        const max = Math.ceil(Math.max(...temps)) + 1;
+        const maxPrecip = Math.max(...days.map((d) => d.precip || 0));
+
+        const plotW = WIDTH - PAD.left - PAD.right;
+        const plotH = HEIGHT - PAD.top - PAD.bottom;
+        const step = plotW / days.length;
+        const x = (i) => PAD.left + step * (i + 0.5);
+        const y = (t) => PAD.top + ((max - t) / (max - min)) * (plotH - PRECIP_HEIGHT);
+
+        // Temperature gridlines
+        for (const t of [min, Math.round((min + max) / 2), max]) {
+            el("line", { x1: PAD.left, x2: WIDTH - PAD.right, y1: y(t), y2: y(t), class: "almanac-grid" });
+            el("text", { x: PAD.left - 6, y: y(t) + 4, "text-anchor": "end", class: "almanac-label" }, `${t}°`);
+        }
+
+        days.forEach((d, i) => {
+            if (d.precip && maxPrecip > 0) {
+                const h = (d.precip / maxPrecip) * PRECIP_HEIGHT;
+                el("rect", {
+                    x: x(i) - step * 0.3, width: step * 0.6,
+                    y: PAD.top + plotH - h, height: h,
+                    class: "almanac-precip"
+                }).appendChild(document.createElementNS(SVG_NS, "title")).textContent =
+                    `${d.date}: ${d.precip} ${data.precipitation_unit}`;
+            }
+            if (days.length <= 16 || i % Math.ceil(days.length / 8) === 0) {
+                el("text", { x: x(i), y: HEIGHT - 8, "text-anchor": "middle", class: "almanac-label" }, d.date.slice(5));
+            }
+        });
+
+        for (const [key, cls] of [["high", "almanac-high"], ["low", "almanac-low"]]) {
+            const points = days
+                .map((d, i) => (d[key] === null ? null : `${x(i).toFixed(1)},${y(d[key]).toFixed(1)}`))
+                .filter(Boolean);
+            if (points.length > 1) el("polyline", { points: points.join(" "), class: cls });
+            days.forEach((d, i) => {
+                if (d[key] === null) return;
+                el("circle", { cx: x(i), cy: y(d[key]), r: 3, class: cls })
+                    .appendChild(document.createElementNS(SVG_NS, "title")).textContent =
+                    `${d.date} ${key}: ${d[key]}°${data.temperature_unit}`;
+            });
+        }
+    }
+
+    // Same days= as the page, and the units it was rendered in.
+    const params = new URLSearchParams(window.location.search);
+    params.set("units", svg.dataset.units);
+    fetch(`/api/v1/almanac/${encodeURIComponent(svg.dataset.station)}?${params}`)
+        .then((r) => {
+            if (!r.ok) throw new Error(`HTTP ${r.status}`);
+            return r.json();
+        })
+        .then(render)
+        .catch((err) => console.error("Failed to load almanac:", err));
+});
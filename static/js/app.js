@@ -11,7 +11,23 @@ document.addEventListener("DOMContentLoaded", () => {
     const locateBtn = document.getElementById("locate-btn");
     const suggestionsList = document.getElementById("suggestions");
     const weatherDisplay = document.getElementById("weather-display");
+    const unitsSelect = document.getElementById("units");
+    const saveBtn = document.getElementById("save-btn");
+    const alertsBtn = document.getElementById("alerts-btn");
     let debounceTimer;
+    let lastQuery = "";
+
+    // Units preference is kept in localStorage and mirrored to a cookie so
+    // server-rendered pages honor it too.
+    const savedUnits = localStorage.getItem("units") || "us";
+    if (unitsSelect) {
+        unitsSelect.value = savedUnits;
+        unitsSelect.addEventListener("change", () => {
+            localStorage.setItem("units", unitsSelect.value);
+            document.cookie = `units=${unitsSelect.value}; path=/; max-age=31536000; samesite=lax`;
+            if (lastQuery) fetchWeather(lastQuery);
+        });
+    }
 
     // Search input handler (debounce)
     if (locationInput) {
@@ -43,7 +59,10 @@ document.addEventListener("DOMContentLoaded", () => {
                                 li.onclick = () => {
                                     locationInput.value = `${p.name}, ${p.state}`;
                                     suggestionsList.classList.remove("visible");
-                                    fetchWeather(`lat=${p.latitude}&lon=${p.longitude}`);
+                                    const path = p.state
+                                        ? `/w/${encodeURIComponent(p.state)}/${placeSlug(p.name)}`
+                                        : coordsPath(p.latitude, p.longitude);
+                                    fetchWeather(`lat=${p.latitude}&lon=${p.longitude}`, path);
                                 };
                                 suggestionsList.appendChild(li);
                             });
@@ -107,7 +126,7 @@ document.addEventListener("DOMContentLoaded", () => {
                 if (!silent) {
                     qs += "&userInitiated=1";
                 }
-                fetchWeather(qs);
+                fetchWeather(qs, silent ? undefined : coordsPath(position.coords.latitude, position.coords.longitude));
 
                 if (!silent) {
                     locateBtn.innerText = originalText;
@@ -126,8 +145,15 @@ document.addEventListener("DOMContentLoaded", () => {
         );
     }
 
+    // Permalink pages arrive with weather already rendered, so don't replace
+    // it with the visitor's own location.
+    const permalink = document.querySelector("main[data-permalink]");
+    if (permalink) {
+        lastQuery = `lat=${permalink.dataset.lat}&lon=${permalink.dataset.lon}`;
+    }
+
     // Auto-locate if permission granted
-    if (navigator.geolocation) {
+    if (navigator.geolocation && !permalink) {
         navigator.permissions.query({ name: "geolocation" }).then((result) => {
             if (result.state === "granted" || result.state === "prompt") {
                 locateMe(true);
@@ -135,10 +161,22 @@ document.addEventListener("DOMContentLoaded", () => {
         });
     }
 
-    // Fetch Weather (HTML Fragment)
-    function fetchWeather(qs) {
+    // Permalink paths, matching db.PlaceSlug and the handlers' coordsPath
+    function placeSlug(name) {
+        return (name || "").toLowerCase().replace(/[^a-z0-9]+/g, "-").replace(/^-+|-+$/g, "");
+    }
+
+    function coordsPath(lat, lon) {
+        return `/w/${Number(lat).toFixed(2)},${Number(lon).toFixed(2)}`;
+    }
+
+    // Fetch Weather (HTML Fragment). When path is given the address bar is
+    // updated to that permalink so the page can be bookmarked or shared.
+    function fetchWeather(qs, path) {
+        lastQuery = qs.replace("&userInitiated=1", "");
+        const units = unitsSelect ? unitsSelect.value : "us";
         weatherDisplay.classList.add("is-loading");
-        fetch(`/api/weather?${qs}`)
+        fetch(`/api/weather?${qs}&units=${encodeURIComponent(units)}`)
             .then(r => {
                 if (!r.ok) throw new Error(r.statusText);
                 return r.text();
@@ -146,6 +184,12 @@ document.addEventListener("DOMContentLoaded", () => {
             .then(html => {
                 weatherDisplay.innerHTML = html;
                 weatherDisplay.classList.remove("is-loading");
+                if (path && window.location.pathname !== path) {
+                    history.replaceState(null, "", path);
+                }
+                if (saveBtn) saveBtn.hidden = false;
+                updateAlertsButton();
+                watchWeather();
                 // Update input if userInitiated
                 if (qs.includes("userInitiated=1")) {
                     const resolved = weatherDisplay.querySelector("#resolved-location");
@@ -170,6 +214,249 @@ document.addEventListener("DOMContentLoaded", () => {
             });
     }
 
+    // Live updates: keep the displayed location current for pages left open
+    // all day. The stream sends the re-rendered fragment whenever the cached
+    // forecast is refreshed or an alert changes.
+    let weatherStream = null;
+
+    function watchWeather() {
+        if (weatherStream) weatherStream.close();
+        weatherStream = null;
+        const resolved = weatherDisplay.querySelector("#resolved-location");
+        if (!window.EventSource || !resolved || !resolved.dataset.lat) return;
+        const units = unitsSelect ? unitsSelect.value : "us";
+        const qs = new URLSearchParams({
+            lat: resolved.dataset.lat,
+            lon: resolved.dataset.lon,
+            units,
+            format: "html",
+            last_event_id: resolved.dataset.version || ""
+        });
+        weatherStream = new EventSource(`/api/weather/stream?${qs}`);
+        weatherStream.addEventListener("weather", (e) => {
+            weatherDisplay.innerHTML = e.data;
+            updateAlertsButton();
+        });
+    }
+
+    if (permalink) watchWeather();
+
+    // --- Saved Locations ---
+    // The list lives in localStorage; a sync token (if any) mirrors it to the
+    // server so it can be shared between devices.
+    const savedSection = document.getElementById("saved-section");
+    const overview = document.getElementById("overview");
+    const syncCode = document.getElementById("sync-code");
+    const syncInput = document.getElementById("sync-input");
+    const syncUseBtn = document.getElementById("sync-use-btn");
+    const syncCreateBtn = document.getElementById("sync-create-btn");
+
+    function loadSaved() {
+        try {
+            return JSON.parse(localStorage.getItem("savedLocations")) || [];
+        } catch (e) {
+            return [];
+        }
+    }
+
+    function storeSaved(list, sync = true) {
+        localStorage.setItem("savedLocations", JSON.stringify(list));
+        renderSaved();
+        const token = localStorage.getItem("syncToken");
+        if (sync && token) {
+            fetch(`/api/saved/${encodeURIComponent(token)}`, {
+                method: "PUT",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify(list)
+            }).catch(console.error);
+        }
+    }
+
+    function renderSaved() {
+        if (!savedSection) return;
+        const list = loadSaved();
+        const token = localStorage.getItem("syncToken");
+        if (syncCode) syncCode.textContent = token ? `Sync code: ${token}` : "";
+        if (list.length === 0) {
+            savedSection.hidden = true;
+            overview.innerHTML = "";
+            return;
+        }
+        savedSection.hidden = false;
+        const units = unitsSelect ? unitsSelect.value : "us";
+        const qs = list.map(l => `loc=${encodeURIComponent(`${l.latitude},${l.longitude},${l.name}`)}`).join("&");
+        fetch(`/api/overview?${qs}&units=${encodeURIComponent(units)}`)
+            .then(r => {
+                if (!r.ok) throw new Error(r.statusText);
+                return r.text();
+            })
+            .then(html => {
+                overview.innerHTML = html;
+            })
+            .catch(e => {
+                overview.textContent = `Unable to load saved places: ${e.message}`;
+            });
+    }
+
+    if (overview) {
+        overview.addEventListener("click", (e) => {
+            const btn = e.target.closest(".overview-remove");
+            if (!btn) return;
+            const list = loadSaved();
+            list.splice(Number(btn.dataset.index), 1);
+            storeSaved(list);
+        });
+    }
+
+    if (saveBtn) {
+        saveBtn.addEventListener("click", () => {
+            const resolved = document.querySelector("#resolved-location");
+            if (!resolved) return;
+            const lat = Number(resolved.dataset.lat);
+            const lon = Number(resolved.dataset.lon);
+            const name = resolved.dataset.location || locationInput.value.trim() || `${lat}, ${lon}`;
+            const list = loadSaved();
+            if (list.some(l => l.latitude === lat && l.longitude === lon)) return;
+            list.push({ name, latitude: lat, longitude: lon });
+            storeSaved(list);
+        });
+    }
+
+    if (syncCreateBtn) {
+        syncCreateBtn.addEventListener("click", () => {
+            fetch("/api/saved", {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify(loadSaved())
+            })
+                .then(r => {
+                    if (!r.ok) throw new Error("Unable to create sync code");
+                    return r.json();
+                })
+                .then(data => {
+                    localStorage.setItem("syncToken", data.token);
+                    renderSaved();
+                })
+                .catch(e => alert(e.message));
+        });
+    }
+
+    if (syncUseBtn) {
+        syncUseBtn.addEventListener("click", () => {
+            const token = syncInput.value.trim();
+            if (!token) return;
+            fetch(`/api/saved/${encodeURIComponent(token)}`)
+                .then(r => {
+                    if (!r.ok) throw new Error("Unknown sync code");
+                    return r.json();
+                })
+                .then(data => {
+                    localStorage.setItem("syncToken", data.token);
+                    syncInput.value = "";
+                    storeSaved(data.locations, false);
+                })
+                .catch(e => alert(e.message));
+        });
+    }
+
+    if (unitsSelect) {
+        unitsSelect.addEventListener("change", renderSaved);
+    }
+
+    // Pull the latest list from the server, falling back to the local copy.
+    const syncToken = localStorage.getItem("syncToken");
+    if (syncToken) {
+        fetch(`/api/saved/${encodeURIComponent(syncToken)}`)
+            .then(r => (r.ok ? r.json() : null))
+            .then(data => {
+                if (data) storeSaved(data.locations, false);
+                else renderSaved();
+            })
+            .catch(renderSaved);
+    } else {
+        renderSaved();
+    }
+
+    // --- Push Alerts ---
+    // One push subscription per browser, watching one location. The server
+    // polls NWS and pushes new severe alerts for it (see sw.js).
+    let pushKey = null;
+
+    function currentLocation() {
+        const resolved = document.querySelector("#resolved-location");
+        if (!resolved || !resolved.dataset.lat) return null;
+        return { lat: Number(resolved.dataset.lat), lon: Number(resolved.dataset.lon) };
+    }
+
+    function pushLocationKey(loc) {
+        return `${loc.lat.toFixed(2)},${loc.lon.toFixed(2)}`;
+    }
+
+    function updateAlertsButton() {
+        if (!alertsBtn || !pushKey) return;
+        const loc = currentLocation();
+        alertsBtn.hidden = !loc;
+        if (!loc) return;
+        const watching = localStorage.getItem("pushLocation") === pushLocationKey(loc);
+        alertsBtn.textContent = watching ? "🔕 Stop alerts" : "🔔 Alert me";
+    }
+
+    function urlBase64ToUint8Array(base64) {
+        const padded = (base64 + "=".repeat((4 - base64.length % 4) % 4)).replace(/-/g, "+").replace(/_/g, "/");
+        return Uint8Array.from(atob(padded), c => c.charCodeAt(0));
+    }
+
+    async function toggleAlerts() {
+        const loc = currentLocation();
+        if (!loc) return;
+        const reg = await navigator.serviceWorker.ready;
+        let sub = await reg.pushManager.getSubscription();
+
+        if (localStorage.getItem("pushLocation") === pushLocationKey(loc)) {
+            if (sub) {
+                await fetch("/api/push/subscribe", {
+                    method: "DELETE",
+                    headers: { "Content-Type": "application/json" },
+                    body: JSON.stringify({ subscription: sub.toJSON() })
+                });
+                await sub.unsubscribe();
+            }
+            localStorage.removeItem("pushLocation");
+            updateAlertsButton();
+            return;
+        }
+
+        if (await Notification.requestPermission() !== "granted") return;
+        if (!sub) {
+            sub = await reg.pushManager.subscribe({
+                userVisibleOnly: true,
+                applicationServerKey: urlBase64ToUint8Array(pushKey)
+            });
+        }
+        const r = await fetch("/api/push/subscribe", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ subscription: sub.toJSON(), latitude: loc.lat, longitude: loc.lon })
+        });
+        if (!r.ok) throw new Error("Unable to subscribe to alerts");
+        localStorage.setItem("pushLocation", pushLocationKey(loc));
+        updateAlertsButton();
+    }
+
+    if (alertsBtn && "serviceWorker" in navigator && "PushManager" in window) {
+        fetch("/api/push/key")
+            .then(r => (r.ok ? r.json() : null))
+            .then(data => {
+                if (!data) return;
+                pushKey = data.publicKey;
+                updateAlertsButton();
+            })
+            .catch(console.error);
+        alertsBtn.addEventListener("click", () => {
+            toggleAlerts().catch(e => alert(e.message));
+        });
+    }
+
     // --- App Interest Modal ---
     const modal = document.getElementById("app-interest-modal");
     const openLink = document.getElementById("app-interest-link");
@@ -0,0 +1,34 @@
+// Replaces each <div data-wthr-embed> on the page with a wthr.lol weather
+// widget iframe. Snippets come from /embed/builder.
+(function () {
+    // Keep in step with embedSizes in internal/handlers/embed.go.
+    const sizes = {
+        small: [240, 110],
+        medium: [320, 180],
+        large: [320, 320],
+    };
+    const script = document.currentScript;
+    const origin = script ? new URL(script.src).origin : "https://wthr.lol";
+
+    function embed(el) {
+        const size = sizes[el.dataset.size] ? el.dataset.size : "medium";
+        const params = new URLSearchParams();
+        for (const key of ["lat", "lon", "location", "name", "theme", "units"]) {
+            if (el.dataset[key]) {
+                params.set(key, el.dataset[key]);
+            }
+        }
+        params.set("size", size);
+
+        const frame = document.createElement("iframe");
+        frame.src = `${origin}/embed?${params}`;
+        frame.width = sizes[size][0];
+        frame.height = sizes[size][1];
+        frame.title = `Weather for ${el.dataset.name || el.dataset.location || "your location"}`;
+        frame.loading = "lazy";
+        frame.style.border = "0";
+        el.replaceWith(frame);
+    }
+
+    document.querySelectorAll("div[data-wthr-embed]").forEach(embed);
+})();
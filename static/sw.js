@@ -1,4 +1,4 @@
-const CACHE_NAME = 'wthr-v2';
+const CACHE_NAME = 'wthr-v3';
 const ASSETS = [
     '/',
     '/static/css/style.css',
@@ -56,3 +56,39 @@ self.addEventListener('fetch', (event) => {
             })
     );
 });
+
+// Severe weather alerts pushed by the server (see internal/push)
+self.addEventListener('push', (event) => {
+    let data = {};
+    try {
+        data = event.data ? event.data.json() : {};
+    } catch (e) {
+        data = { title: 'Weather alert', body: event.data ? event.data.text() : '' };
+    }
+
+    event.waitUntil(
+        self.registration.showNotification(data.title || 'Weather alert', {
+            body: data.body || '',
+            tag: data.tag,
+            icon: '/static/icons/icon-192.png',
+            badge: '/static/icons/icon-192.png',
+            data: { url: data.url || '/' }
+        })
+    );
+});
+
+self.addEventListener('notificationclick', (event) => {
+    event.notification.close();
+    const url = new URL(event.notification.data.url, self.location.origin).href;
+
+    event.waitUntil(
+        clients.matchAll({ type: 'window', includeUncontrolled: true }).then((windows) => {
+            for (const win of windows) {
+                if (win.url === url && 'focus' in win) {
+                    return win.focus();
+                }
+            }
+            return clients.openWindow(url);
+        })
+    );
+});